@@ -0,0 +1,69 @@
+package JWTKeys
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vnlab/makeshop-payment/src/infrastructure/auth"
+)
+
+// Generate writes a new signing key to path. The key ID is the file name without extension.
+func Generate(path, algorithm string) error {
+	log.Println("======= Start JWT Key Generate ======= ")
+	defer log.Println("======= Stop JWT Key Generate ======= ")
+
+	kid := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	key, err := auth.GenerateSigningKey(kid, algorithm)
+	if err != nil {
+		return err
+	}
+	if err := auth.WritePrivateKeyFile(path, key); err != nil {
+		return err
+	}
+
+	log.Printf("Generated %s key %q at %s", key.Algorithm, key.KID, path)
+	return nil
+}
+
+// Rotate adds a new signing key to dir, which becomes the active key unless JWT_ACTIVE_KID
+// pins another one, and deletes the oldest keys so that at most keep previous keys remain.
+// Previous keys must be kept until every token they signed has expired.
+func Rotate(dir, algorithm string, keep int) error {
+	log.Println("======= Start JWT Key Rotate ======= ")
+	defer log.Println("======= Stop JWT Key Rotate ======= ")
+
+	if keep < 0 {
+		return fmt.Errorf("keep must not be negative")
+	}
+
+	key, path, err := auth.GenerateKeyFile(dir, algorithm)
+	if err != nil {
+		return err
+	}
+	log.Printf("Generated %s key %q at %s", key.Algorithm, key.KID, path)
+
+	if activeKID := os.Getenv("JWT_ACTIVE_KID"); activeKID != "" {
+		log.Printf("Warning: JWT_ACTIVE_KID pins key %q; set it to %q to activate the new key", activeKID, key.KID)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	// Key IDs sort by creation time, so the oldest keys come first
+	for len(paths) > keep+1 {
+		if err := os.Remove(paths[0]); err != nil {
+			return err
+		}
+		log.Printf("Removed expired key %s", paths[0])
+		paths = paths[1:]
+	}
+
+	return nil
+}
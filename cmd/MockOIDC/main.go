@@ -0,0 +1,32 @@
+package MockOIDC
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/vnlab/makeshop-payment/src/infrastructure/oidc"
+)
+
+// Execute serves a mock OpenID Connect provider on port until the process is stopped
+func Execute(port int, config oidc.MockConfig) error {
+	log.Println("======= Start Mock OIDC ======= ")
+	defer log.Println("======= Stop Mock OIDC ======= ")
+
+	if config.Issuer == "" {
+		config.Issuer = fmt.Sprintf("http://localhost:%d", port)
+	}
+	server, err := oidc.NewMockServer(config)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Issuer %s signs in %s with groups %v", config.Issuer, config.Email, config.Groups)
+	httpServer := &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           server,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return httpServer.ListenAndServe()
+}
@@ -0,0 +1,63 @@
+package PurgeDeleted
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vnlab/makeshop-payment/src/infrastructure/config"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/logger"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/persistence/mysql"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/persistence/repositories"
+)
+
+// Execute permanently removes users, roles and MFA types deleted more than retentionDays ago.
+// Users are purged first so that roles they referred to can be purged in the same run.
+func Execute(appConfig *config.Config, retentionDays int) error {
+	log.Println("======= Start Purge Deleted ======= ")
+	defer log.Println("======= Stop Purge Deleted ======= ")
+
+	if retentionDays < 1 {
+		return fmt.Errorf("retention must be at least 1 day")
+	}
+
+	appLogger := logger.NewLogger(&logger.Config{
+		LogLevel:      appConfig.LogLevel,
+		LogDirectory:  appConfig.LogDirectory,
+		EnableConsole: appConfig.EnableConsole,
+		EnableSQLLog:  appConfig.EnableSQLLog,
+	})
+
+	db, err := mysql.NewConnection(appConfig, appLogger)
+	if err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	ctx := context.Background()
+	before := time.Now().AddDate(0, 0, -retentionDays)
+	log.Printf("Purging records deleted before %s", before.Format(time.RFC3339))
+
+	purges := []struct {
+		name  string
+		purge func(context.Context, time.Time) (int64, error)
+	}{
+		{"users", repositories.NewUserRepository(db).PurgeDeleted},
+		{"roles", repositories.NewRoleRepository(db).PurgeDeleted},
+		{"MFA types", repositories.NewMFATypeRepository(db).PurgeDeleted},
+	}
+	for _, p := range purges {
+		count, err := p.purge(ctx, before)
+		if err != nil {
+			return fmt.Errorf("failed to purge %s: %w", p.name, err)
+		}
+		log.Printf("Purged %d %s", count, p.name)
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package RegisterQueries
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vnlab/makeshop-payment/src/api/graphql/generated"
+	"github.com/vnlab/makeshop-payment/src/api/graphql/persisted"
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/config"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/logger"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/persistence/mysql"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/persistence/repositories"
+)
+
+// document is a .graphql file to register
+type document struct {
+	name  string
+	query string
+}
+
+// Execute registers every .graphql document under dir. Each file is registered as is, under
+// the SHA-256 hash of its content, so clients must send the file content unchanged or its hash.
+// Nothing is registered unless every document is valid against the schema.
+func Execute(appConfig *config.Config, dir string, prune bool) error {
+	log.Println("======= Start Register Queries ======= ")
+	defer log.Println("======= Stop Register Queries ======= ")
+
+	documents, err := readDocuments(dir)
+	if err != nil {
+		return err
+	}
+	if len(documents) == 0 && !prune {
+		return fmt.Errorf("no .graphql document found in %s", dir)
+	}
+
+	appLogger := logger.NewLogger(&logger.Config{
+		LogLevel:      appConfig.LogLevel,
+		LogDirectory:  appConfig.LogDirectory,
+		EnableConsole: appConfig.EnableConsole,
+		EnableSQLLog:  appConfig.EnableSQLLog,
+	})
+
+	db, err := mysql.NewConnection(appConfig, appLogger)
+	if err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	ctx := context.Background()
+	repo := repositories.NewPersistedQueryRepository(db)
+
+	hashes := make([]string, 0, len(documents))
+	for _, doc := range documents {
+		name := doc.name
+		query := &models.PersistedQuery{
+			Hash:  persisted.Hash(doc.query),
+			Query: doc.query,
+			Name:  &name,
+		}
+		if err := repo.Register(ctx, query); err != nil {
+			return fmt.Errorf("failed to register %s: %w", doc.name, err)
+		}
+		hashes = append(hashes, query.Hash)
+		log.Printf("Registered %s %s", query.Hash, doc.name)
+	}
+
+	if prune {
+		count, err := repo.UnregisterExcept(ctx, hashes)
+		if err != nil {
+			return fmt.Errorf("failed to prune the allowlist: %w", err)
+		}
+		log.Printf("Removed %d documents from the allowlist, restart the servers for it to take effect", count)
+	}
+
+	return nil
+}
+
+// readDocuments reads and validates the .graphql files under dir
+func readDocuments(dir string) ([]document, error) {
+	schema := generated.NewExecutableSchema(generated.Config{}).Schema()
+
+	var documents []document
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".graphql") {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if _, errs := gqlparser.LoadQuery(schema, string(content)); len(errs) > 0 {
+			return fmt.Errorf("invalid document %s: %w", name, errs)
+		}
+
+		documents = append(documents, document{name: filepath.ToSlash(name), query: string(content)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/vnlab/makeshop-payment/cmd/JWTKeys"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/config"
+)
+
+// jwtKeys groups the commands managing the JWT signing keys.
+// To run these commands on local, use the following commands:
+// $ make shell "jwt-keys generate --out ./keys/jwt.pem"
+// $ make shell "jwt-keys rotate --dir ./keys --keep 2"
+var jwtKeys = &cobra.Command{
+	Use:   "jwt-keys",
+	Short: "manage JWT signing keys",
+	Long:  "manage the RS256/ES256 keys used to sign and verify JWT tokens",
+}
+
+var jwtKeysGenerate = &cobra.Command{
+	Use:   "generate",
+	Short: "generate a JWT signing key",
+	Long:  "generate a JWT signing key to use as JWT_PRIVATE_KEY_FILE; the key ID is the file name without extension",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out, _ := cmd.Flags().GetString("out")
+		algorithm, _ := cmd.Flags().GetString("alg")
+		return JWTKeys.Generate(out, algorithm)
+	},
+}
+
+var jwtKeysRotate = &cobra.Command{
+	Use:   "rotate",
+	Short: "rotate the JWT signing keys of a key directory",
+	Long:  "add a new active key to JWT_KEY_DIRECTORY and delete the oldest previous keys",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := cmd.Flags().GetString("dir")
+		algorithm, _ := cmd.Flags().GetString("alg")
+		keep, _ := cmd.Flags().GetInt("keep")
+		return JWTKeys.Rotate(dir, algorithm, keep)
+	},
+}
+
+func init() {
+	appConfig := config.LoadConfig()
+
+	jwtKeysGenerate.Flags().String("out", "./keys/jwt.pem", "path of the generated PEM file")
+	jwtKeysGenerate.Flags().String("alg", appConfig.JWTAlgorithm, "signing algorithm (RS256 or ES256)")
+
+	jwtKeysRotate.Flags().String("dir", appConfig.JWTKeyDirectory, "key directory")
+	jwtKeysRotate.Flags().String("alg", appConfig.JWTAlgorithm, "signing algorithm (RS256 or ES256)")
+	jwtKeysRotate.Flags().Int("keep", 2, "number of previous keys kept for verification")
+	jwtKeysRotate.MarkFlagRequired("dir")
+
+	jwtKeys.AddCommand(jwtKeysGenerate, jwtKeysRotate)
+	rootCmd.AddCommand(jwtKeys)
+}
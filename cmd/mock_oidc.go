@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/vnlab/makeshop-payment/cmd/MockOIDC"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/config"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/oidc"
+)
+
+// mockOIDC serves a mock OpenID Connect provider to try single sign-on on local.
+// Set OIDC_ISSUER_URL=http://localhost:9090 and the same client ID and secret, then run:
+// $ make shell "mock-oidc --port 9090 --email taro@example.com --groups payments-staff"
+var mockOIDC = &cobra.Command{
+	Use:   "mock-oidc",
+	Short: "serve a mock OpenID Connect provider",
+	Long:  "serve an OpenID Connect provider that signs the given user in without credentials, for local development only",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		port, _ := cmd.Flags().GetInt("port")
+		mockConfig := oidc.MockConfig{}
+		mockConfig.Issuer, _ = cmd.Flags().GetString("issuer")
+		mockConfig.ClientID, _ = cmd.Flags().GetString("client-id")
+		mockConfig.ClientSecret, _ = cmd.Flags().GetString("client-secret")
+		mockConfig.Email, _ = cmd.Flags().GetString("email")
+		mockConfig.GivenName, _ = cmd.Flags().GetString("given-name")
+		mockConfig.FamilyName, _ = cmd.Flags().GetString("family-name")
+		mockConfig.Groups, _ = cmd.Flags().GetStringSlice("groups")
+		mockConfig.GroupsClaim, _ = cmd.Flags().GetString("groups-claim")
+		return MockOIDC.Execute(port, mockConfig)
+	},
+}
+
+func init() {
+	appConfig := config.LoadConfig()
+
+	mockOIDC.Flags().Int("port", 9090, "port to listen on")
+	mockOIDC.Flags().String("issuer", "", "issuer URL, defaults to http://localhost:<port>")
+	mockOIDC.Flags().String("client-id", appConfig.OIDCClientID, "client ID accepted")
+	mockOIDC.Flags().String("client-secret", appConfig.OIDCClientSecret, "client secret accepted")
+	mockOIDC.Flags().String("email", "", "email of the user signed in")
+	mockOIDC.Flags().String("given-name", "Taro", "given name of the user signed in")
+	mockOIDC.Flags().String("family-name", "Yamada", "family name of the user signed in")
+	mockOIDC.Flags().StringSlice("groups", nil, "groups of the user signed in")
+	mockOIDC.Flags().String("groups-claim", appConfig.OIDCGroupsClaim, "ID token claim listing the groups")
+	mockOIDC.MarkFlagRequired("email")
+
+	rootCmd.AddCommand(mockOIDC)
+}
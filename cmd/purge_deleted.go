@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/vnlab/makeshop-payment/cmd/PurgeDeleted"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/config"
+)
+
+// purgeDeleted permanently removes soft-deleted records past the retention period.
+// To run this command on local, use the following command:
+// $ make shell "purge-deleted --retention-days 90"
+var purgeDeleted = &cobra.Command{
+	Use:   "purge-deleted",
+	Short: "purge soft-deleted records past the retention period",
+	Long:  "permanently remove users, roles and MFA types deleted more than DELETED_RETENTION_DAYS days ago",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		retentionDays, _ := cmd.Flags().GetInt("retention-days")
+		return PurgeDeleted.Execute(config.LoadConfig(), retentionDays)
+	},
+}
+
+func init() {
+	appConfig := config.LoadConfig()
+
+	purgeDeleted.Flags().Int("retention-days", appConfig.DeletedRetentionDays, "days deleted records are kept")
+
+	rootCmd.AddCommand(purgeDeleted)
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/vnlab/makeshop-payment/cmd/RegisterQueries"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/config"
+)
+
+// registerQueries adds the GraphQL documents of a directory to the allowlist used when
+// GRAPHQL_ALLOWLIST_ONLY is enabled.
+// To run this command on local, use the following command:
+// $ make shell "register-queries --dir ./graphql/operations --prune"
+var registerQueries = &cobra.Command{
+	Use:   "register-queries",
+	Short: "register the GraphQL documents clients may execute",
+	Long:  "validate every .graphql document of a directory against the schema and add it to the persisted query allowlist",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := cmd.Flags().GetString("dir")
+		prune, _ := cmd.Flags().GetBool("prune")
+		return RegisterQueries.Execute(config.LoadConfig(), dir, prune)
+	},
+}
+
+func init() {
+	registerQueries.Flags().String("dir", "./graphql/operations", "directory of .graphql documents, read recursively")
+	registerQueries.Flags().Bool("prune", false, "remove documents that are no longer in the directory from the allowlist")
+
+	rootCmd.AddCommand(registerQueries)
+}
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS user_otp_codes (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `channel` varchar(20) NOT NULL,
+  `purpose` varchar(30) NOT NULL,
+  `code_hash` varchar(255) NOT NULL,
+  `attempts` int NOT NULL DEFAULT '0',
+  `expires_at` datetime NOT NULL,
+  `consumed_at` datetime DEFAULT NULL,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_user_otp_codes_lookup` (`user_id`, `channel`, `purpose`, `consumed_at`),
+  CONSTRAINT `fk_user_otp_codes_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE user_otp_codes;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+  ADD COLUMN `phone_number` varchar(16) DEFAULT NULL AFTER `avatar_url`,
+  ADD COLUMN `phone_verified_at` datetime DEFAULT NULL AFTER `phone_number`,
+  ADD CONSTRAINT `chk_users_phone_number_e164` CHECK (`phone_number` IS NULL OR `phone_number` REGEXP '^\\+[1-9][0-9]{1,14}$');
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE user_otp_codes
+  ADD COLUMN `destination` varchar(255) NOT NULL DEFAULT '' AFTER `purpose`;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE user_otp_codes
+  DROP COLUMN `destination`;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE users
+  DROP CHECK `chk_users_phone_number_e164`,
+  DROP COLUMN `phone_verified_at`,
+  DROP COLUMN `phone_number`;
+-- +goose StatementEnd
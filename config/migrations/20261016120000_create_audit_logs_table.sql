@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS audit_logs (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `actor_user_id` int DEFAULT NULL,
+  `target_user_id` int DEFAULT NULL,
+  `action` varchar(100) NOT NULL,
+  `metadata` json DEFAULT NULL,
+  `ip_address` varchar(45) DEFAULT NULL,
+  `user_agent` varchar(255) DEFAULT NULL,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_audit_logs_actor_user_id` (`actor_user_id`),
+  KEY `idx_audit_logs_target_user_id` (`target_user_id`),
+  KEY `idx_audit_logs_action` (`action`)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE audit_logs;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+  `jti` varchar(36) NOT NULL,
+  `user_id` int NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`jti`),
+  KEY `idx_revoked_tokens_user_id` (`user_id`),
+  KEY `idx_revoked_tokens_expires_at` (`expires_at`)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN `tokens_valid_after` datetime DEFAULT NULL AFTER `phone_verified_at`;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN `tokens_valid_after`;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE revoked_tokens;
+-- +goose StatementEnd
@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN `email_verified_at` datetime DEFAULT NULL AFTER `email`;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- Accounts created before verification existed keep working
+UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS email_verification_tokens (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `email` varchar(255) NOT NULL,
+  `purpose` varchar(20) NOT NULL,
+  `token_hash` varchar(64) NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `used_at` datetime DEFAULT NULL,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_email_verification_tokens_token_hash` (`token_hash`),
+  KEY `idx_email_verification_tokens_user_id` (`user_id`),
+  CONSTRAINT `fk_email_verification_tokens_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE email_verification_tokens;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN `email_verified_at`;
+-- +goose StatementEnd
@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+  ADD COLUMN `failed_login_attempts` int NOT NULL DEFAULT 0 AFTER `password_hash`,
+  ADD COLUMN `lockout_count` int NOT NULL DEFAULT 0 AFTER `failed_login_attempts`,
+  ADD COLUMN `locked_until` datetime DEFAULT NULL AFTER `lockout_count`;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS login_attempts (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user_id` int DEFAULT NULL,
+  `email` varchar(255) NOT NULL,
+  `ip_address` varchar(45) NOT NULL,
+  `user_agent` varchar(255) DEFAULT NULL,
+  `success` tinyint(1) NOT NULL,
+  `failure_reason` varchar(30) DEFAULT NULL,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_login_attempts_user_id_created_at` (`user_id`, `created_at`),
+  KEY `idx_login_attempts_ip_address_created_at` (`ip_address`, `created_at`),
+  KEY `idx_login_attempts_email_created_at` (`email`, `created_at`)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE login_attempts;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE users
+  DROP COLUMN `locked_until`,
+  DROP COLUMN `lockout_count`,
+  DROP COLUMN `failed_login_attempts`;
+-- +goose StatementEnd
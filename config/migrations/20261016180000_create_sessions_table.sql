@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS sessions (
+  `id` varchar(36) NOT NULL,
+  `user_id` int NOT NULL,
+  `device_label` varchar(100) DEFAULT NULL,
+  `user_agent` varchar(255) DEFAULT NULL,
+  `ip_address` varchar(45) DEFAULT NULL,
+  `last_seen_at` datetime NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `revoked_at` datetime DEFAULT NULL,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_sessions_user_id` (`user_id`),
+  CONSTRAINT `fk_sessions_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- Logins made before sessions existed keep working: one session per live refresh token family
+INSERT INTO sessions (`id`, `user_id`, `user_agent`, `ip_address`, `last_seen_at`, `expires_at`, `created_at`)
+SELECT `family_id`, `user_id`, MAX(`device_info`), MAX(`ip_address`), MAX(`created_at`), MAX(`expires_at`), MIN(`created_at`)
+FROM refresh_tokens
+WHERE `revoked_at` IS NULL AND `expires_at` > NOW()
+GROUP BY `family_id`, `user_id`;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE sessions;
+-- +goose StatementEnd
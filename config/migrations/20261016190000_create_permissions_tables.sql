@@ -0,0 +1,58 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS permissions (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `code` varchar(100) NOT NULL,
+  `description` varchar(255) DEFAULT NULL,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_permissions_code` (`code`)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS role_permissions (
+  `role_id` int NOT NULL,
+  `permission_id` int NOT NULL,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`role_id`, `permission_id`),
+  KEY `idx_role_permissions_permission_id` (`permission_id`),
+  CONSTRAINT `fk_role_permissions_role` FOREIGN KEY (`role_id`) REFERENCES `roles` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_role_permissions_permission` FOREIGN KEY (`permission_id`) REFERENCES `permissions` (`id`) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO permissions (`code`, `description`)
+VALUES
+  ('users.read', 'View users and their sessions'),
+  ('users.manage', 'Create, update, disable and delete users, change their role except to or from system administrator, unlock them and reset their MFA'),
+  ('security.audit.read', 'View login attempts and audit logs'),
+  ('roles.manage', 'Grant and revoke role permissions'),
+  ('payments.read', 'View payments'),
+  ('payments.refund', 'Refund payments'),
+  ('reports.export', 'Export reports');
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- Databases whose roles are already seeded keep their current access;
+-- new databases get the same grants from config/seeds/master/roles_permissions.sql
+INSERT IGNORE INTO role_permissions (`role_id`, `permission_id`)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON (
+  r.code = 'SYSTEM_ADMIN'
+  OR (r.code = 'BUSINESS_USER' AND p.code IN ('payments.read', 'payments.refund', 'reports.export'))
+  OR (r.code = 'ACCOUNTING_USER' AND p.code IN ('payments.read', 'reports.export'))
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE role_permissions;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE permissions;
+-- +goose StatementEnd
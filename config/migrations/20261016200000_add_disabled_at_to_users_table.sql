@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+  ADD COLUMN `disabled_at` datetime DEFAULT NULL AFTER `locked_until`;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users
+  DROP COLUMN `disabled_at`;
+-- +goose StatementEnd
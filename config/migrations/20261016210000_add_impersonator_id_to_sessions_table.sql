@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE sessions
+  ADD COLUMN `impersonator_id` int DEFAULT NULL AFTER `user_id`,
+  ADD KEY `idx_sessions_impersonator_id` (`impersonator_id`);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE sessions
+  DROP KEY `idx_sessions_impersonator_id`,
+  DROP COLUMN `impersonator_id`;
+-- +goose StatementEnd
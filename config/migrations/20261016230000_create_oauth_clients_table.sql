@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS oauth_clients (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `client_id` varchar(64) NOT NULL,
+  `name` varchar(100) NOT NULL,
+  `secret_hash` varchar(64) NOT NULL,
+  `user_id` int NOT NULL,
+  `scopes` varchar(255) NOT NULL,
+  `revoked_at` datetime DEFAULT NULL,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_oauth_clients_client_id` (`client_id`),
+  KEY `idx_oauth_clients_user_id` (`user_id`),
+  CONSTRAINT `fk_oauth_clients_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO permissions (`code`, `description`)
+VALUES ('oauth_clients.manage', 'Register and revoke OAuth clients');
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT IGNORE INTO role_permissions (`role_id`, `permission_id`)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON p.code = 'oauth_clients.manage'
+WHERE r.code = 'SYSTEM_ADMIN';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM permissions WHERE `code` = 'oauth_clients.manage';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE oauth_clients;
+-- +goose StatementEnd
@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS user_identities (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `issuer` varchar(255) NOT NULL,
+  `subject` varchar(255) NOT NULL,
+  `email` varchar(255) DEFAULT NULL,
+  `last_login_at` datetime DEFAULT NULL,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_user_identities_issuer_subject` (`issuer`, `subject`),
+  KEY `idx_user_identities_user_id` (`user_id`),
+  CONSTRAINT `fk_user_identities_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS sso_states (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `state_hash` varchar(64) NOT NULL,
+  `nonce` varchar(64) NOT NULL,
+  `code_verifier` varchar(128) NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `used_at` datetime DEFAULT NULL,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_sso_states_state_hash` (`state_hash`),
+  KEY `idx_sso_states_expires_at` (`expires_at`)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE sso_states;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE user_identities;
+-- +goose StatementEnd
@@ -0,0 +1,71 @@
+-- +goose Up
+-- +goose StatementBegin
+INSERT INTO master_mfa_types (`id`, `no`, `title`, `is_active`, `created_at`, `updated_at`)
+SELECT 4, 4, 'パスキー', 1, NOW(), NOW()
+FROM DUAL
+WHERE NOT EXISTS (SELECT 1 FROM master_mfa_types WHERE `no` = 4);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE users
+  ADD COLUMN `passkey_required` tinyint(1) NOT NULL DEFAULT 0 AFTER `mfa_type_id`;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS user_passkeys (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user_id` int NOT NULL,
+  `name` varchar(100) NOT NULL,
+  `credential_id` varbinary(1023) NOT NULL,
+  `user_handle` varbinary(64) NOT NULL,
+  `public_key` blob NOT NULL,
+  `sign_count` int unsigned NOT NULL DEFAULT 0,
+  `transports` varchar(255) NOT NULL DEFAULT '',
+  `backup_eligible` tinyint(1) NOT NULL DEFAULT 0,
+  `last_used_at` datetime DEFAULT NULL,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_user_passkeys_credential_id` (`credential_id`),
+  KEY `idx_user_passkeys_user_id` (`user_id`),
+  CONSTRAINT `fk_user_passkeys_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS passkey_challenges (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `user_id` int DEFAULT NULL,
+  `challenge_hash` varchar(64) NOT NULL,
+  `purpose` varchar(30) NOT NULL,
+  `user_handle` varbinary(64) DEFAULT NULL,
+  `expires_at` datetime NOT NULL,
+  `used_at` datetime DEFAULT NULL,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_passkey_challenges_challenge_hash` (`challenge_hash`),
+  KEY `idx_passkey_challenges_expires_at` (`expires_at`),
+  CONSTRAINT `fk_passkey_challenges_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE passkey_challenges;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE user_passkeys;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE users
+  DROP COLUMN `passkey_required`;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+UPDATE users SET `enabled_mfa` = 0, `mfa_type_id` = NULL WHERE `mfa_type_id` = 4;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DELETE FROM master_mfa_types WHERE `no` = 4;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS persisted_queries (
+  `hash` char(64) NOT NULL,
+  `query` mediumtext NOT NULL,
+  `name` varchar(255) DEFAULT NULL,
+  `registered` tinyint(1) NOT NULL DEFAULT 0,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`hash`),
+  KEY `idx_persisted_queries_registered` (`registered`, `updated_at`)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE persisted_queries;
+-- +goose StatementEnd
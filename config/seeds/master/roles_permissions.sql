@@ -0,0 +1,9 @@
+-- role_permissions
+INSERT IGNORE INTO `role_permissions` (role_id, permission_id, created_at)
+SELECT r.id, p.id, NOW()
+FROM `roles` r
+JOIN `permissions` p ON (
+    r.code = 'SYSTEM_ADMIN'
+    OR (r.code = 'BUSINESS_USER' AND p.code IN ('payments.read', 'payments.refund', 'reports.export'))
+    OR (r.code = 'ACCOUNTING_USER' AND p.code IN ('payments.read', 'reports.export'))
+);
@@ -0,0 +1,100 @@
+// Package directives implements the authorization directives declared in schema/directive.graphql
+package directives
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vnlab/makeshop-payment/src/api/graphql/generated"
+	"github.com/vnlab/makeshop-payment/src/api/graphql/middleware"
+	"github.com/vnlab/makeshop-payment/src/domain/models"
+)
+
+// signInFields are the mutations that return the user who has just proven their identity,
+// before the request carries their access token
+var signInFields = map[string]bool{
+	"register":         true,
+	"login":            true,
+	"refreshToken":     true,
+	"verifyMfa":        true,
+	"completeSsoLogin": true,
+	"loginWithPasskey": true,
+}
+
+// New returns the directive implementations for the executable schema
+func New() generated.DirectiveRoot {
+	return generated.DirectiveRoot{
+		Authenticated:     Authenticated,
+		HasRole:           HasRole,
+		HasPermission:     HasPermission,
+		NoImpersonation:   NoImpersonation,
+		OwnerOrPermission: OwnerOrPermission,
+	}
+}
+
+// Authenticated resolves the field only for users with a valid access token.
+// API keys and OAuth clients are refused since they only grant the permissions of their scopes.
+func Authenticated(ctx context.Context, obj interface{}, next graphql.Resolver) (interface{}, error) {
+	if err := middleware.CheckAuth(ctx); err != nil {
+		return nil, middleware.ErrNotAuthenticated
+	}
+	if !middleware.IsUser(ctx) {
+		return nil, middleware.ErrForbidden
+	}
+	return next(ctx)
+}
+
+// HasRole resolves the field only for users with the given role
+func HasRole(ctx context.Context, obj interface{}, next graphql.Resolver, role generated.RoleCode) (interface{}, error) {
+	if err := middleware.CheckAuth(ctx); err != nil {
+		return nil, middleware.ErrNotAuthenticated
+	}
+	if !middleware.IsUser(ctx) {
+		return nil, middleware.ErrForbidden
+	}
+	if err := middleware.CheckRoleCode(ctx, string(role)); err != nil {
+		return nil, middleware.ErrForbidden
+	}
+	return next(ctx)
+}
+
+// HasPermission resolves the field only for users whose role has been granted the permission,
+// or for API keys and OAuth clients with the permission in their scopes
+func HasPermission(ctx context.Context, obj interface{}, next graphql.Resolver, perm string) (interface{}, error) {
+	if err := middleware.CheckAuth(ctx); err != nil {
+		return nil, middleware.ErrNotAuthenticated
+	}
+	if !middleware.HasPermission(ctx, perm) {
+		return nil, middleware.ErrForbidden
+	}
+	return next(ctx)
+}
+
+// NoImpersonation refuses sensitive fields to administrators acting as a user
+func NoImpersonation(ctx context.Context, obj interface{}, next graphql.Resolver) (interface{}, error) {
+	if middleware.IsImpersonating(ctx) {
+		return nil, middleware.ErrImpersonationForbidden
+	}
+	return next(ctx)
+}
+
+// OwnerOrPermission resolves a field of a user for that user themselves, including in the response
+// of the mutation they signed in with, and otherwise like HasPermission
+func OwnerOrPermission(ctx context.Context, obj interface{}, next graphql.Resolver, perm string) (interface{}, error) {
+	if user, ok := obj.(*models.User); ok && isOwner(ctx, user) {
+		return next(ctx)
+	}
+	return HasPermission(ctx, obj, next, perm)
+}
+
+func isOwner(ctx context.Context, user *models.User) bool {
+	if userID, err := middleware.GetUserID(ctx); err == nil && middleware.IsUser(ctx) && userID == user.ID {
+		return true
+	}
+
+	fc := graphql.GetFieldContext(ctx)
+	for fc != nil && fc.Parent != nil {
+		fc = fc.Parent
+	}
+	return fc != nil && fc.Object == "Mutation" && signInFields[fc.Field.Name]
+}
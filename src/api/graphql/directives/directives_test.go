@@ -0,0 +1,55 @@
+package directives
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vnlab/makeshop-payment/src/api/graphql/middleware"
+	"github.com/vnlab/makeshop-payment/src/domain/models"
+)
+
+func withUser(ctx context.Context, userID int, permissions ...string) context.Context {
+	ctx = context.WithValue(ctx, "authenticated", true)
+	ctx = context.WithValue(ctx, "principalType", middleware.PrincipalUser)
+	ctx = context.WithValue(ctx, "userId", userID)
+	return context.WithValue(ctx, "permissions", permissions)
+}
+
+// underRootField nests the context under the given root field and its user field
+func underRootField(ctx context.Context, object, name string) context.Context {
+	ctx = graphql.WithFieldContext(ctx, &graphql.FieldContext{Object: object, Field: graphql.CollectedField{Field: &ast.Field{Name: name}}})
+	return graphql.WithFieldContext(ctx, &graphql.FieldContext{Object: "AuthResponse", Field: graphql.CollectedField{Field: &ast.Field{Name: "user"}}})
+}
+
+func TestOwnerOrPermission(t *testing.T) {
+	user := &models.User{ID: 1, Email: "owner@example.com"}
+	next := func(ctx context.Context) (interface{}, error) { return user.Email, nil }
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		allowed bool
+	}{
+		{"owner", withUser(context.Background(), 1), true},
+		{"other user", withUser(context.Background(), 2), false},
+		{"other user with the permission", withUser(context.Background(), 2, models.PermissionUsersRead), true},
+		{"anonymous", context.Background(), false},
+		{"signing in", underRootField(context.Background(), "Mutation", "login"), true},
+		{"signing in while authenticated as another user", underRootField(withUser(context.Background(), 2), "Mutation", "verifyMfa"), true},
+		{"other root field", underRootField(withUser(context.Background(), 2), "Query", "oauthClients"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := OwnerOrPermission(tt.ctx, user, next, models.PermissionUsersRead)
+			if tt.allowed && err != nil {
+				t.Fatalf("expected the email to resolve, got %v", err)
+			}
+			if !tt.allowed && !errors.Is(err, middleware.ErrForbidden) && !errors.Is(err, middleware.ErrNotAuthenticated) {
+				t.Fatalf("expected the email to be refused, got %v", err)
+			}
+		})
+	}
+}
@@ -3,14 +3,59 @@
 package generated
 
 import (
+	"fmt"
+	"io"
+	"strconv"
 	"time"
 
 	"github.com/vnlab/makeshop-payment/src/domain/models"
 )
 
+type AdminCreateUserInput struct {
+	Email         string `json:"email"`
+	Password      string `json:"password"`
+	FirstName     string `json:"firstName"`
+	LastName      string `json:"lastName"`
+	FirstNameKana string `json:"firstNameKana"`
+	LastNameKana  string `json:"lastNameKana"`
+	RoleID        *int   `json:"roleId,omitempty"`
+}
+
+type AdminResetMFAInput struct {
+	UserID int    `json:"userId"`
+	Reason string `json:"reason"`
+}
+
+type AdminUpdateUserInput struct {
+	UserID         int     `json:"userId"`
+	RoleID         *int    `json:"roleId,omitempty"`
+	FirstName      *string `json:"firstName,omitempty"`
+	LastName       *string `json:"lastName,omitempty"`
+	FirstNameKana  *string `json:"firstNameKana,omitempty"`
+	LastNameKana   *string `json:"lastNameKana,omitempty"`
+	EnabledMfa     *bool   `json:"enabledMfa,omitempty"`
+	MfaTypeID      *int    `json:"mfaTypeId,omitempty"`
+	RequirePasskey *bool   `json:"requirePasskey,omitempty"`
+}
+
+type APIKeyCreated struct {
+	APIKey *models.APIKey `json:"apiKey"`
+	Key    string         `json:"key"`
+}
+
 type AuthResponse struct {
-	Token string       `json:"token"`
-	User  *models.User `json:"user"`
+	Token        *string      `json:"token,omitempty"`
+	RefreshToken *string      `json:"refreshToken,omitempty"`
+	ExpiresAt    *time.Time   `json:"expiresAt,omitempty"`
+	User         *models.User `json:"user,omitempty"`
+	MfaRequired  bool         `json:"mfaRequired"`
+	MfaToken     *string      `json:"mfaToken,omitempty"`
+	MfaType      *MFAType     `json:"mfaType,omitempty"`
+}
+
+type ChangeEmailInput struct {
+	NewEmail        string `json:"newEmail"`
+	CurrentPassword string `json:"currentPassword"`
 }
 
 type ChangePasswordInput struct {
@@ -18,14 +63,58 @@ type ChangePasswordInput struct {
 	NewPassword     string `json:"newPassword"`
 }
 
+type CompleteSsoLoginInput struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+type ConfirmTOTPInput struct {
+	Code string `json:"code"`
+}
+
+type CreateAPIKeyInput struct {
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	AllowedIps []string   `json:"allowedIps,omitempty"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+}
+
+type CreateOAuthClientInput struct {
+	Name   string   `json:"name"`
+	UserID int      `json:"userId"`
+	Scopes []string `json:"scopes"`
+}
+
+type FinishPasskeyRegistrationInput struct {
+	Credential      string  `json:"credential"`
+	Name            *string `json:"name,omitempty"`
+	CurrentPassword *string `json:"currentPassword,omitempty"`
+	MfaCode         *string `json:"mfaCode,omitempty"`
+}
+
+type LoginAttemptFilter struct {
+	UserID    *int       `json:"userId,omitempty"`
+	Email     *string    `json:"email,omitempty"`
+	IPAddress *string    `json:"ipAddress,omitempty"`
+	Success   *bool      `json:"success,omitempty"`
+	Since     *time.Time `json:"since,omitempty"`
+	Until     *time.Time `json:"until,omitempty"`
+}
+
 type LoginInput struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
+type LogoutInput struct {
+	RefreshToken *string `json:"refreshToken,omitempty"`
+}
+
 type MFASettingsInput struct {
-	Enabled bool `json:"enabled"`
-	TypeID  *int `json:"typeId,omitempty"`
+	Enabled         bool    `json:"enabled"`
+	TypeID          *int    `json:"typeId,omitempty"`
+	CurrentPassword *string `json:"currentPassword,omitempty"`
+	MfaCode         *string `json:"mfaCode,omitempty"`
 }
 
 type MFAType struct {
@@ -40,6 +129,18 @@ type MFAType struct {
 type Mutation struct {
 }
 
+type OAuthClientCredentials struct {
+	Client       *models.OAuthClient `json:"client"`
+	ClientSecret string              `json:"clientSecret"`
+}
+
+type PaginatedLoginAttempts struct {
+	LoginAttempts []*models.LoginAttempt `json:"loginAttempts"`
+	Page          int                    `json:"page"`
+	PageSize      int                    `json:"pageSize"`
+	TotalPages    int                    `json:"totalPages"`
+}
+
 type PaginatedUsers struct {
 	Users      []*models.User `json:"users"`
 	Page       int            `json:"page"`
@@ -50,6 +151,15 @@ type PaginatedUsers struct {
 type Query struct {
 }
 
+type ReauthenticationInput struct {
+	CurrentPassword *string `json:"currentPassword,omitempty"`
+	MfaCode         *string `json:"mfaCode,omitempty"`
+}
+
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
 type RegisterInput struct {
 	Email         string `json:"email"`
 	Password      string `json:"password"`
@@ -59,9 +169,82 @@ type RegisterInput struct {
 	LastNameKana  string `json:"lastNameKana"`
 }
 
+type RegisterPhoneNumberInput struct {
+	PhoneNumber string `json:"phoneNumber"`
+}
+
+type SsoLogin struct {
+	AuthorizationURL string `json:"authorizationUrl"`
+	State            string `json:"state"`
+}
+
+type Subscription struct {
+}
+
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauthUri"`
+	QRCodePng  string `json:"qrCodePng"`
+}
+
 type UpdateProfileInput struct {
 	FirstName     string `json:"firstName"`
 	LastName      string `json:"lastName"`
 	FirstNameKana string `json:"firstNameKana"`
 	LastNameKana  string `json:"lastNameKana"`
 }
+
+type VerifyMFAInput struct {
+	MfaToken string `json:"mfaToken"`
+	Code     string `json:"code"`
+}
+
+type VerifyPhoneNumberInput struct {
+	Code      string `json:"code"`
+	UseForMfa *bool  `json:"useForMfa,omitempty"`
+}
+
+type RoleCode string
+
+const (
+	RoleCodeSystemAdmin    RoleCode = "SYSTEM_ADMIN"
+	RoleCodeGeneralUser    RoleCode = "GENERAL_USER"
+	RoleCodeBusinessUser   RoleCode = "BUSINESS_USER"
+	RoleCodeAccountingUser RoleCode = "ACCOUNTING_USER"
+)
+
+var AllRoleCode = []RoleCode{
+	RoleCodeSystemAdmin,
+	RoleCodeGeneralUser,
+	RoleCodeBusinessUser,
+	RoleCodeAccountingUser,
+}
+
+func (e RoleCode) IsValid() bool {
+	switch e {
+	case RoleCodeSystemAdmin, RoleCodeGeneralUser, RoleCodeBusinessUser, RoleCodeAccountingUser:
+		return true
+	}
+	return false
+}
+
+func (e RoleCode) String() string {
+	return string(e)
+}
+
+func (e *RoleCode) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = RoleCode(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid RoleCode", str)
+	}
+	return nil
+}
+
+func (e RoleCode) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
@@ -0,0 +1,75 @@
+package limits
+
+import (
+	"math"
+
+	"github.com/vnlab/makeshop-payment/src/api/graphql/generated"
+)
+
+const (
+	// defaultPageSize is the page size used by the list queries when none is requested
+	defaultPageSize = 10
+
+	// unpagedListSize is the size assumed for lists returned without pagination
+	unpagedListSize = 10
+)
+
+// Complexity returns the complexity functions of the schema. Fields cost one plus the
+// cost of their selection; lists multiply the cost of their items by the page size requested,
+// so that a client cannot get around the budget by asking for large pages.
+func Complexity() generated.ComplexityRoot {
+	var c generated.ComplexityRoot
+
+	c.Query.Users = func(childComplexity int, page *int, pageSize *int) int {
+		return paged(childComplexity, pageSize)
+	}
+	c.Query.DeletedUsers = func(childComplexity int, page *int, pageSize *int) int {
+		return paged(childComplexity, pageSize)
+	}
+	c.Query.LoginAttempts = func(childComplexity int, filter *generated.LoginAttemptFilter, page *int, pageSize *int) int {
+		return paged(childComplexity, pageSize)
+	}
+
+	c.Query.MySessions = unpaged
+	c.Query.UserSessions = func(childComplexity int, userID int) int {
+		return unpaged(childComplexity)
+	}
+	c.Query.Roles = unpaged
+	c.Query.Permissions = unpaged
+	c.Query.MyAPIKeys = unpaged
+	c.Query.OauthClients = unpaged
+	c.Query.MyPasskeys = unpaged
+	c.Role.Permissions = unpaged
+
+	return c
+}
+
+// paged weights a paginated list by the page size requested
+func paged(childComplexity int, pageSize *int) int {
+	size := defaultPageSize
+	if pageSize != nil && *pageSize > 0 {
+		size = *pageSize
+	}
+	return safeAdd(1, safeMul(childComplexity, size))
+}
+
+// unpaged weights a list returned without pagination
+func unpaged(childComplexity int) int {
+	return safeAdd(1, safeMul(childComplexity, unpagedListSize))
+}
+
+// safeAdd adds two costs, saturating instead of overflowing
+func safeAdd(a, b int) int {
+	if a > math.MaxInt-b {
+		return math.MaxInt
+	}
+	return a + b
+}
+
+// safeMul multiplies two costs, saturating instead of overflowing
+func safeMul(a, b int) int {
+	if a != 0 && b > math.MaxInt/a {
+		return math.MaxInt
+	}
+	return a * b
+}
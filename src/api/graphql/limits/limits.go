@@ -0,0 +1,173 @@
+// Package limits rejects GraphQL operations that are too deep, too wide or too expensive
+// before any resolver runs
+package limits
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/99designs/gqlgen/complexity"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"github.com/vnlab/makeshop-payment/src/api/graphql/middleware"
+)
+
+// Error codes set in the "code" extension of a rejected operation
+const (
+	CodeDepthLimitExceeded      = "DEPTH_LIMIT_EXCEEDED"
+	CodeComplexityLimitExceeded = "COMPLEXITY_LIMIT_EXCEEDED"
+	CodeAliasLimitExceeded      = "ALIAS_LIMIT_EXCEEDED"
+	CodeFieldLimitExceeded      = "FIELD_LIMIT_EXCEEDED"
+)
+
+// Limits bounds a single operation. A zero value disables the corresponding check.
+type Limits struct {
+	MaxDepth      int // Deepest nesting of fields
+	MaxComplexity int // Cost budget, see Complexity for how fields are weighted
+	MaxAliases    int // Fields requested under an alias
+	MaxFields     int // Fields selected, counting each fragment spread
+}
+
+// Config holds the limits of each kind of client
+type Config struct {
+	User   Limits // Users logged in with an access token, whatever their role
+	Client Limits // API keys, OAuth clients and unauthenticated requests
+}
+
+// Extension is a gqlgen handler extension enforcing Config
+type Extension struct {
+	config Config
+	schema graphql.ExecutableSchema
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = (*Extension)(nil)
+
+// New creates a new Extension
+func New(config Config) *Extension {
+	return &Extension{config: config}
+}
+
+// ExtensionName implements graphql.HandlerExtension
+func (e *Extension) ExtensionName() string {
+	return "QueryLimits"
+}
+
+// Validate implements graphql.HandlerExtension
+func (e *Extension) Validate(schema graphql.ExecutableSchema) error {
+	e.schema = schema
+	return nil
+}
+
+// MutateOperationContext rejects the operation when it exceeds the limits of the client
+func (e *Extension) MutateOperationContext(ctx context.Context, opCtx *graphql.OperationContext) *gqlerror.Error {
+	if opCtx.Operation == nil {
+		return nil
+	}
+
+	limits := e.config.Client
+	if middleware.IsUser(ctx) {
+		limits = e.config.User
+	}
+
+	s := measure(opCtx.Doc, opCtx.Operation.SelectionSet)
+	if exceeds(s.depth, limits.MaxDepth) {
+		return limitError(CodeDepthLimitExceeded, "depth", s.depth, limits.MaxDepth)
+	}
+	if exceeds(s.aliases, limits.MaxAliases) {
+		return limitError(CodeAliasLimitExceeded, "alias count", s.aliases, limits.MaxAliases)
+	}
+	if exceeds(s.fields, limits.MaxFields) {
+		return limitError(CodeFieldLimitExceeded, "field count", s.fields, limits.MaxFields)
+	}
+
+	cost := complexity.Calculate(e.schema, opCtx.Operation, opCtx.Variables)
+	if exceeds(cost, limits.MaxComplexity) {
+		return limitError(CodeComplexityLimitExceeded, "complexity", cost, limits.MaxComplexity)
+	}
+	return nil
+}
+
+// exceeds tells whether a value is over a limit, zero meaning no limit
+func exceeds(value, limit int) bool {
+	return limit > 0 && value > limit
+}
+
+// limitError describes a rejected operation so that clients can tell which limit to stay under
+func limitError(code, what string, value, limit int) *gqlerror.Error {
+	return &gqlerror.Error{
+		Message: fmt.Sprintf("operation %s is %d, which exceeds the limit of %d", what, value, limit),
+		Extensions: map[string]interface{}{
+			"code":  code,
+			"value": value,
+			"limit": limit,
+		},
+	}
+}
+
+// shape summarizes a selection set
+type shape struct {
+	depth   int
+	fields  int
+	aliases int
+}
+
+// measure computes the shape of a selection set. Introspection fields are left out so that
+// tools can always load the schema.
+func measure(doc *ast.QueryDocument, set ast.SelectionSet) shape {
+	m := &measurer{doc: doc, fragments: make(map[string]shape)}
+	return m.selectionSet(set)
+}
+
+// measurer remembers the shape of each fragment, so that fragments spread many times
+// are only walked once
+type measurer struct {
+	doc       *ast.QueryDocument
+	fragments map[string]shape
+}
+
+func (m *measurer) selectionSet(set ast.SelectionSet) shape {
+	var s shape
+	for _, selection := range set {
+		var child shape
+		switch sel := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(sel.Name, "__") {
+				continue
+			}
+			child = m.selectionSet(sel.SelectionSet)
+			child.depth++
+			child.fields = safeAdd(child.fields, 1)
+			if sel.Alias != "" && sel.Alias != sel.Name {
+				child.aliases = safeAdd(child.aliases, 1)
+			}
+		case *ast.InlineFragment:
+			child = m.selectionSet(sel.SelectionSet)
+		case *ast.FragmentSpread:
+			child = m.fragment(sel.Name)
+		}
+		s.depth = max(s.depth, child.depth)
+		s.fields = safeAdd(s.fields, child.fields)
+		s.aliases = safeAdd(s.aliases, child.aliases)
+	}
+	return s
+}
+
+func (m *measurer) fragment(name string) shape {
+	if s, ok := m.fragments[name]; ok {
+		return s
+	}
+	// Validation has already refused fragment cycles, this only guards the recursion
+	m.fragments[name] = shape{}
+
+	var s shape
+	if fragment := m.doc.Fragments.ForName(name); fragment != nil {
+		s = m.selectionSet(fragment.SelectionSet)
+	}
+	m.fragments[name] = s
+	return s
+}
@@ -5,68 +5,208 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/gin-gonic/gin"
 	models "github.com/vnlab/makeshop-payment/src/domain/models"
 	"github.com/vnlab/makeshop-payment/src/infrastructure/auth"
+	"github.com/vnlab/makeshop-payment/src/usecase"
 )
 
+// Authorization errors returned to GraphQL clients
+var (
+	ErrNotAuthenticated       = errors.New("not authenticated")
+	ErrForbidden              = errors.New("forbidden")
+	ErrImpersonationForbidden = errors.New("not allowed while impersonating a user")
+)
+
+// Principal types of an authenticated request
+const (
+	PrincipalUser        = "user"         // A person logged in with an access token
+	PrincipalAPIKey      = "api_key"      // A merchant backend using an API key of its owner
+	PrincipalOAuthClient = "oauth_client" // A partner application with a client_credentials token
+)
+
+// authKeys are the context keys describing the authenticated principal
+var authKeys = []string{"authenticated", "principalType", "userId", "apiKeyId", "oauthClientId", "email", "roleId", "roleCode", "permissions", "sessionId", "token", "tokenExpiresAt", "actorId"}
+
+// apiKeyHeader carries an API key as an alternative to "Authorization: ApiKey <key>"
+const apiKeyHeader = "X-API-Key"
+
 // GraphQLAuthMiddleware creates a middleware for GraphQL authentication
-func GraphQLAuthMiddleware(jwtService *auth.JWTService) gin.HandlerFunc {
+func GraphQLAuthMiddleware(
+	jwtService *auth.JWTService,
+	sessionUsecase *usecase.SessionUsecase,
+	permissionUsecase *usecase.PermissionUsecase,
+	apiKeyUsecase *usecase.APIKeyUsecase,
+	oauthClientUsecase *usecase.OAuthClientUsecase,
+) gin.HandlerFunc {
+	a := &authenticator{jwtService, sessionUsecase, permissionUsecase, apiKeyUsecase, oauthClientUsecase}
+
 	return func(c *gin.Context) {
 		// For GraphQL, we don't want to abort the request if authentication fails
 		// Instead, we just set context values that resolvers can check
 		c.Set("authenticated", false)
 
-		// Get the Authorization header
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.Next()
-			return
+		ctx := usecase.WithClientInfo(c.Request.Context(), clientInfo(c))
+		for key, value := range a.authenticate(ctx, c.GetHeader("Authorization"), c.GetHeader(apiKeyHeader)) {
+			c.Set(key, value)
 		}
 
-		// Check if header has the correct format
-		headerParts := strings.Split(authHeader, " ")
-		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
-			c.Next()
-			return
+		c.Next()
+	}
+}
+
+// WebsocketInit authenticates a WebSocket connection with the "Authorization" or "X-API-Key" entry
+// of its connection_init payload, since browsers cannot set headers on the handshake.
+// Connections without credentials in the payload keep those of the handshake request.
+func WebsocketInit(
+	jwtService *auth.JWTService,
+	sessionUsecase *usecase.SessionUsecase,
+	permissionUsecase *usecase.PermissionUsecase,
+	apiKeyUsecase *usecase.APIKeyUsecase,
+	oauthClientUsecase *usecase.OAuthClientUsecase,
+) transport.WebsocketInitFunc {
+	a := &authenticator{jwtService, sessionUsecase, permissionUsecase, apiKeyUsecase, oauthClientUsecase}
+
+	return func(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+		authHeader := payload.Authorization()
+		apiKey := payload.GetString(apiKeyHeader)
+		if authHeader == "" && apiKey == "" {
+			return ctx, nil, nil
 		}
 
-		// Parse and validate the token
-		tokenString := headerParts[1]
-		
-		// Check if token is blacklisted
-		if jwtService.IsBlacklisted(tokenString) {
-			c.Next()
-			return
+		values := a.authenticate(ctx, authHeader, apiKey)
+		if len(values) == 0 {
+			return ctx, nil, ErrNotAuthenticated
 		}
-		
-		claims, err := jwtService.ValidateToken(tokenString)
-		if err != nil {
-			c.Next()
-			return
+		// Values of the handshake are replaced, including those the new principal does not have
+		for _, key := range authKeys {
+			ctx = context.WithValue(ctx, key, values[key])
 		}
+		return ctx, nil, nil
+	}
+}
+
+// authenticator checks the credentials of a request
+type authenticator struct {
+	jwtService         *auth.JWTService
+	sessionUsecase     *usecase.SessionUsecase
+	permissionUsecase  *usecase.PermissionUsecase
+	apiKeyUsecase      *usecase.APIKeyUsecase
+	oauthClientUsecase *usecase.OAuthClientUsecase
+}
 
-		// Set authentication information in context
-		c.Set("authenticated", true)
-		c.Set("userId", claims.UserID)
-		c.Set("email", claims.Email)
-		c.Set("roleId", claims.RoleID)
-		c.Set("roleCode", claims.RoleCode)
-		c.Set("token", tokenString) // Save token in context for logout
+// authenticate returns the authentication values of a request made with the given Authorization
+// header or API key, or nil if the credentials are missing or invalid
+func (a *authenticator) authenticate(ctx context.Context, authHeader, apiKey string) map[string]interface{} {
+	headerParts := strings.Split(authHeader, " ")
 
-		c.Next()
+	// API keys are accepted in their own header or with the ApiKey scheme
+	if apiKey == "" && len(headerParts) == 2 && headerParts[0] == "ApiKey" {
+		apiKey = headerParts[1]
+	}
+	if apiKey != "" {
+		return a.authenticateAPIKey(ctx, apiKey)
+	}
+
+	// Check if header has the correct format
+	if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+		return nil
+	}
+
+	// Parse and validate the token
+	tokenString := headerParts[1]
+
+	// Revoked tokens are rejected during validation
+	claims, err := a.jwtService.ValidateToken(ctx, tokenString)
+	if err != nil {
+		// Tokens issued to OAuth clients have their own type
+		return a.authenticateOAuthClient(ctx, tokenString)
+	}
+
+	// Tokens of a revoked session are rejected even before they expire
+	if err := a.sessionUsecase.ValidateSession(ctx, claims.UserID, claims.SessionID); err != nil {
+		return nil
+	}
+
+	// Permissions come from the role so that changes apply without a new token
+	permissions, err := a.permissionUsecase.PermissionCodes(ctx, claims.RoleID)
+	if err != nil {
+		return nil
+	}
+
+	values := map[string]interface{}{
+		"authenticated": true,
+		"principalType": PrincipalUser,
+		"userId":        claims.UserID,
+		"email":         claims.Email,
+		"roleId":        claims.RoleID,
+		"roleCode":      claims.RoleCode,
+		"sessionId":     claims.SessionID,
+		"permissions":   permissions,
+		"token":         tokenString, // Save token in context for logout
+	}
+	if claims.ExpiresAt != nil {
+		values["tokenExpiresAt"] = claims.ExpiresAt.Time
+	}
+	if claims.IsImpersonation() {
+		values["actorId"] = claims.Actor.UserID
+	}
+	return values
+}
+
+// authenticateAPIKey authenticates a request made with an API key.
+// The key acts for its owner with the permissions of its scopes only, and has no role or session.
+func (a *authenticator) authenticateAPIKey(ctx context.Context, key string) map[string]interface{} {
+	apiKey, permissions, err := a.apiKeyUsecase.Authenticate(ctx, key)
+	if err != nil {
+		return nil
+	}
+
+	return map[string]interface{}{
+		"authenticated": true,
+		"principalType": PrincipalAPIKey,
+		"userId":        apiKey.UserID,
+		"apiKeyId":      apiKey.ID,
+		"permissions":   permissions,
 	}
 }
 
-// WithAuth creates a GraphQL resolver context with auth information
+// authenticateOAuthClient authenticates a request made with an OAuth client token.
+// Like an API key, the client acts for its owner with the permissions of its scopes only.
+func (a *authenticator) authenticateOAuthClient(ctx context.Context, token string) map[string]interface{} {
+	claims, client, permissions, err := a.oauthClientUsecase.AuthenticateToken(ctx, token)
+	if err != nil {
+		return nil
+	}
+
+	return map[string]interface{}{
+		"authenticated": true,
+		"principalType": PrincipalOAuthClient,
+		"userId":        claims.UserID,
+		"oauthClientId": client.ID,
+		"permissions":   permissions,
+	}
+}
+
+// WithAuth creates a GraphQL resolver context with auth and client information
 func WithAuth(ctx context.Context, c *gin.Context) context.Context {
-	for _, key := range []string{"authenticated", "userId", "email", "roleId", "roleCode", "token"} {
+	for _, key := range authKeys {
 		if value, exists := c.Get(key); exists {
 			ctx = context.WithValue(ctx, key, value)
 		}
 	}
-	return ctx
+	return usecase.WithClientInfo(ctx, clientInfo(c))
+}
+
+// clientInfo describes the client of a Gin request
+func clientInfo(c *gin.Context) usecase.ClientInfo {
+	return usecase.ClientInfo{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
 }
 
 // CheckAuth checks if user is authenticated in GraphQL resolver context
@@ -80,48 +220,145 @@ func CheckAuth(ctx context.Context) error {
 
 // GetUserID extracts the user ID from context
 func GetUserID(ctx context.Context) (int, error) {
-    if err := CheckAuth(ctx); err != nil {
-        return 0, err
-    }
-    
-    userID, ok := ctx.Value("userId").(int)
-    if !ok {
-        return 0, errors.New("user ID not found in context")
-    }
-    
-    return userID, nil
+	if err := CheckAuth(ctx); err != nil {
+		return 0, err
+	}
+
+	userID, ok := ctx.Value("userId").(int)
+	if !ok {
+		return 0, errors.New("user ID not found in context")
+	}
+
+	return userID, nil
+}
+
+// GetSessionID extracts the session ID of the access token from context
+func GetSessionID(ctx context.Context) (string, error) {
+	if err := CheckAuth(ctx); err != nil {
+		return "", err
+	}
+
+	sessionID, ok := ctx.Value("sessionId").(string)
+	if !ok || sessionID == "" {
+		return "", errors.New("session ID not found in context")
+	}
+
+	return sessionID, nil
+}
+
+// GetTokenExpiry returns when the access token of the authenticated user expires
+func GetTokenExpiry(ctx context.Context) (time.Time, bool) {
+	if err := CheckAuth(ctx); err != nil {
+		return time.Time{}, false
+	}
+
+	expiresAt, ok := ctx.Value("tokenExpiresAt").(time.Time)
+	return expiresAt, ok
+}
+
+// GetActorID returns the ID of the administrator acting as the authenticated user,
+// and false when the request is not made under impersonation
+func GetActorID(ctx context.Context) (int, bool) {
+	if err := CheckAuth(ctx); err != nil {
+		return 0, false
+	}
+
+	actorID, ok := ctx.Value("actorId").(int)
+	return actorID, ok
+}
+
+// GetPrincipalType returns whether the request is made by a user, with an API key or by an OAuth client
+func GetPrincipalType(ctx context.Context) string {
+	if err := CheckAuth(ctx); err != nil {
+		return ""
+	}
+
+	principalType, _ := ctx.Value("principalType").(string)
+	return principalType
+}
+
+// IsUser checks if the request is made by a person rather than an API key or OAuth client
+func IsUser(ctx context.Context) bool {
+	return GetPrincipalType(ctx) == PrincipalUser
+}
+
+// IsAPIKey checks if the request is authenticated with an API key rather than by a user
+func IsAPIKey(ctx context.Context) bool {
+	return GetPrincipalType(ctx) == PrincipalAPIKey
+}
+
+// GetAPIKeyID returns the ID of the API key used for the request,
+// and false when the request is made by a user
+func GetAPIKeyID(ctx context.Context) (int, bool) {
+	if !IsAPIKey(ctx) {
+		return 0, false
+	}
+
+	apiKeyID, ok := ctx.Value("apiKeyId").(int)
+	return apiKeyID, ok
+}
+
+// GetOAuthClientID returns the ID of the OAuth client making the request,
+// and false when the request is not made by an OAuth client
+func GetOAuthClientID(ctx context.Context) (int, bool) {
+	if GetPrincipalType(ctx) != PrincipalOAuthClient {
+		return 0, false
+	}
+
+	clientID, ok := ctx.Value("oauthClientId").(int)
+	return clientID, ok
+}
+
+// IsImpersonating checks if an administrator is acting as the authenticated user
+func IsImpersonating(ctx context.Context) bool {
+	_, ok := GetActorID(ctx)
+	return ok
 }
 
 // GetUserEmail extracts the user email from context
 func GetUserEmail(ctx context.Context) (string, error) {
-    if err := CheckAuth(ctx); err != nil {
-        return "", err
-    }
-    
-    email, ok := ctx.Value("email").(string)
-    if !ok {
-        return "", errors.New("user email not found in context")
-    }
-    
-    return email, nil
+	if err := CheckAuth(ctx); err != nil {
+		return "", err
+	}
+
+	email, ok := ctx.Value("email").(string)
+	if !ok {
+		return "", errors.New("user email not found in context")
+	}
+
+	return email, nil
 }
 
 // CheckRoleCode verifies if the user has the required role code
 func CheckRoleCode(ctx context.Context, requiredCode string) error {
-    if err := CheckAuth(ctx); err != nil {
-        return err
-    }
-    
-    roleCode, ok := ctx.Value("roleCode").(string)
-    if !ok || roleCode != requiredCode {
-        return fmt.Errorf("permission denied: %s role required", requiredCode)
-    }
-    
-    return nil
+	if err := CheckAuth(ctx); err != nil {
+		return err
+	}
+
+	roleCode, ok := ctx.Value("roleCode").(string)
+	if !ok || roleCode != requiredCode {
+		return fmt.Errorf("permission denied: %s role required", requiredCode)
+	}
+
+	return nil
+}
+
+// HasPermission checks if the role of the authenticated user has been granted a permission
+func HasPermission(ctx context.Context, permission string) bool {
+	permissions, ok := ctx.Value("permissions").([]string)
+	if !ok {
+		return false
+	}
+	for _, granted := range permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
 }
 
 // IsAdminRole checks if the authenticated user has admin role
 func IsAdminRole(ctx context.Context) bool {
-    roleCode, ok := ctx.Value("roleCode").(string)
-    return ok && roleCode == string(models.RoleCodeAdmin)
+	roleCode, ok := ctx.Value("roleCode").(string)
+	return ok && roleCode == string(models.RoleCodeAdmin)
 }
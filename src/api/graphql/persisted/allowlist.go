@@ -0,0 +1,111 @@
+package persisted
+
+import (
+	"context"
+	"log"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/mitchellh/mapstructure"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+)
+
+// Error codes set in the "code" extension of a refused operation
+const (
+	CodeOperationNotAllowed   = "OPERATION_NOT_ALLOWED"
+	CodeInvalidPersistedQuery = "INVALID_PERSISTED_QUERY"
+)
+
+// allowlistCacheSize is the number of registered documents kept in memory
+const allowlistCacheSize = 1000
+
+// Allowlist is a gqlgen handler extension executing only the documents registered with the
+// register-queries command. Clients may send the document, its hash in the APQ extension, or both.
+type Allowlist struct {
+	repo       repositories.PersistedQueryRepository
+	registered *lru.LRU
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationParameterMutator
+} = (*Allowlist)(nil)
+
+// NewAllowlist creates a new Allowlist
+func NewAllowlist(repo repositories.PersistedQueryRepository) *Allowlist {
+	return &Allowlist{
+		repo:       repo,
+		registered: lru.New(allowlistCacheSize),
+	}
+}
+
+// ExtensionName implements graphql.HandlerExtension
+func (a *Allowlist) ExtensionName() string {
+	return "PersistedQueryAllowlist"
+}
+
+// Validate implements graphql.HandlerExtension
+func (a *Allowlist) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+// MutateOperationParameters replaces the request by the registered document, or refuses it
+func (a *Allowlist) MutateOperationParameters(ctx context.Context, rawParams *graphql.RawParams) *gqlerror.Error {
+	hash := ""
+	if rawParams.Extensions["persistedQuery"] != nil {
+		var extension struct {
+			Sha256  string `mapstructure:"sha256Hash"`
+			Version int64  `mapstructure:"version"`
+		}
+		if err := mapstructure.Decode(rawParams.Extensions["persistedQuery"], &extension); err != nil || extension.Version != 1 {
+			return refused(CodeInvalidPersistedQuery, "invalid persisted query extension")
+		}
+		hash = extension.Sha256
+	}
+
+	if rawParams.Query != "" {
+		if hash != "" && hash != Hash(rawParams.Query) {
+			return refused(CodeInvalidPersistedQuery, "persisted query hash does not match the query")
+		}
+		hash = Hash(rawParams.Query)
+	}
+	if hash == "" {
+		return refused(CodeOperationNotAllowed, "operation is not allowed")
+	}
+
+	query, ok := a.lookup(ctx, hash)
+	if !ok {
+		return refused(CodeOperationNotAllowed, "operation is not allowed")
+	}
+	rawParams.Query = query
+	return nil
+}
+
+// lookup finds a registered document. Documents that are not registered are looked up
+// again on each request, so that newly registered ones are picked up without a restart,
+// while removing a document from the allowlist takes effect once the server restarts.
+func (a *Allowlist) lookup(ctx context.Context, hash string) (string, bool) {
+	if query, ok := a.registered.Get(ctx, hash); ok {
+		return query.(string), true
+	}
+
+	query, err := a.repo.FindByHash(ctx, hash)
+	if err != nil {
+		log.Printf("Failed to load persisted query: %v", err)
+		return "", false
+	}
+	if query == nil || !query.Registered {
+		return "", false
+	}
+	a.registered.Add(ctx, hash, query.Query)
+	return query.Query, true
+}
+
+// refused describes an operation that may not be executed
+func refused(code, message string) *gqlerror.Error {
+	return &gqlerror.Error{
+		Message:    message,
+		Extensions: map[string]interface{}{"code": code},
+	}
+}
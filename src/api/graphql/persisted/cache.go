@@ -0,0 +1,129 @@
+// Package persisted lets clients send the hash of a GraphQL document instead of its text,
+// either through automatic persisted queries or from an allowlist registered ahead of time
+package persisted
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+)
+
+const (
+	// maxQueryLength bounds the documents stored on behalf of clients
+	maxQueryLength = 64 << 10
+
+	// pruneInterval is how often an instance trims the documents stored on behalf of clients
+	pruneInterval = time.Minute
+)
+
+// Hash returns the key a document is persisted under, as computed by APQ clients
+func Hash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// CacheConfig selects and sizes the APQ cache
+type CacheConfig struct {
+	Kind      string        // memory, or mysql to share documents between instances
+	Size      int           // Documents kept in memory by each instance
+	StoreSize int           // Documents sent by clients kept in the database
+	StoreTTL  time.Duration // How long the database keeps a document no client has sent again
+}
+
+// NewCache creates the APQ cache selected by the configuration: "memory" keeps the most
+// recently used documents of each instance, "mysql" also shares them between instances.
+// The documents clients send are bounded in number and age, so that anonymous clients
+// cannot grow the database without limit.
+func NewCache(config CacheConfig, repo repositories.PersistedQueryRepository) (graphql.Cache, error) {
+	if config.Size < 1 {
+		return nil, fmt.Errorf("persisted query cache size must be positive")
+	}
+
+	switch config.Kind {
+	case "memory":
+		return lru.New(config.Size), nil
+	case "mysql":
+		if config.StoreSize < 1 || config.StoreTTL <= 0 {
+			return nil, fmt.Errorf("persisted query store size and TTL must be positive")
+		}
+		return &repositoryCache{
+			repo:      repo,
+			recent:    lru.New(config.Size),
+			storeSize: config.StoreSize,
+			storeTTL:  config.StoreTTL,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown persisted query cache: %s", config.Kind)
+	}
+}
+
+// repositoryCache stores documents in the database, keeping the most recently used in memory
+type repositoryCache struct {
+	repo      repositories.PersistedQueryRepository
+	recent    *lru.LRU
+	storeSize int
+	storeTTL  time.Duration
+
+	lastPrune atomic.Int64 // Unix nanoseconds of the last pruning started by this instance
+	pruning   atomic.Bool
+}
+
+// Get looks up a document by its hash, first among the recently used ones.
+// Documents sent by clients are ignored once they have expired.
+func (c *repositoryCache) Get(ctx context.Context, hash string) (interface{}, bool) {
+	if query, ok := c.recent.Get(ctx, hash); ok {
+		return query, true
+	}
+
+	query, err := c.repo.FindByHash(ctx, hash)
+	if err != nil {
+		log.Printf("Failed to load persisted query: %v", err)
+		return nil, false
+	}
+	if query == nil || (!query.Registered && time.Since(query.UpdatedAt) > c.storeTTL) {
+		return nil, false
+	}
+	c.recent.Add(ctx, hash, query.Query)
+	return query.Query, true
+}
+
+// Add stores a document sent by a client. Failures are only logged: the client sends the
+// document again on its next request.
+func (c *repositoryCache) Add(ctx context.Context, hash string, value interface{}) {
+	query, ok := value.(string)
+	if !ok || len(query) > maxQueryLength {
+		return
+	}
+
+	c.recent.Add(ctx, hash, query)
+	if err := c.repo.Save(ctx, &models.PersistedQuery{Hash: hash, Query: query}); err != nil {
+		log.Printf("Failed to save persisted query: %v", err)
+	}
+	c.pruneIfDue()
+}
+
+// pruneIfDue removes expired documents and those past the store size in the background,
+// at most once per pruneInterval
+func (c *repositoryCache) pruneIfDue() {
+	now := time.Now()
+	if now.Sub(time.Unix(0, c.lastPrune.Load())) < pruneInterval || !c.pruning.CompareAndSwap(false, true) {
+		return
+	}
+	c.lastPrune.Store(now.UnixNano())
+
+	go func() {
+		defer c.pruning.Store(false)
+		if _, err := c.repo.PruneUnregistered(context.Background(), now.Add(-c.storeTTL), c.storeSize); err != nil {
+			log.Printf("Failed to prune persisted queries: %v", err)
+		}
+	}()
+}
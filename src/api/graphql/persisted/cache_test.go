@@ -0,0 +1,120 @@
+package persisted
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+)
+
+// fakeRepo keeps persisted queries in memory, as shared by several instances
+type fakeRepo struct {
+	repositories.PersistedQueryRepository
+	mu      sync.Mutex
+	queries map[string]*models.PersistedQuery
+	pruned  chan struct{}
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{queries: make(map[string]*models.PersistedQuery), pruned: make(chan struct{}, 1)}
+}
+
+func (r *fakeRepo) FindByHash(ctx context.Context, hash string) (*models.PersistedQuery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	query, ok := r.queries[hash]
+	if !ok {
+		return nil, nil
+	}
+	copied := *query
+	return &copied, nil
+}
+
+func (r *fakeRepo) Save(ctx context.Context, query *models.PersistedQuery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.queries[query.Hash]; ok {
+		existing.UpdatedAt = time.Now()
+		return nil
+	}
+	query.UpdatedAt = time.Now()
+	r.queries[query.Hash] = query
+	return nil
+}
+
+func (r *fakeRepo) PruneUnregistered(ctx context.Context, before time.Time, keep int) (int64, error) {
+	r.pruned <- struct{}{}
+	return 0, nil
+}
+
+func newTestCache(t *testing.T, repo *fakeRepo) *repositoryCache {
+	t.Helper()
+	cache, err := NewCache(CacheConfig{Kind: "mysql", Size: 10, StoreSize: 100, StoreTTL: time.Hour}, repo)
+	if err != nil {
+		t.Fatalf("NewCache: %v", err)
+	}
+	return cache.(*repositoryCache)
+}
+
+func TestRepositoryCacheSharesClientDocuments(t *testing.T) {
+	repo := newFakeRepo()
+	ctx := context.Background()
+	query := "{ me { id } }"
+
+	newTestCache(t, repo).Add(ctx, Hash(query), query)
+
+	// Another instance finds the document in the database
+	got, ok := newTestCache(t, repo).Get(ctx, Hash(query))
+	if !ok || got != query {
+		t.Fatalf("expected the document on another instance, got %v, %v", got, ok)
+	}
+}
+
+func TestRepositoryCacheIgnoresExpiredClientDocuments(t *testing.T) {
+	repo := newFakeRepo()
+	ctx := context.Background()
+	repo.queries["client"] = &models.PersistedQuery{Hash: "client", Query: "{ a }", UpdatedAt: time.Now().Add(-2 * time.Hour)}
+	repo.queries["registered"] = &models.PersistedQuery{Hash: "registered", Query: "{ b }", Registered: true, UpdatedAt: time.Now().Add(-2 * time.Hour)}
+
+	cache := newTestCache(t, repo)
+	if _, ok := cache.Get(ctx, "client"); ok {
+		t.Errorf("expired client document was served")
+	}
+	if _, ok := cache.Get(ctx, "registered"); !ok {
+		t.Errorf("registered document expired")
+	}
+}
+
+func TestRepositoryCachePrunesAtMostOncePerInterval(t *testing.T) {
+	repo := newFakeRepo()
+	cache := newTestCache(t, repo)
+	ctx := context.Background()
+
+	cache.Add(ctx, Hash("{ a }"), "{ a }")
+	select {
+	case <-repo.pruned:
+	case <-time.After(time.Second):
+		t.Fatal("the store was not pruned")
+	}
+
+	cache.Add(ctx, Hash("{ b }"), "{ b }")
+	select {
+	case <-repo.pruned:
+		t.Error("the store was pruned again within the interval")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestNewCacheRefusesUnboundedStore(t *testing.T) {
+	for _, config := range []CacheConfig{
+		{Kind: "mysql", Size: 10, StoreSize: 0, StoreTTL: time.Hour},
+		{Kind: "mysql", Size: 10, StoreSize: 100, StoreTTL: 0},
+	} {
+		if _, err := NewCache(config, newFakeRepo()); err == nil {
+			t.Errorf("expected %+v to be refused", config)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Package presenter turns the errors returned by resolvers into GraphQL errors carrying
+// a code that clients can match on instead of the message
+package presenter
+
+import (
+	"context"
+	"errors"
+	"log"
+	"runtime/debug"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"github.com/vnlab/makeshop-payment/src/api/graphql/middleware"
+	apperrors "github.com/vnlab/makeshop-payment/src/infrastructure/errors"
+	httpmiddleware "github.com/vnlab/makeshop-payment/src/infrastructure/middleware"
+	"github.com/vnlab/makeshop-payment/src/usecase"
+)
+
+const (
+	// internalErrorMessage replaces the message of unexpected errors in release mode
+	internalErrorMessage = "An unexpected error occurred"
+
+	// panicMessage is reported when a resolver panics
+	panicMessage = "Internal server error"
+
+	// validationMessage is reported for requests refused by the validator
+	validationMessage = "Validation failed"
+)
+
+// sentinelCodes gives a code to the errors that use cases return as plain values.
+// Their messages are written for clients, so they are shown in every mode.
+var sentinelCodes = []struct {
+	err  error
+	code string
+}{
+	{middleware.ErrNotAuthenticated, apperrors.CodeUnauthorized},
+	{middleware.ErrForbidden, apperrors.CodeForbidden},
+	{middleware.ErrImpersonationForbidden, apperrors.CodeForbidden},
+	{usecase.ErrOTPSentRecently, apperrors.CodeTooManyRequests},
+	{usecase.ErrOTPTooManyRequests, apperrors.CodeTooManyRequests},
+	{usecase.ErrInvalidRefreshToken, apperrors.CodeUnauthorized},
+	{usecase.ErrUserNotFound, apperrors.CodeResourceNotFound},
+	{usecase.ErrLastAdmin, apperrors.CodeBadRequest},
+	{usecase.ErrCannotModifySelf, apperrors.CodeForbidden},
+	{usecase.ErrCannotChangeOwnRole, apperrors.CodeForbidden},
+	{usecase.ErrAdminRoleRequired, apperrors.CodeForbidden},
+	{usecase.ErrInvalidClient, apperrors.CodeUnauthorized},
+	{usecase.ErrOAuthClientNotFound, apperrors.CodeResourceNotFound},
+	{usecase.ErrInvalidPasswordResetToken, apperrors.CodeBadRequest},
+	{usecase.ErrRoleNotFound, apperrors.CodeResourceNotFound},
+	{usecase.ErrPermissionNotFound, apperrors.CodeResourceNotFound},
+	{usecase.ErrLastRoleManager, apperrors.CodeBadRequest},
+	{usecase.ErrInvalidScope, apperrors.CodeValidationError},
+	{usecase.ErrInvalidEmailVerificationToken, apperrors.CodeBadRequest},
+	{usecase.ErrEmailNotVerified, apperrors.CodeForbidden},
+	{usecase.ErrEmailVerificationTooMany, apperrors.CodeTooManyRequests},
+	{usecase.ErrImpersonationNotAllowed, apperrors.CodeForbidden},
+	{usecase.ErrImpersonationReason, apperrors.CodeValidationError},
+	{usecase.ErrInvalidAPIKey, apperrors.CodeUnauthorized},
+	{usecase.ErrAPIKeyNotFound, apperrors.CodeResourceNotFound},
+	{usecase.ErrSSODisabled, apperrors.CodeForbidden},
+	{usecase.ErrInvalidSSOState, apperrors.CodeBadRequest},
+	{usecase.ErrSSOFailed, apperrors.CodeBadRequest},
+	{usecase.ErrSSODenied, apperrors.CodeForbidden},
+	{usecase.ErrSessionNotFound, apperrors.CodeResourceNotFound},
+	{usecase.ErrSessionRevoked, apperrors.CodeUnauthorized},
+	{usecase.ErrInvalidPasskey, apperrors.CodeInvalidCredentials},
+	{usecase.ErrPasskeyRequired, apperrors.CodeForbidden},
+	{usecase.ErrInvalidMFAToken, apperrors.CodeUnauthorized},
+	{usecase.ErrInvalidVerificationCode, apperrors.CodeInvalidCredentials},
+}
+
+// traceIDKey is the context key for the trace ID of the request
+type traceIDKey struct{}
+
+// WithTraceID returns a context carrying the trace ID of the request
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// TraceID returns the trace ID of the request, or an empty string outside of a request
+func TraceID(ctx context.Context) string {
+	traceID, _ := ctx.Value(traceIDKey{}).(string)
+	return traceID
+}
+
+// ErrorPresenter implements graphql.ErrorPresenterFunc. Every error gets the extensions
+// { code, details, traceId }; errors with no known meaning are reported as INTERNAL_ERROR
+// and, in release mode, without their message.
+func ErrorPresenter(ctx context.Context, err error) *gqlerror.Error {
+	// The error may be shared, so the copy is the one annotated
+	presented := *graphql.DefaultErrorPresenter(ctx, err)
+	extensions := make(map[string]interface{}, len(presented.Extensions)+3)
+	for key, value := range presented.Extensions {
+		extensions[key] = value
+	}
+	presented.Extensions = extensions
+
+	var customErr *httpmiddleware.CustomError
+	var validationErrs validator.ValidationErrors
+	switch {
+	case errors.As(err, &customErr):
+		presented.Message = customErr.Message
+		extensions["code"] = customErr.Code
+		if len(customErr.Details) > 0 {
+			extensions["details"] = customErr.Details
+		}
+		if customErr.Err != nil && customErr.Status >= 500 {
+			log.Printf("GraphQL error (trace %s): %v", TraceID(ctx), customErr)
+		}
+	case errors.As(err, &validationErrs):
+		presented.Message = validationMessage
+		extensions["code"] = apperrors.CodeValidationError
+		extensions["details"] = validationDetails(validationErrs)
+	case presented.Err == nil:
+		// Errors raised by gqlgen itself, such as parse and validation failures, are already
+		// meant for clients and may carry a code of their own
+	default:
+		code, ok := sentinelCode(err)
+		if !ok {
+			log.Printf("GraphQL error (trace %s): %v", TraceID(ctx), err)
+			code = apperrors.CodeInternalError
+			if gin.Mode() == gin.ReleaseMode {
+				presented.Message = internalErrorMessage
+			}
+		}
+		extensions["code"] = code
+	}
+
+	if traceID := TraceID(ctx); traceID != "" {
+		extensions["traceId"] = traceID
+	}
+	return &presented
+}
+
+// Recover implements graphql.RecoverFunc. The panic is logged with its stack and the client
+// only learns that the field failed.
+func Recover(ctx context.Context, err interface{}) error {
+	log.Printf("Panic recovered in GraphQL resolver (trace %s): %v\n%s", TraceID(ctx), err, debug.Stack())
+	return apperrors.Internal(panicMessage, nil)
+}
+
+// sentinelCode returns the code of a plain use case error
+func sentinelCode(err error) (string, bool) {
+	for _, sentinel := range sentinelCodes {
+		if errors.Is(err, sentinel.err) {
+			return sentinel.code, true
+		}
+	}
+	return "", false
+}
+
+// validationDetails maps each invalid field, named as in the GraphQL input, to the rule it broke
+func validationDetails(errs validator.ValidationErrors) map[string]string {
+	details := make(map[string]string, len(errs))
+	for _, fieldErr := range errs {
+		rule := fieldErr.Tag()
+		if fieldErr.Param() != "" {
+			rule += "=" + fieldErr.Param()
+		}
+		details[lowerFirst(fieldErr.StructField())] = rule
+	}
+	return details
+}
+
+// lowerFirst turns a Go field name such as FirstName into its GraphQL name firstName
+func lowerFirst(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(r)) + name[size:]
+}
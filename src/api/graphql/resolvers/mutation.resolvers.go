@@ -2,6 +2,8 @@ package resolvers
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 
 	"github.com/vnlab/makeshop-payment/src/api/graphql/generated"
 	"github.com/vnlab/makeshop-payment/src/api/graphql/middleware"
@@ -30,12 +32,47 @@ func (r *mutationResolver) Login(ctx context.Context, input generated.LoginInput
 		return nil, err
 	}
 
-	return &generated.AuthResponse{
-		Token: loginResp.Token,
-		User:  loginResp.User,
+	return toAuthResponse(loginResp), nil
+}
+
+// VerifyMfa implements the verifyMfa mutation
+func (r *mutationResolver) VerifyMfa(ctx context.Context, input generated.VerifyMFAInput) (*generated.AuthResponse, error) {
+	verifyReq := usecase.VerifyMFARequest{
+		MFAToken: input.MfaToken,
+		Code:     input.Code,
+	}
+
+	loginResp, err := r.mfaUsecase.VerifyChallenge(ctx, verifyReq)
+	if err != nil {
+		return nil, err
+	}
+
+	return toAuthResponse(loginResp), nil
+}
+
+// BeginSsoLogin implements the beginSsoLogin mutation
+func (r *mutationResolver) BeginSsoLogin(ctx context.Context) (*generated.SsoLogin, error) {
+	login, err := r.ssoUsecase.BeginLogin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &generated.SsoLogin{
+		AuthorizationURL: login.AuthorizationURL,
+		State:            login.State,
 	}, nil
 }
 
+// CompleteSsoLogin implements the completeSsoLogin mutation
+func (r *mutationResolver) CompleteSsoLogin(ctx context.Context, input generated.CompleteSsoLoginInput) (*generated.AuthResponse, error) {
+	loginResp, err := r.ssoUsecase.CompleteLogin(ctx, input.Code, input.State)
+	if err != nil {
+		return nil, err
+	}
+
+	return toAuthResponse(loginResp), nil
+}
+
 // Register implements the register mutation
 func (r *mutationResolver) Register(ctx context.Context, input generated.RegisterInput) (*models.User, error) {
 	registerReq := usecase.RegisterRequest{
@@ -57,11 +94,6 @@ func (r *mutationResolver) Register(ctx context.Context, input generated.Registe
 
 // UpdateProfile implements the updateProfile mutation
 func (r *mutationResolver) UpdateProfile(ctx context.Context, input generated.UpdateProfileInput) (*models.User, error) {
-	err := middleware.CheckAuth(ctx)
-	if err != nil {
-		return nil, ErrNotAuthenticated
-	}
-	
 	userId, err := middleware.GetUserID(ctx)
 	if err != nil {
 		return nil, ErrNotAuthenticated
@@ -84,18 +116,148 @@ func (r *mutationResolver) UpdateProfile(ctx context.Context, input generated.Up
 
 // ChangePassword implements the changePassword mutation
 func (r *mutationResolver) ChangePassword(ctx context.Context, input generated.ChangePasswordInput) (bool, error) {
-	err := middleware.CheckAuth(ctx)
+	userId, err := middleware.GetUserID(ctx)
 	if err != nil {
 		return false, ErrNotAuthenticated
 	}
-	
+
+	err = r.userUsecase.ChangePassword(ctx, userId, input.CurrentPassword, input.NewPassword)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ChangeEmail implements the changeEmail mutation
+func (r *mutationResolver) ChangeEmail(ctx context.Context, input generated.ChangeEmailInput) (bool, error) {
 	userId, err := middleware.GetUserID(ctx)
 	if err != nil {
 		return false, ErrNotAuthenticated
 	}
 
-	err = r.userUsecase.ChangePassword(ctx, userId, input.CurrentPassword, input.NewPassword)
+	err = r.emailVerificationUsecase.RequestEmailChange(ctx, userId, input.NewEmail, input.CurrentPassword)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// RevokeSession implements the revokeSession mutation
+func (r *mutationResolver) RevokeSession(ctx context.Context, id string) (bool, error) {
+	userId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return false, ErrNotAuthenticated
+	}
+
+	if err := r.sessionUsecase.RevokeSession(ctx, userId, id); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// RevokeOtherSessions implements the revokeOtherSessions mutation
+func (r *mutationResolver) RevokeOtherSessions(ctx context.Context) (int, error) {
+	userId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return 0, ErrNotAuthenticated
+	}
+
+	sessionId, err := middleware.GetSessionID(ctx)
+	if err != nil {
+		return 0, ErrNotAuthenticated
+	}
+
+	return r.sessionUsecase.RevokeOtherSessions(ctx, userId, sessionId)
+}
+
+// CreateAPIKey implements the createApiKey mutation
+func (r *mutationResolver) CreateAPIKey(ctx context.Context, input generated.CreateAPIKeyInput) (*generated.APIKeyCreated, error) {
+	userId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+
+	apiKey, key, err := r.apiKeyUsecase.CreateAPIKey(ctx, userId, usecase.CreateAPIKeyRequest{
+		Name:       input.Name,
+		Scopes:     input.Scopes,
+		AllowedIPs: input.AllowedIps,
+		ExpiresAt:  input.ExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &generated.APIKeyCreated{
+		APIKey: apiKey,
+		Key:    key,
+	}, nil
+}
+
+// RevokeAPIKey implements the revokeApiKey mutation
+func (r *mutationResolver) RevokeAPIKey(ctx context.Context, id int) (bool, error) {
+	userId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return false, ErrNotAuthenticated
+	}
+
+	if err := r.apiKeyUsecase.RevokeAPIKey(ctx, userId, id); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// CreateOAuthClient implements the createOAuthClient mutation
+func (r *mutationResolver) CreateOAuthClient(ctx context.Context, input generated.CreateOAuthClientInput) (*generated.OAuthClientCredentials, error) {
+	adminId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+
+	client, secret, err := r.oauthClientUsecase.CreateClient(ctx, adminId, usecase.CreateOAuthClientRequest{
+		Name:   input.Name,
+		UserID: input.UserID,
+		Scopes: input.Scopes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &generated.OAuthClientCredentials{
+		Client:       client,
+		ClientSecret: secret,
+	}, nil
+}
+
+// RotateOAuthClientSecret implements the rotateOAuthClientSecret mutation
+func (r *mutationResolver) RotateOAuthClientSecret(ctx context.Context, id int) (*generated.OAuthClientCredentials, error) {
+	adminId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+
+	client, secret, err := r.oauthClientUsecase.RotateSecret(ctx, adminId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &generated.OAuthClientCredentials{
+		Client:       client,
+		ClientSecret: secret,
+	}, nil
+}
+
+// RevokeOAuthClient implements the revokeOAuthClient mutation
+func (r *mutationResolver) RevokeOAuthClient(ctx context.Context, id int) (bool, error) {
+	adminId, err := middleware.GetUserID(ctx)
 	if err != nil {
+		return false, ErrNotAuthenticated
+	}
+
+	if err := r.oauthClientUsecase.RevokeClient(ctx, adminId, id); err != nil {
 		return false, err
 	}
 
@@ -103,19 +265,481 @@ func (r *mutationResolver) ChangePassword(ctx context.Context, input generated.C
 }
 
 // Logout implements the logout mutation
-func (r *mutationResolver) Logout(ctx context.Context) (bool, error) {
-	err := middleware.CheckAuth(ctx)
+func (r *mutationResolver) Logout(ctx context.Context, input *generated.LogoutInput) (bool, error) {
+	userId, err := middleware.GetUserID(ctx)
 	if err != nil {
 		return false, ErrNotAuthenticated
 	}
 
+	// Revoke the refresh token so the session cannot be renewed
+	if input != nil && input.RefreshToken != nil && *input.RefreshToken != "" {
+		if err := r.tokenUsecase.Revoke(ctx, userId, *input.RefreshToken); err != nil {
+			return false, err
+		}
+	}
+
+	// End the current session so its other tokens stop working as well
+	if sessionId, err := middleware.GetSessionID(ctx); err == nil {
+		if err := r.sessionUsecase.RevokeSession(ctx, userId, sessionId); err != nil {
+			return false, err
+		}
+	}
+
 	// Get token from context (added by middleware)
 	token, ok := ctx.Value("token").(string)
 	if ok && token != "" {
-		// Add token to blacklist
-		r.jwtService.BlacklistToken(token)
+		// Revoke the access token on every replica
+		if err := r.jwtService.RevokeToken(ctx, token); err != nil {
+			return false, err
+		}
 	}
-	
+
 	// Return true to confirm successful logout
 	return true, nil
 }
+
+// RefreshToken implements the refreshToken mutation
+func (r *mutationResolver) RefreshToken(ctx context.Context, input generated.RefreshTokenInput) (*generated.AuthResponse, error) {
+	loginResp, err := r.tokenUsecase.Refresh(ctx, input.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return toAuthResponse(loginResp), nil
+}
+
+// RequestPasswordReset implements the requestPasswordReset mutation
+func (r *mutationResolver) RequestPasswordReset(ctx context.Context, email string) (bool, error) {
+	if err := r.passwordResetUsecase.RequestPasswordReset(ctx, email); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ResetPassword implements the resetPassword mutation
+func (r *mutationResolver) ResetPassword(ctx context.Context, token string, newPassword string) (bool, error) {
+	if err := r.passwordResetUsecase.ResetPassword(ctx, token, newPassword); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// VerifyEmail implements the verifyEmail mutation
+func (r *mutationResolver) VerifyEmail(ctx context.Context, token string) (bool, error) {
+	if _, err := r.emailVerificationUsecase.VerifyEmail(ctx, token); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ResendVerificationEmail implements the resendVerificationEmail mutation
+func (r *mutationResolver) ResendVerificationEmail(ctx context.Context, email string) (bool, error) {
+	if err := r.emailVerificationUsecase.ResendVerification(ctx, email); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ResendMfaCode implements the resendMfaCode mutation
+func (r *mutationResolver) ResendMfaCode(ctx context.Context, mfaToken string) (bool, error) {
+	if err := r.mfaUsecase.ResendChallengeCode(ctx, mfaToken); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// BeginPasskeyLogin implements the beginPasskeyLogin mutation
+func (r *mutationResolver) BeginPasskeyLogin(ctx context.Context, mfaToken *string) (string, error) {
+	token := ""
+	if mfaToken != nil {
+		token = *mfaToken
+	}
+
+	options, err := r.mfaUsecase.BeginPasskeyLogin(ctx, token)
+	if err != nil {
+		return "", err
+	}
+
+	return toJSONString(options)
+}
+
+// LoginWithPasskey implements the loginWithPasskey mutation
+func (r *mutationResolver) LoginWithPasskey(ctx context.Context, credential string) (*generated.AuthResponse, error) {
+	loginResp, err := r.mfaUsecase.LoginWithPasskey(ctx, credential)
+	if err != nil {
+		return nil, err
+	}
+
+	return toAuthResponse(loginResp), nil
+}
+
+// EnrollTotp implements the enrollTotp mutation
+func (r *mutationResolver) EnrollTotp(ctx context.Context, input *generated.ReauthenticationInput) (*generated.TOTPEnrollment, error) {
+	userId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+
+	enrollment, err := r.mfaUsecase.EnrollTOTP(ctx, userId, toReauthenticationRequest(input))
+	if err != nil {
+		return nil, err
+	}
+
+	return &generated.TOTPEnrollment{
+		Secret:     enrollment.Secret,
+		OtpauthURI: enrollment.URI,
+		QRCodePng:  base64.StdEncoding.EncodeToString(enrollment.QRCodePNG),
+	}, nil
+}
+
+// ConfirmTotp implements the confirmTotp mutation
+func (r *mutationResolver) ConfirmTotp(ctx context.Context, input generated.ConfirmTOTPInput) ([]string, error) {
+	userId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+
+	return r.mfaUsecase.ConfirmTOTP(ctx, userId, input.Code)
+}
+
+// RegenerateRecoveryCodes implements the regenerateRecoveryCodes mutation
+func (r *mutationResolver) RegenerateRecoveryCodes(ctx context.Context, input generated.ReauthenticationInput) ([]string, error) {
+	userId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+
+	return r.mfaUsecase.RegenerateRecoveryCodes(ctx, userId, toReauthenticationRequest(&input))
+}
+
+// RegisterPhoneNumber implements the registerPhoneNumber mutation
+func (r *mutationResolver) RegisterPhoneNumber(ctx context.Context, input generated.RegisterPhoneNumberInput) (bool, error) {
+	userId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return false, ErrNotAuthenticated
+	}
+
+	if err := r.mfaUsecase.RegisterPhoneNumber(ctx, userId, input.PhoneNumber); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// VerifyPhoneNumber implements the verifyPhoneNumber mutation
+func (r *mutationResolver) VerifyPhoneNumber(ctx context.Context, input generated.VerifyPhoneNumberInput) (*models.User, error) {
+	userId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+
+	useForMFA := input.UseForMfa != nil && *input.UseForMfa
+	return r.mfaUsecase.VerifyPhoneNumber(ctx, userId, input.Code, useForMFA)
+}
+
+// SendMfaCode implements the sendMfaCode mutation
+func (r *mutationResolver) SendMfaCode(ctx context.Context) (bool, error) {
+	userId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return false, ErrNotAuthenticated
+	}
+
+	if err := r.mfaUsecase.SendReauthenticationCode(ctx, userId); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// UpdateMfaSettings implements the updateMfaSettings mutation
+func (r *mutationResolver) UpdateMfaSettings(ctx context.Context, input generated.MFASettingsInput) (*models.User, error) {
+	userId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+
+	updateReq := usecase.UpdateMFASettingsRequest{
+		Enabled: input.Enabled,
+		TypeID:  input.TypeID,
+	}
+	if input.CurrentPassword != nil {
+		updateReq.CurrentPassword = *input.CurrentPassword
+	}
+	if input.MfaCode != nil {
+		updateReq.MFACode = *input.MfaCode
+	}
+
+	return r.mfaUsecase.UpdateMFASettings(ctx, userId, updateReq)
+}
+
+// BeginPasskeyRegistration implements the beginPasskeyRegistration mutation
+func (r *mutationResolver) BeginPasskeyRegistration(ctx context.Context) (string, error) {
+	userId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return "", ErrNotAuthenticated
+	}
+
+	options, err := r.mfaUsecase.BeginPasskeyRegistration(ctx, userId)
+	if err != nil {
+		return "", err
+	}
+
+	return toJSONString(options)
+}
+
+// FinishPasskeyRegistration implements the finishPasskeyRegistration mutation
+func (r *mutationResolver) FinishPasskeyRegistration(ctx context.Context, input generated.FinishPasskeyRegistrationInput) (*models.Passkey, error) {
+	userId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+
+	req := usecase.RegisterPasskeyRequest{
+		Credential: input.Credential,
+	}
+	if input.Name != nil {
+		req.Name = *input.Name
+	}
+	if input.CurrentPassword != nil {
+		req.CurrentPassword = *input.CurrentPassword
+	}
+	if input.MfaCode != nil {
+		req.MFACode = *input.MfaCode
+	}
+
+	return r.mfaUsecase.FinishPasskeyRegistration(ctx, userId, req)
+}
+
+// BeginPasskeyReauthentication implements the beginPasskeyReauthentication mutation
+func (r *mutationResolver) BeginPasskeyReauthentication(ctx context.Context) (string, error) {
+	userId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return "", ErrNotAuthenticated
+	}
+
+	options, err := r.mfaUsecase.BeginPasskeyReauthentication(ctx, userId)
+	if err != nil {
+		return "", err
+	}
+
+	return toJSONString(options)
+}
+
+// DeletePasskey implements the deletePasskey mutation
+func (r *mutationResolver) DeletePasskey(ctx context.Context, id int) (bool, error) {
+	userId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return false, ErrNotAuthenticated
+	}
+
+	if err := r.mfaUsecase.DeletePasskey(ctx, userId, id); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// AdminResetMfa implements the adminResetMfa mutation
+func (r *mutationResolver) AdminResetMfa(ctx context.Context, input generated.AdminResetMFAInput) (*models.User, error) {
+	adminId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+
+	return r.mfaUsecase.AdminResetMFA(ctx, adminId, input.UserID, input.Reason)
+}
+
+// GrantPermission implements the grantPermission mutation
+func (r *mutationResolver) GrantPermission(ctx context.Context, roleID int, permission string) (*models.Role, error) {
+	adminId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+
+	return r.permissionUsecase.GrantPermission(ctx, adminId, roleID, permission)
+}
+
+// RevokePermission implements the revokePermission mutation
+func (r *mutationResolver) RevokePermission(ctx context.Context, roleID int, permission string) (*models.Role, error) {
+	adminId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+
+	return r.permissionUsecase.RevokePermission(ctx, adminId, roleID, permission)
+}
+
+// UnlockUser implements the unlockUser mutation
+func (r *mutationResolver) UnlockUser(ctx context.Context, userID int) (*models.User, error) {
+	adminId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+
+	return r.loginAttemptUsecase.UnlockUser(ctx, adminId, userID)
+}
+
+// AdminCreateUser implements the adminCreateUser mutation
+func (r *mutationResolver) AdminCreateUser(ctx context.Context, input generated.AdminCreateUserInput) (*models.User, error) {
+	adminId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+
+	// Choosing a role grants its permissions
+	if input.RoleID != nil && !middleware.HasPermission(ctx, models.PermissionRolesManage) {
+		return nil, ErrForbidden
+	}
+
+	req := usecase.AdminCreateUserRequest{
+		Email:         input.Email,
+		Password:      input.Password,
+		FirstName:     input.FirstName,
+		LastName:      input.LastName,
+		FirstNameKana: input.FirstNameKana,
+		LastNameKana:  input.LastNameKana,
+		RoleID:        input.RoleID,
+	}
+
+	return r.adminUserUsecase.CreateUser(ctx, adminId, req)
+}
+
+// AdminUpdateUser implements the adminUpdateUser mutation
+func (r *mutationResolver) AdminUpdateUser(ctx context.Context, input generated.AdminUpdateUserInput) (*models.User, error) {
+	adminId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+
+	// Changing a role grants or removes its permissions
+	if input.RoleID != nil && !middleware.HasPermission(ctx, models.PermissionRolesManage) {
+		return nil, ErrForbidden
+	}
+
+	req := usecase.AdminUpdateUserRequest{
+		RoleID:         input.RoleID,
+		FirstName:      input.FirstName,
+		LastName:       input.LastName,
+		FirstNameKana:  input.FirstNameKana,
+		LastNameKana:   input.LastNameKana,
+		EnabledMFA:     input.EnabledMfa,
+		MFATypeID:      input.MfaTypeID,
+		RequirePasskey: input.RequirePasskey,
+	}
+
+	return r.adminUserUsecase.UpdateUser(ctx, adminId, input.UserID, req)
+}
+
+// DisableUser implements the disableUser mutation
+func (r *mutationResolver) DisableUser(ctx context.Context, userID int, reason *string) (*models.User, error) {
+	adminId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+
+	disableReason := ""
+	if reason != nil {
+		disableReason = *reason
+	}
+
+	return r.adminUserUsecase.DisableUser(ctx, adminId, userID, disableReason)
+}
+
+// EnableUser implements the enableUser mutation
+func (r *mutationResolver) EnableUser(ctx context.Context, userID int) (*models.User, error) {
+	adminId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+
+	return r.adminUserUsecase.EnableUser(ctx, adminId, userID)
+}
+
+// DeleteUser implements the deleteUser mutation
+func (r *mutationResolver) DeleteUser(ctx context.Context, userID int) (bool, error) {
+	adminId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return false, ErrNotAuthenticated
+	}
+
+	if err := r.adminUserUsecase.DeleteUser(ctx, adminId, userID); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// RestoreUser implements the restoreUser mutation
+func (r *mutationResolver) RestoreUser(ctx context.Context, userID int) (*models.User, error) {
+	adminId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+
+	return r.adminUserUsecase.RestoreUser(ctx, adminId, userID)
+}
+
+// ImpersonateUser implements the impersonateUser mutation
+func (r *mutationResolver) ImpersonateUser(ctx context.Context, userID int, reason string) (*generated.AuthResponse, error) {
+	adminId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+
+	loginResp, err := r.impersonationUsecase.ImpersonateUser(ctx, adminId, userID, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	return toAuthResponse(loginResp), nil
+}
+
+// toJSONString encodes WebAuthn options, which the front-end parses with
+// PublicKeyCredential.parseCreationOptionsFromJSON() or parseRequestOptionsFromJSON()
+func toJSONString(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// toReauthenticationRequest converts the optional re-authentication input of a mutation
+func toReauthenticationRequest(input *generated.ReauthenticationInput) usecase.ReauthenticationRequest {
+	var req usecase.ReauthenticationRequest
+	if input == nil {
+		return req
+	}
+	if input.CurrentPassword != nil {
+		req.CurrentPassword = *input.CurrentPassword
+	}
+	if input.MfaCode != nil {
+		req.MFACode = *input.MfaCode
+	}
+	return req
+}
+
+// toAuthResponse converts a login response into the GraphQL AuthResponse
+func toAuthResponse(loginResp *usecase.LoginResponse) *generated.AuthResponse {
+	resp := &generated.AuthResponse{
+		User:        loginResp.User,
+		MfaRequired: loginResp.MFARequired,
+		MfaType:     toGraphMFAType(loginResp.MFAType),
+	}
+	if loginResp.Token != "" {
+		resp.Token = &loginResp.Token
+	}
+	if loginResp.RefreshToken != "" {
+		resp.RefreshToken = &loginResp.RefreshToken
+	}
+	resp.ExpiresAt = loginResp.ExpiresAt
+	if loginResp.MFAToken != "" {
+		resp.MfaToken = &loginResp.MFAToken
+	}
+	return resp
+}
@@ -2,7 +2,6 @@ package resolvers
 
 import (
 	"context"
-	"errors"
 
 	"github.com/vnlab/makeshop-payment/src/api/graphql/generated"
 	"github.com/vnlab/makeshop-payment/src/api/graphql/middleware"
@@ -20,18 +19,12 @@ type queryResolver struct {
 
 // Define custom errors
 var (
-	ErrNotAuthenticated = errors.New("not authenticated")
-	ErrForbidden        = errors.New("forbidden")
+	ErrNotAuthenticated = middleware.ErrNotAuthenticated
+	ErrForbidden        = middleware.ErrForbidden
 )
 
 // Me returns the currently authenticated user
 func (r *queryResolver) Me(ctx context.Context) (*models.User, error) {
-	// Check auth
-	err := middleware.CheckAuth(ctx)
-	if err != nil {
-		return nil, ErrNotAuthenticated
-	}
-	
 	userId, err := middleware.GetUserID(ctx)
 	if err != nil {
 		return nil, ErrNotAuthenticated
@@ -45,19 +38,23 @@ func (r *queryResolver) Me(ctx context.Context) (*models.User, error) {
 	return user, nil
 }
 
-// User returns a user by ID
-func (r *queryResolver) User(ctx context.Context, id int) (*models.User, error) {
-	// Check auth
-	err := middleware.CheckAuth(ctx)
+// MySessions returns the active sessions of the current user
+func (r *queryResolver) MySessions(ctx context.Context) ([]*models.Session, error) {
+	userId, err := middleware.GetUserID(ctx)
 	if err != nil {
 		return nil, ErrNotAuthenticated
 	}
-	
-	// Check if the user has admin rights
-	if !middleware.IsAdminRole(ctx) {
-		return nil, ErrForbidden
-	}
 
+	return r.sessionUsecase.ListSessions(ctx, userId)
+}
+
+// UserSessions returns the active sessions of any user
+func (r *queryResolver) UserSessions(ctx context.Context, userID int) ([]*models.Session, error) {
+	return r.sessionUsecase.ListSessions(ctx, userID)
+}
+
+// User returns a user by ID
+func (r *queryResolver) User(ctx context.Context, id int) (*models.User, error) {
 	user, err := r.userUsecase.GetUserByID(ctx, id)
 	if err != nil {
 		return nil, err
@@ -68,17 +65,31 @@ func (r *queryResolver) User(ctx context.Context, id int) (*models.User, error)
 
 // Users returns a paginated list of users
 func (r *queryResolver) Users(ctx context.Context, page *int, pageSize *int) (*generated.PaginatedUsers, error) {
-	// Check auth
-	err := middleware.CheckAuth(ctx)
-	if err != nil {
-		return nil, ErrNotAuthenticated
+	p := 1
+	if page != nil {
+		p = *page
+	}
+
+	ps := 10
+	if pageSize != nil {
+		ps = *pageSize
 	}
-	
-	// Check if the user has admin rights
-	if !middleware.IsAdminRole(ctx) {
-		return nil, ErrForbidden
+
+	users, totalPages, err := r.userUsecase.ListUsers(ctx, p, ps)
+	if err != nil {
+		return nil, err
 	}
 
+	return &generated.PaginatedUsers{
+		Users:      users,
+		Page:       p,
+		PageSize:   ps,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// DeletedUsers lists deleted users that can still be restored
+func (r *queryResolver) DeletedUsers(ctx context.Context, page *int, pageSize *int) (*generated.PaginatedUsers, error) {
 	p := 1
 	if page != nil {
 		p = *page
@@ -89,7 +100,7 @@ func (r *queryResolver) Users(ctx context.Context, page *int, pageSize *int) (*g
 		ps = *pageSize
 	}
 
-	users, totalPages, err := r.userUsecase.ListUsers(ctx, p, ps)
+	users, totalPages, err := r.adminUserUsecase.ListDeletedUsers(ctx, p, ps)
 	if err != nil {
 		return nil, err
 	}
@@ -101,3 +112,102 @@ func (r *queryResolver) Users(ctx context.Context, page *int, pageSize *int) (*g
 		TotalPages: totalPages,
 	}, nil
 }
+
+// LoginAttempts returns login attempts for security review
+func (r *queryResolver) LoginAttempts(ctx context.Context, filter *generated.LoginAttemptFilter, page *int, pageSize *int) (*generated.PaginatedLoginAttempts, error) {
+	p := 1
+	if page != nil {
+		p = *page
+	}
+
+	ps := 20
+	if pageSize != nil {
+		ps = *pageSize
+	}
+
+	var attemptFilter models.LoginAttemptFilter
+	if filter != nil {
+		attemptFilter = models.LoginAttemptFilter{
+			UserID:  filter.UserID,
+			Success: filter.Success,
+			Since:   filter.Since,
+			Until:   filter.Until,
+		}
+		if filter.Email != nil {
+			attemptFilter.Email = *filter.Email
+		}
+		if filter.IPAddress != nil {
+			attemptFilter.IPAddress = *filter.IPAddress
+		}
+	}
+
+	attempts, totalPages, err := r.loginAttemptUsecase.ListAttempts(ctx, attemptFilter, p, ps)
+	if err != nil {
+		return nil, err
+	}
+
+	return &generated.PaginatedLoginAttempts{
+		LoginAttempts: attempts,
+		Page:          p,
+		PageSize:      ps,
+		TotalPages:    totalPages,
+	}, nil
+}
+
+// Roles returns every role with its permissions
+func (r *queryResolver) Roles(ctx context.Context) ([]*models.Role, error) {
+	return r.permissionUsecase.ListRoles(ctx)
+}
+
+// Permissions returns every permission that can be granted
+func (r *queryResolver) Permissions(ctx context.Context) ([]*models.Permission, error) {
+	return r.permissionUsecase.ListPermissions(ctx)
+}
+
+// MyPermissions returns the permissions of the current user's role
+func (r *queryResolver) MyPermissions(ctx context.Context) ([]string, error) {
+	permissions, _ := ctx.Value("permissions").([]string)
+	if permissions == nil {
+		permissions = []string{}
+	}
+	return permissions, nil
+}
+
+// MyAPIKeys returns the API keys of the current user
+func (r *queryResolver) MyAPIKeys(ctx context.Context) ([]*models.APIKey, error) {
+	userId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+
+	return r.apiKeyUsecase.ListAPIKeys(ctx, userId)
+}
+
+// OauthClients returns every OAuth client
+func (r *queryResolver) OauthClients(ctx context.Context) ([]*models.OAuthClient, error) {
+	return r.oauthClientUsecase.ListClients(ctx)
+}
+
+// MyPasskeys returns the passkeys of the current user
+func (r *queryResolver) MyPasskeys(ctx context.Context) ([]*models.Passkey, error) {
+	userId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+
+	return r.mfaUsecase.ListPasskeys(ctx, userId)
+}
+
+// MfaTypes returns all MFA types
+func (r *queryResolver) MfaTypes(ctx context.Context) ([]*generated.MFAType, error) {
+	mfaTypes, err := r.mfaUsecase.ListMFATypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*generated.MFAType, 0, len(mfaTypes))
+	for _, mfaType := range mfaTypes {
+		result = append(result, toGraphMFAType(mfaType))
+	}
+	return result, nil
+}
@@ -7,17 +7,56 @@ import (
 
 // Root Resolver
 type Resolver struct {
-    userUsecase    *usecase.UserUsecase
-    jwtService     *auth.JWTService
+	userUsecase              *usecase.UserUsecase
+	mfaUsecase               *usecase.MFAUsecase
+	tokenUsecase             *usecase.TokenUsecase
+	passwordResetUsecase     *usecase.PasswordResetUsecase
+	emailVerificationUsecase *usecase.EmailVerificationUsecase
+	loginAttemptUsecase      *usecase.LoginAttemptUsecase
+	sessionUsecase           *usecase.SessionUsecase
+	permissionUsecase        *usecase.PermissionUsecase
+	adminUserUsecase         *usecase.AdminUserUsecase
+	impersonationUsecase     *usecase.ImpersonationUsecase
+	apiKeyUsecase            *usecase.APIKeyUsecase
+	oauthClientUsecase       *usecase.OAuthClientUsecase
+	ssoUsecase               *usecase.SSOUsecase
+	notificationUsecase      *usecase.NotificationUsecase
+	jwtService               *auth.JWTService
 }
 
 // NewResolver creates a new resolver
 func NewResolver(
-    userUsecase *usecase.UserUsecase,
-    jwtService *auth.JWTService,
+	userUsecase *usecase.UserUsecase,
+	mfaUsecase *usecase.MFAUsecase,
+	tokenUsecase *usecase.TokenUsecase,
+	passwordResetUsecase *usecase.PasswordResetUsecase,
+	emailVerificationUsecase *usecase.EmailVerificationUsecase,
+	loginAttemptUsecase *usecase.LoginAttemptUsecase,
+	sessionUsecase *usecase.SessionUsecase,
+	permissionUsecase *usecase.PermissionUsecase,
+	adminUserUsecase *usecase.AdminUserUsecase,
+	impersonationUsecase *usecase.ImpersonationUsecase,
+	apiKeyUsecase *usecase.APIKeyUsecase,
+	oauthClientUsecase *usecase.OAuthClientUsecase,
+	ssoUsecase *usecase.SSOUsecase,
+	notificationUsecase *usecase.NotificationUsecase,
+	jwtService *auth.JWTService,
 ) *Resolver {
-    return &Resolver{
-        userUsecase: userUsecase,
-        jwtService:  jwtService,
-    }
+	return &Resolver{
+		userUsecase:              userUsecase,
+		mfaUsecase:               mfaUsecase,
+		tokenUsecase:             tokenUsecase,
+		passwordResetUsecase:     passwordResetUsecase,
+		emailVerificationUsecase: emailVerificationUsecase,
+		loginAttemptUsecase:      loginAttemptUsecase,
+		sessionUsecase:           sessionUsecase,
+		permissionUsecase:        permissionUsecase,
+		adminUserUsecase:         adminUserUsecase,
+		impersonationUsecase:     impersonationUsecase,
+		apiKeyUsecase:            apiKeyUsecase,
+		oauthClientUsecase:       oauthClientUsecase,
+		ssoUsecase:               ssoUsecase,
+		notificationUsecase:      notificationUsecase,
+		jwtService:               jwtService,
+	}
 }
@@ -0,0 +1,38 @@
+package resolvers
+
+import (
+	"context"
+
+	"github.com/vnlab/makeshop-payment/src/api/graphql/generated"
+	"github.com/vnlab/makeshop-payment/src/api/graphql/middleware"
+	"github.com/vnlab/makeshop-payment/src/domain/models"
+)
+
+// Subscription returns the SubscriptionResolver implementation
+func (r *Resolver) Subscription() generated.SubscriptionResolver {
+	return &subscriptionResolver{Resolver: r}
+}
+
+type subscriptionResolver struct {
+	*Resolver
+}
+
+// MyNotifications streams the notifications of the current user until the access token expires
+func (r *subscriptionResolver) MyNotifications(ctx context.Context) (<-chan *models.Notification, error) {
+	userId, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+
+	// The client resumes the subscription with a renewed token, which is checked again
+	if expiresAt, ok := middleware.GetTokenExpiry(ctx); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, expiresAt)
+		go func() {
+			<-ctx.Done()
+			cancel()
+		}()
+	}
+
+	return r.notificationUsecase.Subscribe(ctx, userId)
+}
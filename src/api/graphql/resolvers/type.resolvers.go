@@ -2,13 +2,16 @@ package resolvers
 
 import (
 	"context"
+	"time"
 
 	"github.com/vnlab/makeshop-payment/src/api/graphql/generated"
+	"github.com/vnlab/makeshop-payment/src/api/graphql/middleware"
 	"github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/loader"
 )
 
 type typeResolver struct {
-    *Resolver
+	*Resolver
 }
 
 // If you need to process specific fields, provide them as separate functions
@@ -19,28 +22,151 @@ type typeResolver struct {
 
 // User returns UserResolver implementation.
 func (r *Resolver) User() generated.UserResolver {
-    return &userResolver{r}
+	return &userResolver{r}
 }
 
 // Thêm struct userResolver
 type userResolver struct {
-    *Resolver
+	*Resolver
 }
 
 // MFA implementation
 func (r *userResolver) MfaType(ctx context.Context, obj *models.User) (*generated.MFAType, error) {
-    // Nếu user không có MFA type được bật
-    if obj.MFATypeID == nil || obj.MFAType == nil {
-        return nil, nil
-    }
-    
-    // convert from models.MFAType to generated.MFAType
-    return &generated.MFAType{
-        ID:        obj.MFAType.ID,
-        No:        obj.MFAType.No,
-        Title:     obj.MFAType.Title,
-        IsActive:  obj.MFAType.IsActive,
-        CreatedAt: obj.MFAType.CreatedAt,
-        UpdatedAt: obj.MFAType.UpdatedAt,
-    }, nil
+	// Nếu user không có MFA type được bật
+	if obj.MFATypeID == nil {
+		return nil, nil
+	}
+
+	mfaType := obj.MFAType
+	if loaders := loader.FromContext(ctx); loaders != nil {
+		var err error
+		if mfaType, err = loaders.MFATypeByID.Load(ctx, *obj.MFATypeID)(); err != nil {
+			return nil, err
+		}
+	}
+	if mfaType == nil {
+		return nil, nil
+	}
+
+	return toGraphMFAType(mfaType), nil
+}
+
+// Role loads the role of the user, batched with the other users of the request
+func (r *userResolver) Role(ctx context.Context, obj *models.User) (*models.Role, error) {
+	if loaders := loader.FromContext(ctx); loaders != nil {
+		return loaders.RoleByID.Load(ctx, obj.RoleID)()
+	}
+	return obj.Role, nil
+}
+
+// DeletedAt exposes the soft-delete timestamp, which is null for live users
+func (r *userResolver) DeletedAt(ctx context.Context, obj *models.User) (*time.Time, error) {
+	if !obj.DeletedAt.Valid {
+		return nil, nil
+	}
+	return &obj.DeletedAt.Time, nil
+}
+
+// Role returns RoleResolver implementation.
+func (r *Resolver) Role() generated.RoleResolver {
+	return &roleResolver{r}
+}
+
+type roleResolver struct {
+	*Resolver
+}
+
+// Permissions returns the permissions granted to the role
+func (r *roleResolver) Permissions(ctx context.Context, obj *models.Role) ([]*models.Permission, error) {
+	return r.permissionUsecase.ListRolePermissions(ctx, obj.ID)
+}
+
+// Session returns SessionResolver implementation.
+func (r *Resolver) Session() generated.SessionResolver {
+	return &sessionResolver{r}
+}
+
+type sessionResolver struct {
+	*Resolver
+}
+
+// Current tells whether the session belongs to the access token of the request
+func (r *sessionResolver) Current(ctx context.Context, obj *models.Session) (bool, error) {
+	sessionId, err := middleware.GetSessionID(ctx)
+	if err != nil {
+		return false, nil
+	}
+	return obj.ID == sessionId, nil
+}
+
+// ApiKey returns ApiKeyResolver implementation.
+func (r *Resolver) ApiKey() generated.ApiKeyResolver {
+	return &apiKeyResolver{r}
+}
+
+type apiKeyResolver struct {
+	*Resolver
+}
+
+// Scopes lists the permission codes delegated to the key
+func (r *apiKeyResolver) Scopes(ctx context.Context, obj *models.APIKey) ([]string, error) {
+	return obj.ScopeList(), nil
+}
+
+// AllowedIps lists the addresses allowed to use the key
+func (r *apiKeyResolver) AllowedIps(ctx context.Context, obj *models.APIKey) ([]string, error) {
+	return obj.AllowedIPList(), nil
+}
+
+// OAuthClient returns OAuthClientResolver implementation.
+func (r *Resolver) OAuthClient() generated.OAuthClientResolver {
+	return &oauthClientResolver{r}
+}
+
+type oauthClientResolver struct {
+	*Resolver
+}
+
+// User loads the account the client acts for, batched with the other clients of the request.
+// Clients outlive the soft deletion of their account, so deleted accounts are loaded too.
+func (r *oauthClientResolver) User(ctx context.Context, obj *models.OAuthClient) (*models.User, error) {
+	if loaders := loader.FromContext(ctx); loaders != nil {
+		return loaders.UserByIDIncludingDeleted.Load(ctx, obj.UserID)()
+	}
+	return obj.User, nil
+}
+
+// Scopes lists the permission codes granted to the client
+func (r *oauthClientResolver) Scopes(ctx context.Context, obj *models.OAuthClient) ([]string, error) {
+	return obj.ScopeList(), nil
+}
+
+// Passkey returns PasskeyResolver implementation.
+func (r *Resolver) Passkey() generated.PasskeyResolver {
+	return &passkeyResolver{r}
+}
+
+type passkeyResolver struct {
+	*Resolver
+}
+
+// Transports lists the ways the browser can reach the authenticator
+func (r *passkeyResolver) Transports(ctx context.Context, obj *models.Passkey) ([]string, error) {
+	return obj.TransportList(), nil
+}
+
+// toGraphMFAType converts from models.MFAType to generated.MFAType
+func toGraphMFAType(mfaType *models.MFAType) *generated.MFAType {
+	if mfaType == nil {
+		return nil
+	}
+
+	return &generated.MFAType{
+		ID:        mfaType.ID,
+		No:        mfaType.No,
+		Title:     mfaType.Title,
+		IsActive:  mfaType.IsActive,
+		CreatedAt: mfaType.CreatedAt,
+		UpdatedAt: mfaType.UpdatedAt,
+	}
 }
@@ -1,11 +1,14 @@
 package graphql
 
 import (
+	gqlgen "github.com/99designs/gqlgen/graphql"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/gin-gonic/gin"
+	"github.com/vnlab/makeshop-payment/src/api/graphql/limits"
 	"github.com/vnlab/makeshop-payment/src/api/graphql/middleware"
 	"github.com/vnlab/makeshop-payment/src/api/http/handlers"
 	"github.com/vnlab/makeshop-payment/src/infrastructure/auth"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/loader"
 	"github.com/vnlab/makeshop-payment/src/usecase"
 )
 
@@ -13,13 +16,29 @@ import (
 func SetupGraphQL(
 	router *gin.Engine,
 	userUsecase *usecase.UserUsecase,
+	mfaUsecase *usecase.MFAUsecase,
+	tokenUsecase *usecase.TokenUsecase,
+	passwordResetUsecase *usecase.PasswordResetUsecase,
+	emailVerificationUsecase *usecase.EmailVerificationUsecase,
+	loginAttemptUsecase *usecase.LoginAttemptUsecase,
+	sessionUsecase *usecase.SessionUsecase,
+	permissionUsecase *usecase.PermissionUsecase,
+	adminUserUsecase *usecase.AdminUserUsecase,
+	impersonationUsecase *usecase.ImpersonationUsecase,
+	apiKeyUsecase *usecase.APIKeyUsecase,
+	oauthClientUsecase *usecase.OAuthClientUsecase,
+	ssoUsecase *usecase.SSOUsecase,
+	notificationUsecase *usecase.NotificationUsecase,
 	jwtService *auth.JWTService,
+	loaderFactory *loader.Factory,
+	queryLimits limits.Config,
+	persistedQueries gqlgen.HandlerExtension,
 ) {
 	// Set up authentication middleware for GraphQL
-	graphAuthMiddleware := middleware.GraphQLAuthMiddleware(jwtService)
+	graphAuthMiddleware := middleware.GraphQLAuthMiddleware(jwtService, sessionUsecase, permissionUsecase, apiKeyUsecase, oauthClientUsecase)
 
 	// Initialize GraphQL handler
-	graphHandler := handlers.NewGraphHandler(userUsecase, jwtService)
+	graphHandler := handlers.NewGraphHandler(userUsecase, mfaUsecase, tokenUsecase, passwordResetUsecase, emailVerificationUsecase, loginAttemptUsecase, sessionUsecase, permissionUsecase, adminUserUsecase, impersonationUsecase, apiKeyUsecase, oauthClientUsecase, ssoUsecase, notificationUsecase, jwtService, loaderFactory, queryLimits, persistedQueries)
 
 	// Setup GraphQL endpoint with middleware
 	v1 := router.Group("/api/v1")
@@ -27,8 +46,10 @@ func SetupGraphQL(
 		graphqlRoute := v1.Group("/graphql")
 		graphqlRoute.Use(graphAuthMiddleware)
 		{
-			// Main endpoint for GraphQL API
-			graphqlRoute.POST("", graphHandler.QueryHandler())
+			// Main endpoint for GraphQL API, GET carries persisted queries and WebSocket subscriptions
+			queryHandler := graphHandler.QueryHandler()
+			graphqlRoute.POST("", queryHandler)
+			graphqlRoute.GET("", queryHandler)
 		}
 
 		// GraphQL Playground (development only)
@@ -1,36 +1,92 @@
 package handlers
 
 import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
 	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vnlab/makeshop-payment/src/api/graphql/directives"
 	"github.com/vnlab/makeshop-payment/src/api/graphql/generated"
+	"github.com/vnlab/makeshop-payment/src/api/graphql/limits"
 	"github.com/vnlab/makeshop-payment/src/api/graphql/middleware"
+	"github.com/vnlab/makeshop-payment/src/api/graphql/presenter"
 	"github.com/vnlab/makeshop-payment/src/api/graphql/resolvers"
 	"github.com/vnlab/makeshop-payment/src/infrastructure/auth"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/loader"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/logger"
 	"github.com/vnlab/makeshop-payment/src/usecase"
 )
 
+// rootTypeNames maps operation types to the root types of the schema
+var rootTypeNames = map[ast.Operation]string{
+	ast.Query:        "Query",
+	ast.Mutation:     "Mutation",
+	ast.Subscription: "Subscription",
+}
+
 type Graph interface {
 	QueryHandler() gin.HandlerFunc
 }
 
 // GraphHandler handles GraphQL request processing
 type GraphHandler struct {
-	UserUsecase *usecase.UserUsecase
-	JwtService  *auth.JWTService
+	UserUsecase              *usecase.UserUsecase
+	MFAUsecase               *usecase.MFAUsecase
+	TokenUsecase             *usecase.TokenUsecase
+	PasswordResetUsecase     *usecase.PasswordResetUsecase
+	EmailVerificationUsecase *usecase.EmailVerificationUsecase
+	LoginAttemptUsecase      *usecase.LoginAttemptUsecase
+	SessionUsecase           *usecase.SessionUsecase
+	PermissionUsecase        *usecase.PermissionUsecase
+	AdminUserUsecase         *usecase.AdminUserUsecase
+	ImpersonationUsecase     *usecase.ImpersonationUsecase
+	APIKeyUsecase            *usecase.APIKeyUsecase
+	OAuthClientUsecase       *usecase.OAuthClientUsecase
+	SSOUsecase               *usecase.SSOUsecase
+	NotificationUsecase      *usecase.NotificationUsecase
+	JwtService               *auth.JWTService
+	LoaderFactory            *loader.Factory
+	QueryLimits              limits.Config
+	PersistedQueries         graphql.HandlerExtension
 }
 
 // NewGraphHandler creates a new GraphHandler
-func NewGraphHandler(us *usecase.UserUsecase, js *auth.JWTService) Graph {
+func NewGraphHandler(us *usecase.UserUsecase, ms *usecase.MFAUsecase, ts *usecase.TokenUsecase, ps *usecase.PasswordResetUsecase, es *usecase.EmailVerificationUsecase, ls *usecase.LoginAttemptUsecase, ss *usecase.SessionUsecase, pms *usecase.PermissionUsecase, as *usecase.AdminUserUsecase, is *usecase.ImpersonationUsecase, ks *usecase.APIKeyUsecase, ocs *usecase.OAuthClientUsecase, sss *usecase.SSOUsecase, ns *usecase.NotificationUsecase, js *auth.JWTService, lf *loader.Factory, ql limits.Config, pq graphql.HandlerExtension) Graph {
 	return &GraphHandler{
-		UserUsecase: us,
-		JwtService: js,
+		UserUsecase:              us,
+		MFAUsecase:               ms,
+		TokenUsecase:             ts,
+		PasswordResetUsecase:     ps,
+		EmailVerificationUsecase: es,
+		LoginAttemptUsecase:      ls,
+		SessionUsecase:           ss,
+		PermissionUsecase:        pms,
+		AdminUserUsecase:         as,
+		ImpersonationUsecase:     is,
+		APIKeyUsecase:            ks,
+		OAuthClientUsecase:       ocs,
+		SSOUsecase:               sss,
+		NotificationUsecase:      ns,
+		JwtService:               js,
+		LoaderFactory:            lf,
+		QueryLimits:              ql,
+		PersistedQueries:         pq,
 	}
 }
 
 // QueryHandler godoc
 // @Summary GraphQL query endpoint
-// @Description Process GraphQL queries and mutations
+// @Description Process GraphQL queries and mutations, and subscriptions over WebSocket (GET with Upgrade)
 // @Tags graphql
 // @Accept json
 // @Produce json
@@ -41,18 +97,104 @@ func NewGraphHandler(us *usecase.UserUsecase, js *auth.JWTService) Graph {
 // @Failure 401 {object} map[string]string "Unauthorized"
 // @Failure 500 {object} map[string]string "Internal Server Error"
 // @Router /graphql [post]
+// @Router /graphql [get]
 func (h *GraphHandler) QueryHandler() gin.HandlerFunc {
-	// TODO: Implement GraphQL loader
-
-	graphHandler := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{
-		Resolvers: resolvers.NewResolver(h.UserUsecase, h.JwtService),
+	graphHandler := handler.New(generated.NewExecutableSchema(generated.Config{
+		Resolvers:  resolvers.NewResolver(h.UserUsecase, h.MFAUsecase, h.TokenUsecase, h.PasswordResetUsecase, h.EmailVerificationUsecase, h.LoginAttemptUsecase, h.SessionUsecase, h.PermissionUsecase, h.AdminUserUsecase, h.ImpersonationUsecase, h.APIKeyUsecase, h.OAuthClientUsecase, h.SSOUsecase, h.NotificationUsecase, h.JwtService),
+		Directives: directives.New(),
+		Complexity: limits.Complexity(),
 	}))
 
-    return func(c *gin.Context) {
+	// Subscriptions are served over WebSocket and authenticated by their connection_init payload
+	graphHandler.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+		InitFunc:              middleware.WebsocketInit(h.JwtService, h.SessionUsecase, h.PermissionUsecase, h.APIKeyUsecase, h.OAuthClientUsecase),
+		Upgrader: websocket.Upgrader{
+			CheckOrigin: checkOrigin,
+		},
+	})
+	graphHandler.AddTransport(transport.Options{})
+	graphHandler.AddTransport(transport.GET{})
+	graphHandler.AddTransport(transport.POST{})
+	graphHandler.AddTransport(transport.MultipartForm{})
+
+	// Errors carry a code and the trace ID of the request, and unexpected ones are not leaked in release mode
+	graphHandler.SetErrorPresenter(presenter.ErrorPresenter)
+	graphHandler.SetRecoverFunc(presenter.Recover)
+
+	graphHandler.SetQueryCache(lru.New(1000))
+	graphHandler.Use(extension.Introspection{})
+
+	// Clients may send the hash of a known document instead of its text
+	graphHandler.Use(h.PersistedQueries)
+
+	// Operations too deep, too wide or too expensive for the client are refused before execution
+	graphHandler.Use(limits.New(h.QueryLimits))
+
+	// Everything an administrator does while acting as a user is recorded
+	graphHandler.AroundOperations(h.recordImpersonatedOperation)
+
+	return func(c *gin.Context) {
 		// Send authentication information from Gin context to GraphQL context
 		ctx := middleware.WithAuth(c.Request.Context(), c)
+		// Loaders are per request so that batched values are never shared between users
+		ctx = loader.WithLoaders(ctx, h.LoaderFactory.New())
+		ctx = presenter.WithTraceID(ctx, traceID(c))
 		c.Request = c.Request.WithContext(ctx)
 
 		graphHandler.ServeHTTP(c.Writer, c.Request)
-    }
-}
\ No newline at end of file
+	}
+}
+
+// traceID returns the trace ID of the request and sends it back in the X-Trace-ID header.
+// The ID set by the request logger is reused, then the one sent by the client.
+func traceID(c *gin.Context) string {
+	if id := c.Writer.Header().Get("X-Trace-ID"); id != "" {
+		return id
+	}
+	id := c.GetHeader("X-Trace-ID")
+	if id == "" {
+		id = logger.GenerateTraceID()
+	}
+	c.Header("X-Trace-ID", id)
+	return id
+}
+
+// checkOrigin accepts WebSocket handshakes from the front end, the API itself or clients that are not browsers
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || origin == os.Getenv("API_FRONT_URL") {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// recordImpersonatedOperation records each operation made under impersonation together with the
+// acting administrator. The operation is refused if it cannot be recorded.
+func (h *GraphHandler) recordImpersonatedOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	actorID, ok := middleware.GetActorID(ctx)
+	if !ok {
+		return next(ctx)
+	}
+	userID, _ := middleware.GetUserID(ctx)
+	sessionID, _ := middleware.GetSessionID(ctx)
+
+	opCtx := graphql.GetOperationContext(ctx)
+	operation := ""
+	var fields []string
+	if opCtx.Operation != nil {
+		operation = string(opCtx.Operation.Operation)
+		// Only field names are kept: arguments and variables may contain personal data
+		rootType := []string{rootTypeNames[opCtx.Operation.Operation]}
+		for _, field := range graphql.CollectFields(opCtx, opCtx.Operation.SelectionSet, rootType) {
+			fields = append(fields, field.Name)
+		}
+	}
+
+	err := h.ImpersonationUsecase.RecordRequest(ctx, actorID, userID, sessionID, operation, opCtx.OperationName, fields)
+	if err != nil {
+		return graphql.OneShot(graphql.ErrorResponse(ctx, "failed to record impersonated request"))
+	}
+	return next(ctx)
+}
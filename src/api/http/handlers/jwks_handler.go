@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/auth"
+)
+
+// JWKSHandler publishes the public keys used to verify our tokens
+type JWKSHandler struct {
+	JwtService *auth.JWTService
+}
+
+// NewJWKSHandler creates a new JWKSHandler
+func NewJWKSHandler(js *auth.JWTService) *JWKSHandler {
+	return &JWKSHandler{
+		JwtService: js,
+	}
+}
+
+// GetJWKS godoc
+// @Summary JSON Web Key Set
+// @Description Public keys for verifying access tokens, identified by the "kid" token header
+// @Tags auth
+// @Produce json
+// @Success 200 {object} auth.JWKS "JSON Web Key Set"
+// @Router /.well-known/jwks.json [get]
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	// Let verifiers cache the keys, but pick up a rotation within minutes
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.JwtService.JWKS())
+}
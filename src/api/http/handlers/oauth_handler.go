@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/usecase"
+)
+
+// OAuth2 error codes (RFC 6749 section 5.2)
+const (
+	oauthErrorInvalidRequest       = "invalid_request"
+	oauthErrorInvalidClient        = "invalid_client"
+	oauthErrorInvalidScope         = "invalid_scope"
+	oauthErrorUnsupportedGrantType = "unsupported_grant_type"
+	oauthErrorServerError          = "server_error"
+)
+
+// OAuthTokenResponse is a successful token response (RFC 6749 section 5.1)
+type OAuthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// OAuthIntrospectionResponse describes a token (RFC 7662 section 2.2)
+type OAuthIntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+}
+
+// OAuthErrorResponse is an OAuth2 error response (RFC 6749 section 5.2)
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// OAuthHandler implements the OAuth2 endpoints used by partner applications
+type OAuthHandler struct {
+	OAuthClientUsecase *usecase.OAuthClientUsecase
+}
+
+// NewOAuthHandler creates a new OAuthHandler
+func NewOAuthHandler(ocs *usecase.OAuthClientUsecase) *OAuthHandler {
+	return &OAuthHandler{
+		OAuthClientUsecase: ocs,
+	}
+}
+
+// Token godoc
+// @Summary OAuth2 token endpoint
+// @Description Issues an access token with the client_credentials grant. The client authenticates with HTTP Basic (client_secret_basic) or with client_id and client_secret form fields (client_secret_post).
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "Must be client_credentials"
+// @Param scope formData string false "Space separated scopes, defaults to every scope of the client"
+// @Success 200 {object} OAuthTokenResponse "Access token"
+// @Failure 400 {object} OAuthErrorResponse "Invalid request"
+// @Failure 401 {object} OAuthErrorResponse "Invalid client"
+// @Router /oauth/token [post]
+func (h *OAuthHandler) Token(c *gin.Context) {
+	noStore(c)
+
+	client, ok := h.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	if grantType := c.PostForm("grant_type"); grantType != "client_credentials" {
+		if grantType == "" {
+			oauthError(c, http.StatusBadRequest, oauthErrorInvalidRequest, "grant_type is required")
+			return
+		}
+		oauthError(c, http.StatusBadRequest, oauthErrorUnsupportedGrantType, "")
+		return
+	}
+
+	token, err := h.OAuthClientUsecase.IssueToken(c.Request.Context(), client, strings.Fields(c.PostForm("scope")))
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidScope):
+			oauthError(c, http.StatusBadRequest, oauthErrorInvalidScope, "")
+		case errors.Is(err, usecase.ErrInvalidClient):
+			oauthError(c, http.StatusUnauthorized, oauthErrorInvalidClient, "")
+		default:
+			log.Printf("Failed to issue OAuth token: %v", err)
+			oauthError(c, http.StatusInternalServerError, oauthErrorServerError, "")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, OAuthTokenResponse{
+		AccessToken: token.AccessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(token.ExpiresIn.Seconds()),
+		Scope:       strings.Join(token.Scopes, " "),
+	})
+}
+
+// Introspect godoc
+// @Summary OAuth2 token introspection
+// @Description Describes an access token issued to the calling client (RFC 7662). Other tokens are reported as inactive.
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Access token"
+// @Success 200 {object} OAuthIntrospectionResponse "Token description"
+// @Failure 400 {object} OAuthErrorResponse "Invalid request"
+// @Failure 401 {object} OAuthErrorResponse "Invalid client"
+// @Router /oauth/introspect [post]
+func (h *OAuthHandler) Introspect(c *gin.Context) {
+	noStore(c)
+
+	client, ok := h.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	token := c.PostForm("token")
+	if token == "" {
+		oauthError(c, http.StatusBadRequest, oauthErrorInvalidRequest, "token is required")
+		return
+	}
+
+	claims, err := h.OAuthClientUsecase.Introspect(c.Request.Context(), client, token)
+	if err != nil {
+		log.Printf("Failed to introspect OAuth token: %v", err)
+		oauthError(c, http.StatusInternalServerError, oauthErrorServerError, "")
+		return
+	}
+	if claims == nil {
+		c.JSON(http.StatusOK, OAuthIntrospectionResponse{Active: false})
+		return
+	}
+
+	resp := OAuthIntrospectionResponse{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		TokenType: "Bearer",
+		Sub:       claims.Subject,
+		Jti:       claims.ID,
+	}
+	if claims.ExpiresAt != nil {
+		resp.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		resp.Iat = claims.IssuedAt.Unix()
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Revoke godoc
+// @Summary OAuth2 token revocation
+// @Description Revokes an access token issued to the calling client (RFC 7009). Unknown tokens are ignored.
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Param token formData string true "Access token"
+// @Param token_type_hint formData string false "Only access_token is supported"
+// @Success 200 "Token revoked or unknown"
+// @Failure 400 {object} OAuthErrorResponse "Invalid request"
+// @Failure 401 {object} OAuthErrorResponse "Invalid client"
+// @Router /oauth/revoke [post]
+func (h *OAuthHandler) Revoke(c *gin.Context) {
+	client, ok := h.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	token := c.PostForm("token")
+	if token == "" {
+		oauthError(c, http.StatusBadRequest, oauthErrorInvalidRequest, "token is required")
+		return
+	}
+
+	if err := h.OAuthClientUsecase.RevokeToken(c.Request.Context(), client, token); err != nil {
+		log.Printf("Failed to revoke OAuth token: %v", err)
+		oauthError(c, http.StatusServiceUnavailable, oauthErrorServerError, "")
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// authenticateClient authenticates the calling client with client_secret_basic or
+// client_secret_post, and writes the error response when it fails
+func (h *OAuthHandler) authenticateClient(c *gin.Context) (*models.OAuthClient, bool) {
+	clientID, secret, basic := c.Request.BasicAuth()
+	if basic {
+		// Credentials are form-urlencoded before being put in the header (RFC 6749 section 2.3.1)
+		var idErr, secretErr error
+		clientID, idErr = url.QueryUnescape(clientID)
+		secret, secretErr = url.QueryUnescape(secret)
+		if idErr != nil || secretErr != nil {
+			invalidClient(c, basic)
+			return nil, false
+		}
+		if c.PostForm("client_secret") != "" {
+			oauthError(c, http.StatusBadRequest, oauthErrorInvalidRequest, "only one client authentication method may be used")
+			return nil, false
+		}
+	} else {
+		clientID = c.PostForm("client_id")
+		secret = c.PostForm("client_secret")
+	}
+
+	if clientID == "" || secret == "" {
+		invalidClient(c, basic)
+		return nil, false
+	}
+
+	client, err := h.OAuthClientUsecase.AuthenticateClient(c.Request.Context(), clientID, secret)
+	if err != nil {
+		if !errors.Is(err, usecase.ErrInvalidClient) {
+			log.Printf("Failed to authenticate OAuth client: %v", err)
+			oauthError(c, http.StatusInternalServerError, oauthErrorServerError, "")
+			return nil, false
+		}
+		invalidClient(c, basic)
+		return nil, false
+	}
+
+	return client, true
+}
+
+// invalidClient refuses the client credentials, asking for Basic authentication when it was used
+func invalidClient(c *gin.Context, basic bool) {
+	if basic {
+		c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	oauthError(c, http.StatusUnauthorized, oauthErrorInvalidClient, "")
+}
+
+// oauthError writes an OAuth2 error response
+func oauthError(c *gin.Context, status int, code, description string) {
+	c.JSON(status, OAuthErrorResponse{
+		Error:            code,
+		ErrorDescription: description,
+	})
+}
+
+// noStore prevents responses carrying tokens from being cached
+func noStore(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+}
@@ -7,13 +7,16 @@ import (
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"github.com/vnlab/makeshop-payment/src/api/http/handlers"
 	"github.com/vnlab/makeshop-payment/src/infrastructure/auth"
+	"github.com/vnlab/makeshop-payment/src/usecase"
 )
 
 // SetupRouter sets up the Gin router with all routes and middleware
 func SetupRouter(
 	router *gin.Engine,
 	jwtService *auth.JWTService,
+	oauthClientUsecase *usecase.OAuthClientUsecase,
 ) *gin.Engine {
 	// Configure CORS
 	config := cors.DefaultConfig()
@@ -22,6 +25,19 @@ func SetupRouter(
 	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
 	router.Use(cors.New(config))
 
+	// Public keys for services verifying our tokens
+	jwksHandler := handlers.NewJWKSHandler(jwtService)
+	router.GET("/.well-known/jwks.json", jwksHandler.GetJWKS)
+
+	// OAuth2 endpoints for partner applications
+	oauthHandler := handlers.NewOAuthHandler(oauthClientUsecase)
+	oauth := router.Group("/oauth")
+	{
+		oauth.POST("/token", oauthHandler.Token)
+		oauth.POST("/introspect", oauthHandler.Introspect)
+		oauth.POST("/revoke", oauthHandler.Revoke)
+	}
+
 	// Health check endpoint
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
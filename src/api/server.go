@@ -7,31 +7,78 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	gqlgen "github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
 	"github.com/gin-gonic/gin"
 	"github.com/vnlab/makeshop-payment/src/api/graphql"
+	"github.com/vnlab/makeshop-payment/src/api/graphql/limits"
+	"github.com/vnlab/makeshop-payment/src/api/graphql/persisted"
 	httpAPI "github.com/vnlab/makeshop-payment/src/api/http"
 	"github.com/vnlab/makeshop-payment/src/domain/repositories"
 	"github.com/vnlab/makeshop-payment/src/infrastructure/auth"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/config"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/loader"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/mail"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/oidc"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/pubsub"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/sms"
 	"github.com/vnlab/makeshop-payment/src/lib/validator"
+	"github.com/vnlab/makeshop-payment/src/lib/webauthn"
 	"github.com/vnlab/makeshop-payment/src/usecase"
 )
 
 // Server represents the API server
 type Server struct {
-	router         *gin.Engine
-	httpServer     *http.Server
-	jwtService     *auth.JWTService
-	userUsecase    *usecase.UserUsecase
+	router       *gin.Engine
+	httpServer   *http.Server
+	jwtService   *auth.JWTService
+	userUsecase  *usecase.UserUsecase
+	mfaUsecase   *usecase.MFAUsecase
+	tokenUsecase *usecase.TokenUsecase
+
+	passwordResetUsecase     *usecase.PasswordResetUsecase
+	emailVerificationUsecase *usecase.EmailVerificationUsecase
+	loginAttemptUsecase      *usecase.LoginAttemptUsecase
+	sessionUsecase           *usecase.SessionUsecase
+	permissionUsecase        *usecase.PermissionUsecase
+	adminUserUsecase         *usecase.AdminUserUsecase
+	impersonationUsecase     *usecase.ImpersonationUsecase
+	apiKeyUsecase            *usecase.APIKeyUsecase
+	oauthClientUsecase       *usecase.OAuthClientUsecase
+	ssoUsecase               *usecase.SSOUsecase
+	revocationStore          auth.RevocationStore
 }
 
 // NewServer creates a new API server
 func NewServer(
+	appConfig *config.Config,
 	userRepo repositories.UserRepository,
 	roleRepo repositories.RoleRepository,
-) *Server {
+	mfaTypeRepo repositories.MFATypeRepository,
+	totpRepo repositories.UserTOTPRepository,
+	recoveryCodeRepo repositories.RecoveryCodeRepository,
+	otpCodeRepo repositories.OTPCodeRepository,
+	auditLogRepo repositories.AuditLogRepository,
+	refreshTokenRepo repositories.RefreshTokenRepository,
+	passwordResetTokenRepo repositories.PasswordResetTokenRepository,
+	emailVerificationTokenRepo repositories.EmailVerificationTokenRepository,
+	loginAttemptRepo repositories.LoginAttemptRepository,
+	sessionRepo repositories.SessionRepository,
+	permissionRepo repositories.PermissionRepository,
+	apiKeyRepo repositories.APIKeyRepository,
+	oauthClientRepo repositories.OAuthClientRepository,
+	userIdentityRepo repositories.UserIdentityRepository,
+	ssoStateRepo repositories.SSOStateRepository,
+	passkeyRepo repositories.PasskeyRepository,
+	passkeyChallengeRepo repositories.PasskeyChallengeRepository,
+	persistedQueryRepo repositories.PersistedQueryRepository,
+	revocationStore auth.RevocationStore,
+	broker pubsub.Broker,
+) (*Server, error) {
 	// Set Gin mode
 	ginMode := os.Getenv("GIN_MODE")
 	if ginMode != "" {
@@ -45,20 +92,266 @@ func NewServer(
 	router := gin.Default()
 
 	// Initialize services
-	jwtService := auth.NewJWTService()
-	userUsecase := usecase.NewUserUseCase(userRepo, roleRepo, jwtService)
+	keyRing, err := auth.LoadKeyRing(appConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JWT signing keys: %w", err)
+	}
+	if keyRing == nil {
+		if gin.Mode() == gin.ReleaseMode {
+			return nil, fmt.Errorf("JWT_KEY_DIRECTORY or JWT_PRIVATE_KEY_FILE must be set in release mode")
+		}
+		log.Println("Warning: no JWT signing key is configured, using an ephemeral development key")
+		keyRing, err = newEphemeralKeyRing(appConfig.JWTAlgorithm)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate JWT signing key: %w", err)
+		}
+	}
+	jwtService := auth.NewJWTService(appConfig, keyRing, revocationStore)
+
+	// Every audited change to an account is also pushed to its owner while they are online
+	notificationUsecase := usecase.NewNotificationUseCase(broker)
+	auditLogRepo = notificationUsecase.NotifyingAuditLog(auditLogRepo)
+
+	mfaKey := appConfig.MFAEncryptionKey
+	if mfaKey == "" {
+		if gin.Mode() == gin.ReleaseMode {
+			return nil, fmt.Errorf("MFA_ENCRYPTION_KEY must be set in release mode")
+		}
+		log.Println("Warning: MFA_ENCRYPTION_KEY is not set, using an insecure development key")
+		mfaKey = "insecure_development_mfa_key"
+	}
+	secretCipher, err := auth.NewSecretCipher(mfaKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize MFA cipher: %w", err)
+	}
+
+	mailer, err := mail.NewMailer(appConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize mailer: %w", err)
+	}
+	mailTemplates := mail.NewTemplateRenderer(appConfig.MailLocale)
+
+	smsSender, err := sms.NewSender(appConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize SMS sender: %w", err)
+	}
+
+	tokenUsecase := usecase.NewTokenUseCase(
+		userRepo,
+		refreshTokenRepo,
+		sessionRepo,
+		auditLogRepo,
+		jwtService,
+		time.Duration(appConfig.RefreshTokenDays)*24*time.Hour,
+	)
+	sessionUsecase := usecase.NewSessionUseCase(sessionRepo, tokenUsecase)
+	permissionUsecase := usecase.NewPermissionUseCase(roleRepo, permissionRepo, auditLogRepo)
+	loginAttemptUsecase := usecase.NewLoginAttemptUseCase(
+		userRepo,
+		loginAttemptRepo,
+		auditLogRepo,
+		usecase.LoginProtectionConfig{
+			MaxFailedAttempts: appConfig.LoginMaxFailedAttempts,
+			LockoutDuration:   time.Duration(appConfig.LoginLockoutMinutes) * time.Minute,
+			MaxLockoutTime:    time.Duration(appConfig.LoginMaxLockoutMinutes) * time.Minute,
+			IPMaxFailures:     appConfig.LoginIPMaxFailures,
+			IPWindow:          time.Duration(appConfig.LoginIPWindowMinutes) * time.Minute,
+		},
+	)
+	var webAuthnOrigins []string
+	for _, origin := range strings.Split(appConfig.WebAuthnOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			webAuthnOrigins = append(webAuthnOrigins, origin)
+		}
+	}
+	passkeyTTL := time.Duration(appConfig.WebAuthnChallengeTTL) * time.Minute
+	relyingParty := &webauthn.RelyingParty{
+		ID:      appConfig.WebAuthnRPID,
+		Name:    appConfig.WebAuthnRPName,
+		Origins: webAuthnOrigins,
+		Timeout: passkeyTTL,
+	}
+
+	emailVerificationUsecase := usecase.NewEmailVerificationUseCase(
+		userRepo,
+		emailVerificationTokenRepo,
+		auditLogRepo,
+		mailer,
+		mailTemplates,
+		usecase.EmailVerificationConfig{
+			Required:   appConfig.RequireEmailVerification,
+			TokenTTL:   time.Duration(appConfig.EmailVerificationTTL) * time.Hour,
+			VerifyURL:  appConfig.EmailVerificationURL,
+			MaxPerHour: appConfig.EmailVerificationMaxPerHour,
+		},
+	)
+	mfaUsecase := usecase.NewMFAUseCase(
+		userRepo,
+		mfaTypeRepo,
+		totpRepo,
+		recoveryCodeRepo,
+		otpCodeRepo,
+		passkeyRepo,
+		passkeyChallengeRepo,
+		auditLogRepo,
+		jwtService,
+		tokenUsecase,
+		loginAttemptUsecase,
+		emailVerificationUsecase,
+		secretCipher,
+		mailer,
+		mailTemplates,
+		smsSender,
+		relyingParty,
+		usecase.MFAConfig{
+			Issuer:         appConfig.MFAIssuer,
+			OTPCodeTTL:     time.Duration(appConfig.OTPCodeTTL) * time.Minute,
+			OTPMaxAttempts: appConfig.OTPMaxAttempts,
+			OTPResendDelay: time.Duration(appConfig.OTPResendSeconds) * time.Second,
+			OTPMaxPerHour:  appConfig.OTPMaxPerHour,
+			PasskeyTTL:     passkeyTTL,
+		},
+	)
+	userUsecase := usecase.NewUserUseCase(userRepo, roleRepo, jwtService, tokenUsecase, mfaUsecase, emailVerificationUsecase, loginAttemptUsecase)
+	passwordResetUsecase := usecase.NewPasswordResetUseCase(
+		userRepo,
+		passwordResetTokenRepo,
+		auditLogRepo,
+		tokenUsecase,
+		mailer,
+		mailTemplates,
+		usecase.PasswordResetConfig{
+			TokenTTL:   time.Duration(appConfig.PasswordResetTTL) * time.Minute,
+			ResetURL:   appConfig.PasswordResetURL,
+			MaxPerHour: appConfig.PasswordResetMaxPerHour,
+		},
+	)
+	adminUserUsecase := usecase.NewAdminUserUseCase(
+		userRepo,
+		roleRepo,
+		auditLogRepo,
+		apiKeyRepo,
+		oauthClientRepo,
+		tokenUsecase,
+		mfaUsecase,
+		emailVerificationUsecase,
+	)
+	impersonationUsecase := usecase.NewImpersonationUseCase(
+		userRepo,
+		auditLogRepo,
+		tokenUsecase,
+		time.Duration(appConfig.ImpersonationMinutes)*time.Minute,
+	)
+	apiKeyUsecase := usecase.NewAPIKeyUseCase(
+		apiKeyRepo,
+		userRepo,
+		auditLogRepo,
+		permissionUsecase,
+	)
+	oauthClientUsecase := usecase.NewOAuthClientUseCase(
+		oauthClientRepo,
+		userRepo,
+		auditLogRepo,
+		permissionUsecase,
+		jwtService,
+		time.Duration(appConfig.OAuthTokenMinutes)*time.Minute,
+	)
+
+	var ssoProvider *oidc.Provider
+	if appConfig.OIDCEnabled {
+		if appConfig.OIDCIssuerURL == "" || appConfig.OIDCClientID == "" || appConfig.OIDCRedirectURL == "" {
+			return nil, fmt.Errorf("OIDC_ISSUER_URL, OIDC_CLIENT_ID and OIDC_REDIRECT_URL must be set when OIDC_ENABLED is true")
+		}
+		ssoProvider = oidc.NewProvider(oidc.Config{
+			IssuerURL:    appConfig.OIDCIssuerURL,
+			ClientID:     appConfig.OIDCClientID,
+			ClientSecret: appConfig.OIDCClientSecret,
+			RedirectURL:  appConfig.OIDCRedirectURL,
+			Scopes:       strings.Fields(appConfig.OIDCScopes),
+			GroupsClaim:  appConfig.OIDCGroupsClaim,
+		})
+	}
+	ssoRoleMapping, err := usecase.ParseSSORoleMapping(appConfig.OIDCRoleMapping)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OIDC_ROLE_MAPPING: %w", err)
+	}
+	ssoUsecase := usecase.NewSSOUseCase(
+		userRepo,
+		roleRepo,
+		userIdentityRepo,
+		ssoStateRepo,
+		auditLogRepo,
+		tokenUsecase,
+		mfaUsecase,
+		loginAttemptUsecase,
+		ssoProvider,
+		usecase.SSOConfig{
+			RoleMapping: ssoRoleMapping,
+			StateTTL:    time.Duration(appConfig.OIDCStateTTL) * time.Minute,
+		},
+	)
+
+	// Release builds only execute the registered documents
+	if !appConfig.GraphQLAllowlistOnly && gin.Mode() == gin.ReleaseMode {
+		return nil, fmt.Errorf("GRAPHQL_ALLOWLIST_ONLY must be enabled in release mode")
+	}
+	var persistedQueries gqlgen.HandlerExtension
+	if appConfig.GraphQLAllowlistOnly {
+		persistedQueries = persisted.NewAllowlist(persistedQueryRepo)
+	} else {
+		apqCache, err := persisted.NewCache(persisted.CacheConfig{
+			Kind:      appConfig.GraphQLAPQCache,
+			Size:      appConfig.GraphQLAPQCacheSize,
+			StoreSize: appConfig.GraphQLAPQStoreSize,
+			StoreTTL:  time.Duration(appConfig.GraphQLAPQStoreTTL) * time.Hour,
+		}, persistedQueryRepo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create persisted query cache: %w", err)
+		}
+		persistedQueries = extension.AutomaticPersistedQuery{Cache: apqCache}
+	}
 
 	// Set up HTTP routes - FIX: Save the router returned from SetupRouter
 	router = httpAPI.SetupRouter(
 		router,
 		jwtService,
+		oauthClientUsecase,
 	)
 
 	// Set up GraphQL
 	graphql.SetupGraphQL(
-		router,  // This router instance is created but never assigned to the Server struct
+		router, // This router instance is created but never assigned to the Server struct
 		userUsecase,
+		mfaUsecase,
+		tokenUsecase,
+		passwordResetUsecase,
+		emailVerificationUsecase,
+		loginAttemptUsecase,
+		sessionUsecase,
+		permissionUsecase,
+		adminUserUsecase,
+		impersonationUsecase,
+		apiKeyUsecase,
+		oauthClientUsecase,
+		ssoUsecase,
+		notificationUsecase,
 		jwtService,
+		loader.NewFactory(userRepo, roleRepo, mfaTypeRepo),
+		limits.Config{
+			User: limits.Limits{
+				MaxDepth:      appConfig.GraphQLUserMaxDepth,
+				MaxComplexity: appConfig.GraphQLUserMaxComplexity,
+				MaxAliases:    appConfig.GraphQLUserMaxAliases,
+				MaxFields:     appConfig.GraphQLUserMaxFields,
+			},
+			Client: limits.Limits{
+				MaxDepth:      appConfig.GraphQLClientMaxDepth,
+				MaxComplexity: appConfig.GraphQLClientMaxComplexity,
+				MaxAliases:    appConfig.GraphQLClientMaxAliases,
+				MaxFields:     appConfig.GraphQLClientMaxFields,
+			},
+		},
+		persistedQueries,
 	)
 
 	// Create HTTP server
@@ -73,11 +366,35 @@ func NewServer(
 	}
 
 	return &Server{
-		router:         router,
-		httpServer:     httpServer,
-		jwtService:     jwtService,
-		userUsecase:    userUsecase,
+		router:       router,
+		httpServer:   httpServer,
+		jwtService:   jwtService,
+		userUsecase:  userUsecase,
+		mfaUsecase:   mfaUsecase,
+		tokenUsecase: tokenUsecase,
+
+		passwordResetUsecase:     passwordResetUsecase,
+		emailVerificationUsecase: emailVerificationUsecase,
+		loginAttemptUsecase:      loginAttemptUsecase,
+		sessionUsecase:           sessionUsecase,
+		permissionUsecase:        permissionUsecase,
+		adminUserUsecase:         adminUserUsecase,
+		impersonationUsecase:     impersonationUsecase,
+		apiKeyUsecase:            apiKeyUsecase,
+		oauthClientUsecase:       oauthClientUsecase,
+		ssoUsecase:               ssoUsecase,
+		revocationStore:          revocationStore,
+	}, nil
+}
+
+// newEphemeralKeyRing generates an in-memory signing key.
+// Tokens signed with it do not survive a restart.
+func newEphemeralKeyRing(algorithm string) (*auth.KeyRing, error) {
+	key, err := auth.GenerateSigningKey(auth.NewKeyID(algorithm, time.Now()), algorithm)
+	if err != nil {
+		return nil, err
 	}
+	return auth.NewKeyRing(key)
 }
 
 // Start starts the API server
@@ -86,6 +403,11 @@ func (s *Server) Start() error {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
+	// Drop revocations of tokens that have expired anyway
+	pruneCtx, stopPruning := context.WithCancel(context.Background())
+	defer stopPruning()
+	go auth.RunRevocationPruner(pruneCtx, s.revocationStore, time.Hour)
+
 	go func() {
 		<-quit
 		log.Println("Shutting down server...")
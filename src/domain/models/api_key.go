@@ -0,0 +1,105 @@
+package models
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// APIKeyPrefix starts every API key so that leaked keys are easy to recognise
+const APIKeyPrefix = "msp_"
+
+// APIKey is a credential used by a merchant backend to call the API on behalf of its owner.
+// The key is "msp_<prefix>_<secret>"; only the prefix and a hash of the whole key are stored.
+type APIKey struct {
+	ID         int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID     int        `json:"user_id" gorm:"type:int;not null;index"`
+	Name       string     `json:"name" gorm:"type:varchar(100);not null"`
+	Prefix     string     `json:"prefix" gorm:"type:varchar(16);not null;uniqueIndex"`
+	KeyHash    string     `json:"-" gorm:"column:key_hash;type:varchar(64);not null"`       // Never exposed in JSON
+	Scopes     string     `json:"scopes" gorm:"type:varchar(255);not null"`                 // Comma separated permission codes
+	AllowedIPs string     `json:"allowed_ips" gorm:"column:allowed_ips;type:varchar(1000)"` // Comma separated IPs or CIDR ranges, empty allows any
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip" gorm:"column:last_used_ip;type:varchar(45)"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the database table name
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// ValidateIPRule checks that an allowlist entry is an IP address or a CIDR range
+func ValidateIPRule(rule string) error {
+	if net.ParseIP(rule) != nil {
+		return nil
+	}
+	if _, _, err := net.ParseCIDR(rule); err != nil {
+		return fmt.Errorf("invalid IP address or CIDR range: %s", rule)
+	}
+	return nil
+}
+
+// ScopeList returns the permission codes delegated to the key
+func (k *APIKey) ScopeList() []string {
+	return splitList(k.Scopes)
+}
+
+// AllowedIPList returns the IP addresses and CIDR ranges the key may be used from
+func (k *APIKey) AllowedIPList() []string {
+	return splitList(k.AllowedIPs)
+}
+
+// AllowsIP checks if the key may be used from an IP address. An empty allowlist allows any address.
+func (k *APIKey) AllowsIP(ipAddress string) bool {
+	rules := k.AllowedIPList()
+	if len(rules) == 0 {
+		return true
+	}
+
+	ip := net.ParseIP(ipAddress)
+	if ip == nil {
+		return false
+	}
+	for _, rule := range rules {
+		if _, network, err := net.ParseCIDR(rule); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if allowed := net.ParseIP(rule); allowed != nil && allowed.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRevoked checks if the key has been revoked
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
+
+// IsExpired checks if the key has passed its expiry, if it has one
+func (k *APIKey) IsExpired() bool {
+	return k.ExpiresAt != nil && time.Now().After(*k.ExpiresAt)
+}
+
+// IsActive checks if the key is neither revoked nor expired
+func (k *APIKey) IsActive() bool {
+	return !k.IsRevoked() && !k.IsExpired()
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audit actions
+const (
+	AuditActionMFASettingsUpdated       = "mfa.settings_updated"
+	AuditActionMFAReset                 = "mfa.admin_reset"
+	AuditActionRefreshTokenReused       = "auth.refresh_token_reused"
+	AuditActionPasswordReset            = "auth.password_reset"
+	AuditActionEmailChanged             = "user.email_changed"
+	AuditActionUserUnlocked             = "user.unlocked"
+	AuditActionUserCreated              = "user.created"
+	AuditActionUserUpdated              = "user.updated"
+	AuditActionUserDisabled             = "user.disabled"
+	AuditActionUserEnabled              = "user.enabled"
+	AuditActionUserDeleted              = "user.deleted"
+	AuditActionUserRestored             = "user.restored"
+	AuditActionPermissionGranted        = "role.permission_granted"
+	AuditActionPermissionRevoked        = "role.permission_revoked"
+	AuditActionImpersonationStarted     = "auth.impersonation_started"
+	AuditActionImpersonatedRequest      = "auth.impersonated_request"
+	AuditActionAPIKeyCreated            = "api_key.created"
+	AuditActionAPIKeyRevoked            = "api_key.revoked"
+	AuditActionOAuthClientCreated       = "oauth_client.created"
+	AuditActionOAuthClientRotated       = "oauth_client.secret_rotated"
+	AuditActionOAuthClientRevoked       = "oauth_client.revoked"
+	AuditActionSSOLinked                = "user.sso_linked"
+	AuditActionSSOProvisioned           = "user.sso_provisioned"
+	AuditActionPasskeyRegistered        = "mfa.passkey_registered"
+	AuditActionPasskeyDeleted           = "mfa.passkey_deleted"
+	AuditActionRecoveryCodesRegenerated = "mfa.recovery_codes_regenerated"
+)
+
+// AuditLog represents a security relevant change recorded for later review
+type AuditLog struct {
+	ID           int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	ActorUserID  *int      `json:"actor_user_id" gorm:"type:int;index"`
+	TargetUserID *int      `json:"target_user_id" gorm:"type:int;index"`
+	Action       string    `json:"action" gorm:"type:varchar(100);not null;index"`
+	Metadata     *string   `json:"metadata,omitempty" gorm:"type:json"`
+	IPAddress    string    `json:"ip_address" gorm:"type:varchar(45)"`
+	UserAgent    string    `json:"user_agent" gorm:"type:varchar(255)"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the database table name
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// NewAuditLog creates a new audit log entry with JSON encoded metadata
+func NewAuditLog(action string, actorUserID, targetUserID *int, metadata map[string]interface{}) (*AuditLog, error) {
+	auditLog := &AuditLog{
+		ActorUserID:  actorUserID,
+		TargetUserID: targetUserID,
+		Action:       action,
+	}
+
+	if len(metadata) > 0 {
+		encoded, err := json.Marshal(metadata)
+		if err != nil {
+			return nil, err
+		}
+		value := string(encoded)
+		auditLog.Metadata = &value
+	}
+
+	return auditLog, nil
+}
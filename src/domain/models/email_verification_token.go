@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// Email verification purposes
+const (
+	EmailVerificationPurposeVerify = "verify"       // Confirm the address given at registration
+	EmailVerificationPurposeChange = "change_email" // Confirm a new address before switching to it
+)
+
+// EmailVerificationToken represents a single-use token proving that a user controls an email address
+type EmailVerificationToken struct {
+	ID        int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    int        `json:"user_id" gorm:"type:int;not null;index"`
+	Email     string     `json:"email" gorm:"type:varchar(255);not null"`
+	Purpose   string     `json:"purpose" gorm:"type:varchar(20);not null"`
+	TokenHash string     `json:"-" gorm:"column:token_hash;type:varchar(64);not null;uniqueIndex"` // Never exposed in JSON
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the database table name
+func (EmailVerificationToken) TableName() string {
+	return "email_verification_tokens"
+}
+
+// IsExpired checks if the token has expired
+func (t *EmailVerificationToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+// IsUsed checks if the token has already been used or invalidated
+func (t *EmailVerificationToken) IsUsed() bool {
+	return t.UsedAt != nil
+}
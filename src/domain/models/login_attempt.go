@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+// Login failure reasons
+const (
+	LoginFailureUnknownUser     = "unknown_user"
+	LoginFailureBadPassword     = "bad_password"
+	LoginFailureBadMFACode      = "bad_mfa_code"
+	LoginFailureAccountLocked   = "account_locked"
+	LoginFailureAccountDisabled = "account_disabled"
+	LoginFailureIPThrottled     = "ip_throttled"
+	LoginFailureEmailUnverified = "email_unverified"
+	LoginFailureSSODenied       = "sso_denied"
+	LoginFailureBadPasskey      = "bad_passkey"
+)
+
+// LoginAttempt records a login attempt for throttling and later review by security staff
+type LoginAttempt struct {
+	ID            int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID        *int      `json:"user_id" gorm:"type:int;index"`
+	Email         string    `json:"email" gorm:"type:varchar(255);not null"`
+	IPAddress     string    `json:"ip_address" gorm:"type:varchar(45);not null"`
+	UserAgent     string    `json:"user_agent" gorm:"type:varchar(255)"`
+	Success       bool      `json:"success" gorm:"type:tinyint(1);not null"`
+	FailureReason string    `json:"failure_reason,omitempty" gorm:"type:varchar(30)"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the database table name
+func (LoginAttempt) TableName() string {
+	return "login_attempts"
+}
+
+// LoginAttemptFilter narrows down a search of login attempts. Empty fields are ignored.
+type LoginAttemptFilter struct {
+	UserID    *int
+	Email     string
+	IPAddress string
+	Success   *bool
+	Since     *time.Time
+	Until     *time.Time
+}
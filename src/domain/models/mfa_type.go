@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // MFAType represents a multi-factor authentication type
@@ -12,9 +14,17 @@ type MFAType struct {
 	IsActive  int       `json:"is_active" gorm:"type:int;not null"`
 	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
-	DeletedAt *time.Time `json:"deleted_at,omitempty" gorm:"index"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
 }
 
+// MFA type numbers as seeded in master_mfa_types
+const (
+	MFATypeNoOTP     = 1
+	MFATypeNoEmail   = 2
+	MFATypeNoSMS     = 3
+	MFATypeNoPasskey = 4
+)
+
 // TableName specifies the database table name
 func (MFAType) TableName() string {
 	return "master_mfa_types"
@@ -24,3 +34,23 @@ func (MFAType) TableName() string {
 func (m *MFAType) IsActiveType() bool {
 	return m.IsActive == 1
 }
+
+// IsOTP checks if this MFA type is the authenticator app (TOTP) type
+func (m *MFAType) IsOTP() bool {
+	return m.No == MFATypeNoOTP
+}
+
+// IsEmail checks if this MFA type is the email one-time code type
+func (m *MFAType) IsEmail() bool {
+	return m.No == MFATypeNoEmail
+}
+
+// IsSMS checks if this MFA type is the SMS one-time code type
+func (m *MFAType) IsSMS() bool {
+	return m.No == MFATypeNoSMS
+}
+
+// IsPasskey checks if this MFA type is the passkey (WebAuthn) type
+func (m *MFAType) IsPasskey() bool {
+	return m.No == MFATypeNoPasskey
+}
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+// Notification tells a user about a change to their account while they are online.
+// Notifications are only delivered live and are not stored.
+type Notification struct {
+	Type          string    `json:"type"`           // Audit action of the change, e.g. mfa.settings_updated
+	SelfInitiated bool      `json:"self_initiated"` // False when an administrator or the system made the change
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// OAuthClient is a partner application that obtains access tokens with the OAuth2
+// client_credentials grant. It acts on behalf of its owner with the scopes it was granted.
+type OAuthClient struct {
+	ID         int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	ClientID   string     `json:"client_id" gorm:"column:client_id;type:varchar(64);not null;uniqueIndex"`
+	Name       string     `json:"name" gorm:"type:varchar(100);not null"`
+	SecretHash string     `json:"-" gorm:"type:varchar(64);not null"` // Never exposed in JSON
+	UserID     int        `json:"user_id" gorm:"type:int;not null;index"`
+	User       *User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Scopes     string     `json:"scopes" gorm:"type:varchar(255);not null"` // Comma separated permission codes
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the database table name
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// ScopeList returns the permission codes granted to the client
+func (c *OAuthClient) ScopeList() []string {
+	return splitList(c.Scopes)
+}
+
+// IsRevoked checks if the client has been revoked
+func (c *OAuthClient) IsRevoked() bool {
+	return c.RevokedAt != nil
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+)
+
+// OTP delivery channels
+const (
+	OTPChannelEmail = "email"
+	OTPChannelSMS   = "sms"
+)
+
+// OTP purposes
+const (
+	OTPPurposeLogin             = "login"
+	OTPPurposePhoneVerification = "phone_verification"
+	OTPPurposeReauthentication  = "reauthentication"
+)
+
+// OTPCode represents a one-time code delivered to a user by email or SMS
+type OTPCode struct {
+	ID          int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID      int        `json:"user_id" gorm:"type:int;not null;index"`
+	Channel     string     `json:"channel" gorm:"type:varchar(20);not null"`
+	Purpose     string     `json:"purpose" gorm:"type:varchar(30);not null"`
+	Destination string     `json:"destination" gorm:"type:varchar(255);not null"`
+	CodeHash    string     `json:"-" gorm:"column:code_hash;type:varchar(255);not null"` // Never exposed in JSON
+	Attempts    int        `json:"attempts" gorm:"type:int;not null;default:0"`
+	ExpiresAt   time.Time  `json:"expires_at" gorm:"not null"`
+	ConsumedAt  *time.Time `json:"consumed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the database table name
+func (OTPCode) TableName() string {
+	return "user_otp_codes"
+}
+
+// IsExpired checks if the code has expired
+func (c *OTPCode) IsExpired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
+
+// IsConsumed checks if the code has already been used or invalidated
+func (c *OTPCode) IsConsumed() bool {
+	return c.ConsumedAt != nil
+}
+
+// IsUsable checks if the code can still be verified
+func (c *OTPCode) IsUsable(maxAttempts int) bool {
+	return !c.IsExpired() && !c.IsConsumed() && c.Attempts < maxAttempts
+}
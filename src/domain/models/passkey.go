@@ -0,0 +1,35 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Passkey is a WebAuthn credential a user has registered. A user can register several,
+// e.g. one per device, and use any of them as a second factor or to log in without a password.
+type Passkey struct {
+	ID             int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID         int        `json:"user_id" gorm:"type:int;not null;index"`
+	Name           string     `json:"name" gorm:"type:varchar(100);not null"`
+	CredentialID   []byte     `json:"-" gorm:"column:credential_id;type:varbinary(1023);not null;uniqueIndex"`
+	UserHandle     []byte     `json:"-" gorm:"column:user_handle;type:varbinary(64);not null"` // Same for every passkey of a user
+	PublicKey      []byte     `json:"-" gorm:"column:public_key;type:blob;not null"`           // COSE_Key
+	SignCount      uint32     `json:"-" gorm:"type:int unsigned;not null;default:0"`
+	Transports     string     `json:"transports" gorm:"type:varchar(255);not null;default:''"` // Comma separated hints such as usb,nfc,internal
+	BackupEligible bool       `json:"backup_eligible" gorm:"type:tinyint(1);not null;default:0"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the database table name
+func (Passkey) TableName() string {
+	return "user_passkeys"
+}
+
+// TransportList returns the transports the browser can use to reach the authenticator
+func (p *Passkey) TransportList() []string {
+	if p.Transports == "" {
+		return []string{}
+	}
+	return strings.Split(p.Transports, ",")
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+)
+
+// Passkey ceremony purposes. Second factor challenges use the OTP purposes, so a challenge
+// answered to log in cannot be used to confirm a settings change.
+const (
+	PasskeyPurposeRegistration = "registration"
+	PasskeyPurposePasswordless = "passwordless"
+)
+
+// PasskeyChallenge is a challenge sent to the browser for a passkey ceremony. It is stored
+// hashed and can be answered once. Passwordless logins have no user until the passkey is known.
+type PasskeyChallenge struct {
+	ID            int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID        *int       `json:"user_id,omitempty" gorm:"type:int"`
+	ChallengeHash string     `json:"-" gorm:"column:challenge_hash;type:varchar(64);not null;uniqueIndex"` // Never exposed in JSON
+	Purpose       string     `json:"purpose" gorm:"type:varchar(30);not null"`
+	UserHandle    []byte     `json:"-" gorm:"column:user_handle;type:varbinary(64)"` // Handle given to a new passkey
+	ExpiresAt     time.Time  `json:"expires_at" gorm:"not null;index"`
+	UsedAt        *time.Time `json:"used_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the database table name
+func (PasskeyChallenge) TableName() string {
+	return "passkey_challenges"
+}
+
+// IsExpired checks if the ceremony took too long
+func (c *PasskeyChallenge) IsExpired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
+
+// IsUsed checks if the challenge has already been answered
+func (c *PasskeyChallenge) IsUsed() bool {
+	return c.UsedAt != nil
+}
+
+// IsFor checks if the challenge was issued for the purpose and user.
+// Passwordless challenges have no user.
+func (c *PasskeyChallenge) IsFor(purpose string, userID *int) bool {
+	if c.Purpose != purpose {
+		return false
+	}
+	if c.UserID == nil || userID == nil {
+		return c.UserID == nil && userID == nil
+	}
+	return *c.UserID == *userID
+}
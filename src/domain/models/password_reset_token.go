@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// PasswordResetToken represents a single-use token emailed to a user who forgot their password
+type PasswordResetToken struct {
+	ID        int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    int        `json:"user_id" gorm:"type:int;not null;index"`
+	TokenHash string     `json:"-" gorm:"column:token_hash;type:varchar(64);not null;uniqueIndex"` // Never exposed in JSON
+	IPAddress string     `json:"ip_address" gorm:"type:varchar(45)"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the database table name
+func (PasswordResetToken) TableName() string {
+	return "password_reset_tokens"
+}
+
+// IsExpired checks if the token has expired
+func (t *PasswordResetToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+// IsUsed checks if the token has already been used or invalidated
+func (t *PasswordResetToken) IsUsed() bool {
+	return t.UsedAt != nil
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+)
+
+// Permission codes checked by the API. Roles are granted permissions through role_permissions.
+const (
+	PermissionUsersRead          = "users.read"
+	PermissionUsersManage        = "users.manage"
+	PermissionSecurityAuditRead  = "security.audit.read"
+	PermissionRolesManage        = "roles.manage"
+	PermissionPaymentsRead       = "payments.read"
+	PermissionPaymentsRefund     = "payments.refund"
+	PermissionReportsExport      = "reports.export"
+	PermissionOAuthClientsManage = "oauth_clients.manage"
+)
+
+// DelegatedScopes are the permissions that can be delegated to API keys and OAuth clients
+var DelegatedScopes = []string{
+	PermissionPaymentsRead,
+	PermissionPaymentsRefund,
+	PermissionReportsExport,
+}
+
+// IsDelegatedScope checks if a permission can be delegated to API keys and OAuth clients
+func IsDelegatedScope(code string) bool {
+	for _, scope := range DelegatedScopes {
+		if scope == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Permission represents an action that can be granted to roles
+type Permission struct {
+	ID          int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Code        string    `json:"code" gorm:"type:varchar(100);uniqueIndex"`
+	Description string    `json:"description" gorm:"type:varchar(255)"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the database table name
+func (Permission) TableName() string {
+	return "permissions"
+}
+
+// RolePermission grants a permission to a role
+type RolePermission struct {
+	RoleID       int       `json:"role_id" gorm:"primaryKey;type:int"`
+	PermissionID int       `json:"permission_id" gorm:"primaryKey;type:int"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the database table name
+func (RolePermission) TableName() string {
+	return "role_permissions"
+}
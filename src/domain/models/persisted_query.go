@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// PersistedQuery is a GraphQL document stored under the SHA-256 hash of its text.
+// Documents sent by clients through automatic persisted queries are only cached for a limited
+// time, while registered documents make up the allowlist of operations executable in allowlist mode.
+type PersistedQuery struct {
+	Hash       string    `json:"hash" gorm:"type:char(64);primaryKey"`
+	Query      string    `json:"query" gorm:"type:mediumtext;not null"`
+	Name       *string   `json:"name,omitempty" gorm:"type:varchar(255)"` // File the registered document was read from
+	Registered bool      `json:"registered" gorm:"type:tinyint(1);not null;default:0"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the database table name
+func (PersistedQuery) TableName() string {
+	return "persisted_queries"
+}
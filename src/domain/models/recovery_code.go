@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// RecoveryCode represents a single-use MFA recovery code
+type RecoveryCode struct {
+	ID        int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    int        `json:"user_id" gorm:"type:int;not null;index"`
+	CodeHash  string     `json:"-" gorm:"column:code_hash;type:varchar(64);not null"` // Never exposed in JSON
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the database table name
+func (RecoveryCode) TableName() string {
+	return "user_recovery_codes"
+}
+
+// IsUsed checks if the recovery code has already been consumed
+func (c *RecoveryCode) IsUsed() bool {
+	return c.UsedAt != nil
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken represents an opaque refresh token issued alongside an access token.
+// Tokens are rotated on every use; all tokens descending from the same login share a FamilyID.
+type RefreshToken struct {
+	ID         int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID     int        `json:"user_id" gorm:"type:int;not null;index"`
+	FamilyID   string     `json:"family_id" gorm:"type:varchar(36);not null;index"`
+	ParentID   *int       `json:"parent_id,omitempty" gorm:"type:int"`
+	TokenHash  string     `json:"-" gorm:"column:token_hash;type:varchar(64);not null;uniqueIndex"` // Never exposed in JSON
+	DeviceInfo string     `json:"device_info" gorm:"type:varchar(255)"`
+	IPAddress  string     `json:"ip_address" gorm:"type:varchar(45)"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the database table name
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// IsExpired checks if the token has expired
+func (t *RefreshToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+// IsRotated checks if the token has already been exchanged for a new one
+func (t *RefreshToken) IsRotated() bool {
+	return t.RotatedAt != nil
+}
+
+// IsRevoked checks if the token has been revoked
+func (t *RefreshToken) IsRevoked() bool {
+	return t.RevokedAt != nil
+}
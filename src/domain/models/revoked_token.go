@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// RevokedToken represents an access token revoked before its expiry, identified by its "jti" claim
+type RevokedToken struct {
+	JTI       string    `json:"jti" gorm:"column:jti;type:varchar(36);primaryKey"`
+	UserID    int       `json:"user_id" gorm:"type:int;not null;index"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the database table name
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
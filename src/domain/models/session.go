@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+)
+
+// Session represents a login on a device. Its ID is carried in the "sid" claim of access tokens
+// and is also the family ID of the refresh tokens issued for the login.
+type Session struct {
+	ID          string     `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID      int        `json:"user_id" gorm:"type:int;not null;index"`
+	DeviceLabel string     `json:"device_label" gorm:"type:varchar(100)"`
+	UserAgent   string     `json:"user_agent" gorm:"type:varchar(255)"`
+	IPAddress   string     `json:"ip_address" gorm:"type:varchar(45)"`
+	LastSeenAt  time.Time  `json:"last_seen_at" gorm:"not null"`
+	ExpiresAt   time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	// ImpersonatorID is the administrator acting as the user, for sessions started by impersonateUser
+	ImpersonatorID *int `json:"impersonator_id,omitempty" gorm:"type:int"`
+}
+
+// TableName specifies the database table name
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// IsRevoked checks if the session has been ended by the user, an admin or a logout
+func (s *Session) IsRevoked() bool {
+	return s.RevokedAt != nil
+}
+
+// IsExpired checks if the session can no longer be refreshed
+func (s *Session) IsExpired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
+
+// IsImpersonation checks if an administrator started the session to act as the user
+func (s *Session) IsImpersonation() bool {
+	return s.ImpersonatorID != nil
+}
+
+// IsActive checks if the session is neither revoked nor expired
+func (s *Session) IsActive() bool {
+	return !s.IsRevoked() && !s.IsExpired()
+}
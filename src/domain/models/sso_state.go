@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// SSOState is a sign-in started with the OpenID Connect provider. The state value sent to
+// the provider is stored hashed; the nonce and PKCE code verifier are needed to finish it.
+type SSOState struct {
+	ID           int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	StateHash    string     `json:"-" gorm:"column:state_hash;type:varchar(64);not null;uniqueIndex"` // Never exposed in JSON
+	Nonce        string     `json:"-" gorm:"type:varchar(64);not null"`
+	CodeVerifier string     `json:"-" gorm:"column:code_verifier;type:varchar(128);not null"`
+	ExpiresAt    time.Time  `json:"expires_at" gorm:"not null;index"`
+	UsedAt       *time.Time `json:"used_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the database table name
+func (SSOState) TableName() string {
+	return "sso_states"
+}
+
+// IsExpired checks if the sign-in took too long
+func (s *SSOState) IsExpired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
+
+// IsUsed checks if the sign-in has already been completed
+func (s *SSOState) IsUsed() bool {
+	return s.UsedAt != nil
+}
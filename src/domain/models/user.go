@@ -2,29 +2,63 @@ package models
 
 import (
 	"errors"
+	"net/mail"
+	"regexp"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 // User represents a user entity in the system
 type User struct {
-	ID            int       `json:"id" gorm:"primaryKey;autoIncrement"`
-	Email         string    `json:"email" gorm:"type:varchar(255);uniqueIndex"`
-	PasswordHash  string    `json:"-" gorm:"column:password_hash;type:varchar(255)"` // Never exposed in JSON
-	RoleID        int       `json:"role_id" gorm:"type:int;not null"`
-	Role          *Role     `json:"role" gorm:"foreignKey:RoleID"`
-	EnabledMFA    bool      `json:"enabled_mfa" gorm:"type:tinyint(1);default:1"`
-	MFATypeID     *int      `json:"mfa_type_id" gorm:"type:int"`
-	MFAType       *MFAType  `json:"mfa_type" gorm:"foreignKey:MFATypeID"`
-	LastName      string    `json:"last_name" gorm:"type:varchar(100);not null"`
-	FirstName     string    `json:"first_name" gorm:"type:varchar(100);not null"`
-	LastNameKana  string    `json:"last_name_kana" gorm:"type:varchar(100);not null"`
-	FirstNameKana string    `json:"first_name_kana" gorm:"type:varchar(100);not null"`
-	AvatarURL     *string   `json:"avatar_url,omitempty" gorm:"type:varchar(255)"`
-	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt     time.Time `json:"updated_at" gorm:"autoUpdateTime"`
-	DeletedAt     *time.Time `json:"deleted_at,omitempty" gorm:"index"`
+	ID              int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	Email           string     `json:"email" gorm:"type:varchar(255);uniqueIndex"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	PasswordHash    string     `json:"-" gorm:"column:password_hash;type:varchar(255)"` // Never exposed in JSON
+	// Lockout and disabled states are read-only here and only changed through
+	// UserRepository, so saving a stale user cannot reset them
+	FailedLoginAttempts int            `json:"failed_login_attempts" gorm:"->;type:int"`
+	LockoutCount        int            `json:"lockout_count" gorm:"->;type:int"`
+	LockedUntil         *time.Time     `json:"locked_until,omitempty" gorm:"->"`
+	DisabledAt          *time.Time     `json:"disabled_at,omitempty" gorm:"->"`
+	RoleID              int            `json:"role_id" gorm:"type:int;not null"`
+	Role                *Role          `json:"role" gorm:"foreignKey:RoleID"`
+	EnabledMFA          bool           `json:"enabled_mfa" gorm:"type:tinyint(1);default:1"`
+	MFATypeID           *int           `json:"mfa_type_id" gorm:"type:int"`
+	MFAType             *MFAType       `json:"mfa_type" gorm:"foreignKey:MFATypeID"`
+	PasskeyRequired     bool           `json:"passkey_required" gorm:"type:tinyint(1);not null;default:0"` // Set by an administrator, e.g. for staff handling payouts
+	LastName            string         `json:"last_name" gorm:"type:varchar(100);not null"`
+	FirstName           string         `json:"first_name" gorm:"type:varchar(100);not null"`
+	LastNameKana        string         `json:"last_name_kana" gorm:"type:varchar(100);not null"`
+	FirstNameKana       string         `json:"first_name_kana" gorm:"type:varchar(100);not null"`
+	AvatarURL           *string        `json:"avatar_url,omitempty" gorm:"type:varchar(255)"`
+	PhoneNumber         *string        `json:"phone_number,omitempty" gorm:"type:varchar(16)"`
+	PhoneVerifiedAt     *time.Time     `json:"phone_verified_at,omitempty"`
+	CreatedAt           time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt           time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt           gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+// e164Pattern matches phone numbers in E.164 format, e.g. +819012345678
+var e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+// ValidatePhoneNumber checks that a phone number is in E.164 format
+func ValidatePhoneNumber(phoneNumber string) error {
+	if !e164Pattern.MatchString(phoneNumber) {
+		return errors.New("phone number must be in E.164 format (e.g. +819012345678)")
+	}
+	return nil
+}
+
+// ValidateEmail checks that an email address is a plain address such as user@example.com
+func ValidateEmail(email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return errors.New("invalid email address")
+	}
+	return nil
 }
 
 // TableName specifies the database table name
@@ -65,14 +99,36 @@ func NewUser(email, password, firstName, lastName, firstNameKana, lastNameKana s
 	}
 
 	return &User{
-		Email:          email,
-		PasswordHash:   string(hashedPassword),
-		RoleID:         roleID,
-		EnabledMFA:     true, // Default to enabled
-		FirstName:      firstName,
-		LastName:       lastName,
-		FirstNameKana:  firstNameKana,
-		LastNameKana:   lastNameKana,
+		Email:         email,
+		PasswordHash:  string(hashedPassword),
+		RoleID:        roleID,
+		EnabledMFA:    true, // Default to enabled
+		FirstName:     firstName,
+		LastName:      lastName,
+		FirstNameKana: firstNameKana,
+		LastNameKana:  lastNameKana,
+	}, nil
+}
+
+// NewSSOUser creates a user signing in with an external identity provider.
+// The user has no password, so they cannot log in with one, and the provider has
+// already verified their email address. MFA is left to the provider.
+func NewSSOUser(email, firstName, lastName string, roleID int) (*User, error) {
+	if err := ValidateEmail(email); err != nil {
+		return nil, err
+	}
+	if firstName == "" && lastName == "" {
+		firstName = strings.SplitN(email, "@", 2)[0]
+	}
+
+	now := time.Now()
+	return &User{
+		Email:           email,
+		EmailVerifiedAt: &now,
+		RoleID:          roleID,
+		EnabledMFA:      false,
+		FirstName:       firstName,
+		LastName:        lastName,
 	}, nil
 }
 
@@ -103,7 +159,7 @@ func (u *User) UpdateProfile(firstName, lastName, firstNameKana, lastNameKana st
 	if firstName == "" || lastName == "" {
 		return errors.New("first name and last name cannot be empty")
 	}
-	
+
 	if firstNameKana == "" || lastNameKana == "" {
 		return errors.New("first name kana and last name kana cannot be empty")
 	}
@@ -123,6 +179,80 @@ func (u *User) SetMFA(enabled bool, mfaTypeID *int) {
 	u.UpdatedAt = time.Now()
 }
 
+// SetVerifiedPhoneNumber stores a phone number whose ownership has been verified
+func (u *User) SetVerifiedPhoneNumber(phoneNumber string) error {
+	if err := ValidatePhoneNumber(phoneNumber); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	u.PhoneNumber = &phoneNumber
+	u.PhoneVerifiedAt = &now
+	u.UpdatedAt = now
+	return nil
+}
+
+// IsLocked checks if the account is temporarily locked after too many failed logins
+func (u *User) IsLocked() bool {
+	return u.LockedUntil != nil && time.Now().Before(*u.LockedUntil)
+}
+
+// IsDisabled checks if an administrator has disabled the account
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
+}
+
+// IsDeleted checks if the account has been deleted
+func (u *User) IsDeleted() bool {
+	return u.DeletedAt.Valid
+}
+
+// CanLogin checks if the account may authenticate at all
+func (u *User) CanLogin() bool {
+	return !u.IsDisabled() && !u.IsDeleted()
+}
+
+// IsEmailVerified checks if the user has proven control of their email address
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// MarkEmailVerified records that the user controls their current email address
+func (u *User) MarkEmailVerified() {
+	now := time.Now()
+	u.EmailVerifiedAt = &now
+	u.UpdatedAt = now
+}
+
+// ChangeEmail switches to a new email address whose ownership has been verified
+func (u *User) ChangeEmail(email string) error {
+	if err := ValidateEmail(email); err != nil {
+		return err
+	}
+
+	u.Email = email
+	u.MarkEmailVerified()
+	return nil
+}
+
+// HasVerifiedPhoneNumber checks if the user has a verified phone number
+func (u *User) HasVerifiedPhoneNumber() bool {
+	return u.PhoneNumber != nil && u.PhoneVerifiedAt != nil
+}
+
+// RequiresMFA checks if the user must pass a second factor when logging in
+func (u *User) RequiresMFA() bool {
+	return u.EnabledMFA && u.MFATypeID != nil
+}
+
+// RequirePasskey makes passkeys the user's only accepted second factor.
+// The passkey MFA type must be one the user has already set up.
+func (u *User) RequirePasskey(passkeyType *MFAType) {
+	u.PasskeyRequired = true
+	u.SetMFA(true, &passkeyType.ID)
+	u.MFAType = passkeyType
+}
+
 // IsAdmin checks if the user has admin privileges
 func (u *User) IsAdmin() bool {
 	return u.Role != nil && u.Role.IsAdmin()
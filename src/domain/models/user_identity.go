@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// UserIdentity links a user to their account at an external OpenID Connect provider.
+// The provider identifies the account by its issuer and subject, which never change.
+type UserIdentity struct {
+	ID          int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID      int        `json:"user_id" gorm:"type:int;not null;index"`
+	Issuer      string     `json:"issuer" gorm:"type:varchar(255);not null;uniqueIndex:uk_user_identities_issuer_subject"`
+	Subject     string     `json:"subject" gorm:"type:varchar(255);not null;uniqueIndex:uk_user_identities_issuer_subject"`
+	Email       string     `json:"email" gorm:"type:varchar(255)"` // Email given by the provider when the identity was linked
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName specifies the database table name
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// UserTOTP represents a user's TOTP authenticator secret
+type UserTOTP struct {
+	ID              int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID          int        `json:"user_id" gorm:"type:int;not null;uniqueIndex"`
+	SecretEncrypted string     `json:"-" gorm:"column:secret_encrypted;type:varchar(255);not null"` // Never exposed in JSON
+	LastUsedStep    int64      `json:"-" gorm:"type:bigint;not null;default:0"`
+	ConfirmedAt     *time.Time `json:"confirmed_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt       time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName specifies the database table name
+func (UserTOTP) TableName() string {
+	return "user_totp_secrets"
+}
+
+// IsConfirmed checks if the enrollment has been confirmed with a valid code
+func (t *UserTOTP) IsConfirmed() bool {
+	return t.ConfirmedAt != nil
+}
+
+// Confirm marks the enrollment as confirmed
+func (t *UserTOTP) Confirm(step int64) {
+	now := time.Now()
+	t.ConfirmedAt = &now
+	t.LastUsedStep = step
+}
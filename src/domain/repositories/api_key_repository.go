@@ -0,0 +1,31 @@
+package repositories
+
+import (
+	"context"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+)
+
+// APIKeyRepository defines the interface for API key data access
+type APIKeyRepository interface {
+	// Create stores a new API key
+	Create(ctx context.Context, apiKey *models.APIKey) error
+
+	// FindByID finds an API key by ID
+	FindByID(ctx context.Context, id int) (*models.APIKey, error)
+
+	// FindByPrefix finds an API key by the public prefix of the key
+	FindByPrefix(ctx context.Context, prefix string) (*models.APIKey, error)
+
+	// ListByUser lists the API keys of a user, newest first
+	ListByUser(ctx context.Context, userID int) ([]*models.APIKey, error)
+
+	// Touch records a use of an API key from the given IP address
+	Touch(ctx context.Context, id int, ipAddress string) error
+
+	// Revoke revokes an API key. It returns false if the key was already revoked.
+	Revoke(ctx context.Context, id int) (bool, error)
+
+	// RevokeByUser revokes every API key of a user and returns how many were revoked
+	RevokeByUser(ctx context.Context, userID int) (int64, error)
+}
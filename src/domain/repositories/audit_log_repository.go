@@ -0,0 +1,13 @@
+package repositories
+
+import (
+	"context"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+)
+
+// AuditLogRepository defines the interface for audit log data access
+type AuditLogRepository interface {
+	// Create records a new audit log entry
+	Create(ctx context.Context, auditLog *models.AuditLog) error
+}
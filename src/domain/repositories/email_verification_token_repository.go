@@ -0,0 +1,23 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+)
+
+// EmailVerificationTokenRepository defines the interface for email verification token data access
+type EmailVerificationTokenRepository interface {
+	// Create stores a new token and invalidates older unused tokens of the same user and purpose
+	Create(ctx context.Context, token *models.EmailVerificationToken) error
+
+	// FindByHash finds a token by the hash of its value
+	FindByHash(ctx context.Context, tokenHash string) (*models.EmailVerificationToken, error)
+
+	// CountCreatedSince counts tokens sent to a user since the given time
+	CountCreatedSince(ctx context.Context, userID int, since time.Time) (int64, error)
+
+	// MarkUsed marks a token as used. It returns false if the token was already used.
+	MarkUsed(ctx context.Context, id int) (bool, error)
+}
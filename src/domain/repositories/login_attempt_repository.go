@@ -0,0 +1,20 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+)
+
+// LoginAttemptRepository defines the interface for login attempt data access
+type LoginAttemptRepository interface {
+	// Create records a login attempt
+	Create(ctx context.Context, attempt *models.LoginAttempt) error
+
+	// CountFailedByIPSince counts failed attempts from an IP address since the given time
+	CountFailedByIPSince(ctx context.Context, ipAddress string, since time.Time) (int64, error)
+
+	// List lists attempts matching the filter, newest first, with pagination
+	List(ctx context.Context, filter models.LoginAttemptFilter, page, pageSize int) ([]*models.LoginAttempt, int, error)
+}
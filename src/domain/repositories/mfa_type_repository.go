@@ -0,0 +1,26 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+)
+
+// MFATypeRepository defines the interface for MFA type data access
+type MFATypeRepository interface {
+	// FindByID finds an MFA type by ID
+	FindByID(ctx context.Context, id int) (*models.MFAType, error)
+
+	// FindByIDs finds the MFA types with the given IDs
+	FindByIDs(ctx context.Context, ids []int) ([]*models.MFAType, error)
+
+	// FindByNo finds an MFA type by its number
+	FindByNo(ctx context.Context, no int) (*models.MFAType, error)
+
+	// List lists all MFA types ordered by number
+	List(ctx context.Context) ([]*models.MFAType, error)
+
+	// PurgeDeleted permanently removes MFA types deleted before the given time
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,31 @@
+package repositories
+
+import (
+	"context"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+)
+
+// OAuthClientRepository defines the interface for OAuth client data access
+type OAuthClientRepository interface {
+	// Create stores a new OAuth client
+	Create(ctx context.Context, client *models.OAuthClient) error
+
+	// FindByID finds an OAuth client by ID
+	FindByID(ctx context.Context, id int) (*models.OAuthClient, error)
+
+	// FindByClientID finds an OAuth client by its public client identifier
+	FindByClientID(ctx context.Context, clientID string) (*models.OAuthClient, error)
+
+	// List lists every OAuth client, newest first
+	List(ctx context.Context) ([]*models.OAuthClient, error)
+
+	// UpdateSecret replaces the secret hash of an OAuth client
+	UpdateSecret(ctx context.Context, id int, secretHash string) error
+
+	// Revoke revokes an OAuth client. It returns false if the client was already revoked.
+	Revoke(ctx context.Context, id int) (bool, error)
+
+	// RevokeByUser revokes every OAuth client of a user and returns how many were revoked
+	RevokeByUser(ctx context.Context, userID int) (int64, error)
+}
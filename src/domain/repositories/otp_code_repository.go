@@ -0,0 +1,26 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+)
+
+// OTPCodeRepository defines the interface for one-time code data access
+type OTPCodeRepository interface {
+	// Create stores a new code and invalidates older active codes for the same user, channel and purpose
+	Create(ctx context.Context, code *models.OTPCode) error
+
+	// FindLatestActive finds the newest unconsumed code for a user, channel and purpose
+	FindLatestActive(ctx context.Context, userID int, channel, purpose string) (*models.OTPCode, error)
+
+	// IncrementAttempts records a failed verification attempt
+	IncrementAttempts(ctx context.Context, id int) error
+
+	// CountCreatedSince counts codes sent to a user on a channel since the given time
+	CountCreatedSince(ctx context.Context, userID int, channel string, since time.Time) (int64, error)
+
+	// MarkConsumed marks a code as used. It returns false if the code was already consumed.
+	MarkConsumed(ctx context.Context, id int) (bool, error)
+}
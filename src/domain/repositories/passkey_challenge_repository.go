@@ -0,0 +1,19 @@
+package repositories
+
+import (
+	"context"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+)
+
+// PasskeyChallengeRepository defines the interface for passkey challenge data access
+type PasskeyChallengeRepository interface {
+	// Create stores a new challenge and deletes expired ones
+	Create(ctx context.Context, challenge *models.PasskeyChallenge) error
+
+	// FindByHash finds a challenge by the hash of its value
+	FindByHash(ctx context.Context, challengeHash string) (*models.PasskeyChallenge, error)
+
+	// MarkUsed marks a challenge as used. It returns false if the challenge was already used.
+	MarkUsed(ctx context.Context, id int) (bool, error)
+}
@@ -0,0 +1,32 @@
+package repositories
+
+import (
+	"context"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+)
+
+// PasskeyRepository defines the interface for passkey data access
+type PasskeyRepository interface {
+	// Create stores a new passkey
+	Create(ctx context.Context, passkey *models.Passkey) error
+
+	// FindByID finds a passkey by ID
+	FindByID(ctx context.Context, id int) (*models.Passkey, error)
+
+	// FindByCredentialID finds a passkey by the credential ID the authenticator gave it
+	FindByCredentialID(ctx context.Context, credentialID []byte) (*models.Passkey, error)
+
+	// ListByUser lists the passkeys of a user, oldest first
+	ListByUser(ctx context.Context, userID int) ([]*models.Passkey, error)
+
+	// UpdateSignCount records a use of a passkey if its signature counter is still the given one.
+	// It returns false when another use has been recorded in the meantime.
+	UpdateSignCount(ctx context.Context, id int, previousCount, signCount uint32) (bool, error)
+
+	// Delete removes a passkey
+	Delete(ctx context.Context, id int) error
+
+	// DeleteByUserID removes every passkey of a user
+	DeleteByUserID(ctx context.Context, userID int) error
+}
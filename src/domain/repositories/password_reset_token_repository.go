@@ -0,0 +1,23 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+)
+
+// PasswordResetTokenRepository defines the interface for password reset token data access
+type PasswordResetTokenRepository interface {
+	// Create stores a new token and invalidates older unused tokens of the same user
+	Create(ctx context.Context, token *models.PasswordResetToken) error
+
+	// FindByHash finds a token by the hash of its value
+	FindByHash(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error)
+
+	// CountCreatedSince counts tokens requested for a user since the given time
+	CountCreatedSince(ctx context.Context, userID int, since time.Time) (int64, error)
+
+	// MarkUsed marks a token as used. It returns false if the token was already used.
+	MarkUsed(ctx context.Context, id int) (bool, error)
+}
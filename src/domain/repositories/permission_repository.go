@@ -0,0 +1,25 @@
+package repositories
+
+import (
+	"context"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+)
+
+// PermissionRepository defines the interface for permission data access
+type PermissionRepository interface {
+	// List lists every permission ordered by code
+	List(ctx context.Context) ([]*models.Permission, error)
+
+	// FindByCode finds a permission by code
+	FindByCode(ctx context.Context, code string) (*models.Permission, error)
+
+	// ListByRole lists the permissions granted to a role ordered by code
+	ListByRole(ctx context.Context, roleID int) ([]*models.Permission, error)
+
+	// Grant grants a permission to a role. Granting it twice has no effect.
+	Grant(ctx context.Context, roleID, permissionID int) error
+
+	// Revoke removes a permission from a role
+	Revoke(ctx context.Context, roleID, permissionID int) error
+}
@@ -0,0 +1,28 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+)
+
+// PersistedQueryRepository defines the interface for persisted query data access
+type PersistedQueryRepository interface {
+	// FindByHash finds a persisted query by the hash of its text
+	FindByHash(ctx context.Context, hash string) (*models.PersistedQuery, error)
+
+	// Save stores a query sent by a client. A known hash keeps its row, which is marked as
+	// sent again so that it does not expire.
+	Save(ctx context.Context, query *models.PersistedQuery) error
+
+	// PruneUnregistered removes the queries sent by clients that were last sent before the
+	// given time, then the oldest of them beyond the given number
+	PruneUnregistered(ctx context.Context, before time.Time, keep int) (int64, error)
+
+	// Register adds a query to the allowlist, creating or updating its row
+	Register(ctx context.Context, query *models.PersistedQuery) error
+
+	// UnregisterExcept removes every query from the allowlist except the given ones
+	UnregisterExcept(ctx context.Context, hashes []string) (int64, error)
+}
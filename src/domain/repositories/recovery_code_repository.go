@@ -0,0 +1,22 @@
+package repositories
+
+import (
+	"context"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+)
+
+// RecoveryCodeRepository defines the interface for MFA recovery code data access
+type RecoveryCodeRepository interface {
+	// ReplaceForUser deletes all existing codes of a user and stores the new ones
+	ReplaceForUser(ctx context.Context, userID int, codes []*models.RecoveryCode) error
+
+	// FindUnusedByHash finds an unused recovery code of a user by its hash
+	FindUnusedByHash(ctx context.Context, userID int, codeHash string) (*models.RecoveryCode, error)
+
+	// MarkUsed marks a recovery code as used. It returns false if the code was already used.
+	MarkUsed(ctx context.Context, id int) (bool, error)
+
+	// DeleteByUserID removes all recovery codes of a user
+	DeleteByUserID(ctx context.Context, userID int) error
+}
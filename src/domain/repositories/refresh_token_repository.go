@@ -0,0 +1,25 @@
+package repositories
+
+import (
+	"context"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+)
+
+// RefreshTokenRepository defines the interface for refresh token data access
+type RefreshTokenRepository interface {
+	// Create stores a new refresh token
+	Create(ctx context.Context, token *models.RefreshToken) error
+
+	// FindByHash finds a refresh token by the hash of its value
+	FindByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+
+	// MarkRotated marks a token as exchanged. It returns false if the token was already rotated or revoked.
+	MarkRotated(ctx context.Context, id int) (bool, error)
+
+	// RevokeFamily revokes every token descending from the same login
+	RevokeFamily(ctx context.Context, familyID string) error
+
+	// RevokeAllForUser revokes every refresh token of a user
+	RevokeAllForUser(ctx context.Context, userID int) error
+}
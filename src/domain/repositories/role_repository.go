@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"time"
 
 	models "github.com/vnlab/makeshop-payment/src/domain/models"
 )
@@ -11,6 +12,16 @@ type RoleRepository interface {
 	// FindByID finds a role by ID
 	FindByID(ctx context.Context, id int) (*models.Role, error)
 
+	// FindByIDs finds the roles with the given IDs
+	FindByIDs(ctx context.Context, ids []int) ([]*models.Role, error)
+
 	// FindByCode finds a role by code
 	FindByCode(ctx context.Context, code string) (*models.Role, error)
+
+	// List lists every role ordered by ID
+	List(ctx context.Context) ([]*models.Role, error)
+
+	// PurgeDeleted permanently removes roles deleted before the given time
+	// that no user, including deleted users, still refers to
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
 }
@@ -0,0 +1,30 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+)
+
+// SessionRepository defines the interface for session data access
+type SessionRepository interface {
+	// Create stores a new session
+	Create(ctx context.Context, session *models.Session) error
+
+	// FindByID finds a session by ID
+	FindByID(ctx context.Context, id string) (*models.Session, error)
+
+	// ListActiveByUser lists the sessions of a user that are neither revoked nor expired, most recently used first
+	ListActiveByUser(ctx context.Context, userID int) ([]*models.Session, error)
+
+	// Touch records activity on a session from the given IP address.
+	// The expiry is only moved when expiresAt is not nil.
+	Touch(ctx context.Context, id string, ipAddress string, expiresAt *time.Time) error
+
+	// Revoke revokes a session. It returns false if the session was already revoked.
+	Revoke(ctx context.Context, id string) (bool, error)
+
+	// RevokeAllForUser revokes every session of a user except the given one, and returns the revoked IDs
+	RevokeAllForUser(ctx context.Context, userID int, exceptID string) ([]string, error)
+}
@@ -0,0 +1,19 @@
+package repositories
+
+import (
+	"context"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+)
+
+// SSOStateRepository defines the interface for single sign-on state data access
+type SSOStateRepository interface {
+	// Create stores a new state and deletes expired ones
+	Create(ctx context.Context, state *models.SSOState) error
+
+	// FindByHash finds a state by the hash of its value
+	FindByHash(ctx context.Context, stateHash string) (*models.SSOState, error)
+
+	// MarkUsed marks a state as used. It returns false if the state was already used.
+	MarkUsed(ctx context.Context, id int) (bool, error)
+}
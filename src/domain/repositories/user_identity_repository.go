@@ -0,0 +1,19 @@
+package repositories
+
+import (
+	"context"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+)
+
+// UserIdentityRepository defines the interface for external identity data access
+type UserIdentityRepository interface {
+	// Create links an external identity to a user
+	Create(ctx context.Context, identity *models.UserIdentity) error
+
+	// FindByIssuerAndSubject finds the identity of a provider account
+	FindByIssuerAndSubject(ctx context.Context, issuer, subject string) (*models.UserIdentity, error)
+
+	// TouchLastLogin records that the identity was used to sign in
+	TouchLastLogin(ctx context.Context, id int) error
+}
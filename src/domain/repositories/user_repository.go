@@ -2,18 +2,31 @@ package repositories
 
 import (
 	"context"
+	"time"
 
 	models "github.com/vnlab/makeshop-payment/src/domain/models"
 )
 
 // UserRepository defines the interface for user data access
 type UserRepository interface {
-	// FindByID finds a user by ID
+	// FindByID finds a user by ID, ignoring deleted users
 	FindByID(ctx context.Context, id int) (*models.User, error)
 
-	// FindByEmail finds a user by email
+	// FindByIDIncludingDeleted finds a user by ID, deleted or not
+	FindByIDIncludingDeleted(ctx context.Context, id int) (*models.User, error)
+
+	// FindByIDs finds the users with the given IDs, ignoring deleted users
+	FindByIDs(ctx context.Context, ids []int) ([]*models.User, error)
+
+	// FindByIDsIncludingDeleted finds the users with the given IDs, deleted or not
+	FindByIDsIncludingDeleted(ctx context.Context, ids []int) ([]*models.User, error)
+
+	// FindByEmail finds a user by email, ignoring deleted users
 	FindByEmail(ctx context.Context, email string) (*models.User, error)
 
+	// FindByEmailIncludingDeleted finds a user by email, deleted or not
+	FindByEmailIncludingDeleted(ctx context.Context, email string) (*models.User, error)
+
 	// Create creates a new user
 	Create(ctx context.Context, user *models.User) error
 
@@ -23,6 +36,30 @@ type UserRepository interface {
 	// Delete soft-deletes a user by ID
 	Delete(ctx context.Context, id int) error
 
+	// IncrementFailedLogins records a failed login and returns the number of consecutive failures
+	IncrementFailedLogins(ctx context.Context, id int) (int, error)
+
+	// Lock locks an account until the given time and resets the failure counter
+	Lock(ctx context.Context, id int, until time.Time) error
+
+	// ResetLoginFailures clears the failure counter, the lockout history and any lock
+	ResetLoginFailures(ctx context.Context, id int) error
+
+	// SetDisabled disables an account at the given time, or enables it when at is nil
+	SetDisabled(ctx context.Context, id int, at *time.Time) error
+
+	// Restore restores a deleted user
+	Restore(ctx context.Context, id int) error
+
+	// CountActiveByRole counts the users of a role that are neither disabled nor deleted
+	CountActiveByRole(ctx context.Context, roleID int) (int64, error)
+
+	// PurgeDeleted permanently removes users deleted before the given time
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+
 	// List lists all users with pagination
 	List(ctx context.Context, page, pageSize int) ([]*models.User, int, error)
+
+	// ListDeleted lists deleted users with pagination, most recently deleted first
+	ListDeleted(ctx context.Context, page, pageSize int) ([]*models.User, int, error)
 }
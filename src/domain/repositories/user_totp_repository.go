@@ -0,0 +1,23 @@
+package repositories
+
+import (
+	"context"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+)
+
+// UserTOTPRepository defines the interface for TOTP secret data access
+type UserTOTPRepository interface {
+	// FindByUserID finds the TOTP secret of a user
+	FindByUserID(ctx context.Context, userID int) (*models.UserTOTP, error)
+
+	// Save creates or updates a TOTP secret
+	Save(ctx context.Context, totp *models.UserTOTP) error
+
+	// UpdateLastUsedStep records the last accepted time step if it is newer than the stored one.
+	// It returns false when the step has already been used (replay).
+	UpdateLastUsedStep(ctx context.Context, id int, step int64) (bool, error)
+
+	// DeleteByUserID removes the TOTP secret of a user
+	DeleteByUserID(ctx context.Context, userID int) error
+}
@@ -1,22 +1,37 @@
 package auth
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"os"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/config"
 )
 
+// Token types carried in the "typ" claim
+const (
+	// TokenTypeAccess is a regular access token accepted by the API
+	TokenTypeAccess = "access"
+	// TokenTypeMFAPending is a short-lived challenge token issued after the password check
+	// that can only be exchanged for an access token through MFA verification
+	TokenTypeMFAPending = "mfa_pending"
+	// TokenTypeClient is an access token issued to an OAuth client with the client_credentials grant
+	TokenTypeClient = "client"
+)
+
+// mfaChallengeDuration is the lifetime of an MFA challenge token
+const mfaChallengeDuration = 5 * time.Minute
+
 // JWTService provides JWT token generation and validation
 type JWTService struct {
-	secretKey     string
-	tokenDuration time.Duration
-	blacklist     map[string]time.Time // Map to store blacklisted tokens
-	mutex         sync.RWMutex         // Mutex to ensure thread-safety
+	keyRing         *KeyRing
+	tokenDuration   time.Duration
+	revocationStore RevocationStore
 }
 
 // TokenClaims represents the claims in a JWT token
@@ -25,39 +40,89 @@ type TokenClaims struct {
 	Email     string `json:"email"`
 	RoleID    int    `json:"role_id"`
 	RoleCode  string `json:"role_code,omitempty"`
+	TokenType string `json:"typ,omitempty"`
+	SessionID string `json:"sid,omitempty"`
+	// Actor is set when an administrator is acting as the user
+	Actor *ActorClaims `json:"act,omitempty"`
+	// ClientID and Scope are set on tokens issued to OAuth clients, as in RFC 9068
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// NewJWTService creates a new JWTService
-func NewJWTService() *JWTService {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "default_jwt_secret_key_change_in_production"
-	}
+// ActorClaims identifies the administrator behind an impersonation token, as in the RFC 8693 "act" claim
+type ActorClaims struct {
+	Subject string `json:"sub"`
+	UserID  int    `json:"user_id"`
+	Email   string `json:"email,omitempty"`
+}
 
-	// Get token duration from environment (default 24 hours)
-	var tokenDuration time.Duration
-	tokenExpirationHours := os.Getenv("JWT_EXPIRATION_HOURS")
-	if tokenExpirationHours == "" {
-		tokenDuration = 24 * time.Hour
-	} else {
-		var hours int
-		fmt.Sscanf(tokenExpirationHours, "%d", &hours)
-		if hours <= 0 {
-			hours = 24
-		}
-		tokenDuration = time.Duration(hours) * time.Hour
+// IsImpersonation checks if the token was issued to an administrator acting as its user
+func (c *TokenClaims) IsImpersonation() bool {
+	return c.Actor != nil
+}
+
+// NewJWTService creates a new JWTService signing tokens with the active key of the ring
+func NewJWTService(appConfig *config.Config, keyRing *KeyRing, revocationStore RevocationStore) *JWTService {
+	// Access tokens are short-lived and renewed with a refresh token (default 15 minutes)
+	minutes := appConfig.AccessTokenMinutes
+	if minutes <= 0 {
+		minutes = 15
 	}
 
 	return &JWTService{
-		secretKey:     secret,
-		tokenDuration: tokenDuration,
-		blacklist:     make(map[string]time.Time),
+		keyRing:         keyRing,
+		tokenDuration:   time.Duration(minutes) * time.Minute,
+		revocationStore: revocationStore,
+	}
+}
+
+// GenerateToken generates a new access token for a user's session
+func (s *JWTService) GenerateToken(user *models.User, sessionID string) (string, error) {
+	return s.generateToken(user, TokenTypeAccess, sessionID, s.tokenDuration, nil)
+}
+
+// GenerateImpersonationToken generates an access token for a user that names the
+// administrator acting as them in the "act" claim. It cannot be refreshed.
+func (s *JWTService) GenerateImpersonationToken(user, actor *models.User, sessionID string, duration time.Duration) (string, error) {
+	if actor == nil {
+		return "", errors.New("actor is nil")
+	}
+	return s.generateToken(user, TokenTypeAccess, sessionID, duration, actor)
+}
+
+// GenerateClientToken generates an access token for an OAuth client acting on behalf of its owner.
+// The scopes are space separated in the "scope" claim and the client is the subject.
+func (s *JWTService) GenerateClientToken(clientID string, ownerID int, scopes []string, duration time.Duration) (string, error) {
+	now := time.Now()
+	claims := TokenClaims{
+		UserID:    ownerID,
+		TokenType: TokenTypeClient,
+		ClientID:  clientID,
+		Scope:     strings.Join(scopes, " "),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Subject:   clientID,
+		},
 	}
+	return s.sign(claims)
 }
 
-// GenerateToken generates a new JWT token for a user
-func (s *JWTService) GenerateToken(user *models.User) (string, error) {
+// TokenDuration returns the lifetime of access tokens
+func (s *JWTService) TokenDuration() time.Duration {
+	return s.tokenDuration
+}
+
+// GenerateMFAChallengeToken generates a short-lived token proving that the password
+// check succeeded. It is only accepted by ValidateMFAChallengeToken.
+func (s *JWTService) GenerateMFAChallengeToken(user *models.User) (string, error) {
+	return s.generateToken(user, TokenTypeMFAPending, "", mfaChallengeDuration, nil)
+}
+
+// generateToken signs a token of the given type for a user, on behalf of actor if not nil
+func (s *JWTService) generateToken(user *models.User, tokenType, sessionID string, duration time.Duration, actor *models.User) (string, error) {
 	if user == nil {
 		return "", errors.New("user is nil")
 	}
@@ -72,35 +137,122 @@ func (s *JWTService) GenerateToken(user *models.User) (string, error) {
 		Email:     user.Email,
 		RoleID:    user.RoleID,
 		RoleCode:  roleCode,
+		TokenType: tokenType,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.tokenDuration)),
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Subject:   fmt.Sprintf("%d", user.ID),
 		},
 	}
+	if actor != nil {
+		claims.Actor = &ActorClaims{
+			Subject: fmt.Sprintf("%d", actor.ID),
+			UserID:  actor.ID,
+			Email:   actor.Email,
+		}
+	}
+
+	return s.sign(claims)
+}
+
+// sign signs claims with the active key and names the key in the "kid" header
+func (s *JWTService) sign(claims TokenClaims) (string, error) {
+	key := s.keyRing.Active()
+	token := jwt.NewWithClaims(key.Method(), claims)
+	token.Header["kid"] = key.KID
+	return token.SignedString(key.PrivateKey)
+}
+
+// ValidateToken validates the provided access token string and returns the claims
+func (s *JWTService) ValidateToken(ctx context.Context, tokenString string) (*TokenClaims, error) {
+	claims, err := s.parseToken(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tokens issued before the "typ" claim existed are access tokens
+	if claims.TokenType != "" && claims.TokenType != TokenTypeAccess {
+		return nil, errors.New("invalid token type")
+	}
+
+	return claims, nil
+}
+
+// ValidateMFAChallengeToken validates an MFA challenge token and returns the claims
+func (s *JWTService) ValidateMFAChallengeToken(ctx context.Context, tokenString string) (*TokenClaims, error) {
+	claims, err := s.parseToken(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.TokenType != TokenTypeMFAPending {
+		return nil, errors.New("invalid token type")
+	}
+
+	return claims, nil
+}
+
+// ValidateClientToken validates an access token issued to an OAuth client and returns the claims
+func (s *JWTService) ValidateClientToken(ctx context.Context, tokenString string) (*TokenClaims, error) {
+	claims, err := s.parseToken(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.secretKey))
+	if claims.TokenType != TokenTypeClient || claims.ClientID == "" {
+		return nil, errors.New("invalid token type")
+	}
+
+	return claims, nil
 }
 
-// ValidateToken validates the provided token string and returns the claims
-func (s *JWTService) ValidateToken(tokenString string) (*TokenClaims, error) {
-	// Check if the token is blacklisted
-	s.mutex.RLock()
-	_, blacklisted := s.blacklist[tokenString]
-	s.mutex.RUnlock()
-	
-	if blacklisted {
+// parseToken verifies a token and checks that it has not been revoked
+func (s *JWTService) parseToken(ctx context.Context, tokenString string) (*TokenClaims, error) {
+	claims, err := s.verifyToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tokens without an ID cannot be revoked and are therefore not accepted
+	if claims.ID == "" {
+		return nil, errors.New("invalid token")
+	}
+
+	revoked, err := s.revocationStore.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errors.New("token has been revoked")
+	}
+
+	validAfter, err := s.revocationStore.TokensValidAfter(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if claims.IssuedAt == nil || claims.IssuedAt.Time.Before(validAfter) {
 		return nil, errors.New("token has been revoked")
 	}
 
+	return claims, nil
+}
+
+// verifyToken verifies the signature and expiry of a token and returns its claims
+func (s *JWTService) verifyToken(tokenString string) (*TokenClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// Validate signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := s.keyRing.Key(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key: %q", kid)
+		}
+		// The algorithm is bound to the key, never taken from the token alone
+		if token.Method.Alg() != key.Algorithm {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(s.secretKey), nil
-	})
+		return key.PublicKey, nil
+	}, jwt.WithValidMethods(s.keyRing.Algorithms()))
 
 	if err != nil {
 		return nil, err
@@ -113,47 +265,34 @@ func (s *JWTService) ValidateToken(tokenString string) (*TokenClaims, error) {
 	return nil, errors.New("invalid token")
 }
 
-// BlacklistToken adds a token to the blacklist
-func (s *JWTService) BlacklistToken(tokenString string) {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-	s.blacklist[tokenString] = time.Now()
-	
-	// Auto cleanup expired tokens from the blacklist
-	s.cleanupBlacklist()
+// RevokeToken revokes a single token until it expires
+func (s *JWTService) RevokeToken(ctx context.Context, tokenString string) error {
+	claims, err := s.verifyToken(tokenString)
+	if err != nil {
+		return err
+	}
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return errors.New("invalid token")
+	}
+
+	return s.revocationStore.Revoke(ctx, claims.ID, claims.UserID, claims.ExpiresAt.Time)
 }
 
-// IsBlacklisted checks if a token is in the blacklist
-func (s *JWTService) IsBlacklisted(tokenString string) bool {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
-	_, exists := s.blacklist[tokenString]
-	return exists
+// RevokeAllForUser revokes every token issued to a user so far
+func (s *JWTService) RevokeAllForUser(ctx context.Context, userID int) error {
+	return s.revocationStore.RevokeAllForUser(ctx, userID, time.Now())
+}
+
+// JWKS returns the public keys used to verify tokens
+func (s *JWTService) JWKS() JWKS {
+	return s.keyRing.JWKS()
 }
 
 // ExtractUserIDFromToken extracts user ID from a token string
-func (s *JWTService) ExtractUserIDFromToken(tokenString string) (int, error) {
-	claims, err := s.ValidateToken(tokenString)
+func (s *JWTService) ExtractUserIDFromToken(ctx context.Context, tokenString string) (int, error) {
+	claims, err := s.ValidateToken(ctx, tokenString)
 	if err != nil {
 		return 0, err
 	}
 	return claims.UserID, nil
 }
-
-// cleanupBlacklist removes expired tokens from the blacklist
-func (s *JWTService) cleanupBlacklist() {
-	// Implement cleanupBlacklist in a goroutine to avoid blocking the main process
-	go func() {
-		// Ensure thread-safety when deleting
-		s.mutex.Lock()
-		defer s.mutex.Unlock()
-		
-		// Check and delete tokens that have expired (e.g., over 24 hours)
-		threshold := time.Now().Add(-24 * time.Hour)
-		for token, blacklistedAt := range s.blacklist {
-			if blacklistedAt.Before(threshold) {
-				delete(s.blacklist, token)
-			}
-		}
-	}()
-}
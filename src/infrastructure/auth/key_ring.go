@@ -0,0 +1,316 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/config"
+)
+
+// Supported signing algorithms
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+)
+
+// rsaKeyBits is the size of generated RSA keys
+const rsaKeyBits = 3072
+
+// SigningKey is a key pair identified by the "kid" header of the tokens it signs.
+// Keys loaded from a public key PEM can only verify tokens.
+type SigningKey struct {
+	KID        string
+	Algorithm  string
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+// Method returns the JWT signing method of the key
+func (k *SigningKey) Method() jwt.SigningMethod {
+	if k.Algorithm == AlgorithmES256 {
+		return jwt.SigningMethodES256
+	}
+	return jwt.SigningMethodRS256
+}
+
+// KeyRing holds the active signing key and the previous keys still accepted for verification
+type KeyRing struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+// NewKeyRing creates a key ring from an active key and previous keys
+func NewKeyRing(active *SigningKey, previous ...*SigningKey) (*KeyRing, error) {
+	if active == nil || active.PrivateKey == nil {
+		return nil, errors.New("active signing key must have a private key")
+	}
+
+	ring := &KeyRing{
+		active: active,
+		keys:   map[string]*SigningKey{active.KID: active},
+	}
+	for _, key := range previous {
+		if _, exists := ring.keys[key.KID]; exists {
+			return nil, fmt.Errorf("duplicate key id: %s", key.KID)
+		}
+		ring.keys[key.KID] = key
+	}
+	return ring, nil
+}
+
+// LoadKeyRing loads the signing keys configured by JWT_KEY_DIRECTORY or JWT_PRIVATE_KEY_FILE.
+// It returns nil if no key is configured.
+func LoadKeyRing(appConfig *config.Config) (*KeyRing, error) {
+	if appConfig.JWTKeyDirectory != "" {
+		return LoadKeyRingFromDirectory(appConfig.JWTKeyDirectory, appConfig.JWTActiveKID)
+	}
+
+	if appConfig.JWTPrivateKeyFile == "" {
+		return nil, nil
+	}
+
+	active, err := LoadSigningKey(appConfig.JWTPrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var previous []*SigningKey
+	for _, path := range strings.Split(appConfig.JWTPreviousKeyFiles, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		key, err := LoadSigningKey(path)
+		if err != nil {
+			return nil, err
+		}
+		previous = append(previous, key)
+	}
+
+	return NewKeyRing(active, previous...)
+}
+
+// LoadKeyRingFromDirectory loads every <kid>.pem file of a directory.
+// The active key is activeKID, or the last key in lexical order when activeKID is empty,
+// which is the newest key for files created by GenerateKeyFile.
+func LoadKeyRingFromDirectory(dir, activeKID string) (*KeyRing, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no signing keys found in %s", dir)
+	}
+	sort.Strings(paths)
+
+	keys := make([]*SigningKey, 0, len(paths))
+	for _, path := range paths {
+		key, err := LoadSigningKey(path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	var active *SigningKey
+	var previous []*SigningKey
+	for _, key := range keys {
+		if activeKID == "" && key.PrivateKey != nil {
+			// Keep the last private key seen as the active one
+			if active != nil {
+				previous = append(previous, active)
+			}
+			active = key
+			continue
+		}
+		if key.KID == activeKID {
+			active = key
+			continue
+		}
+		previous = append(previous, key)
+	}
+
+	if active == nil {
+		return nil, fmt.Errorf("active signing key %q not found in %s", activeKID, dir)
+	}
+	return NewKeyRing(active, previous...)
+}
+
+// LoadSigningKey loads a private or public key PEM file. The key ID is the file name without extension.
+func LoadSigningKey(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	kid := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	key, err := ParseSigningKey(kid, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load signing key %s: %w", path, err)
+	}
+	return key, nil
+}
+
+// ParseSigningKey parses an RSA or P-256 ECDSA key from PEM data
+func ParseSigningKey(kid string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type: %s", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{KID: kid}
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.PrivateKey = signer
+		key.PublicKey = signer.Public()
+	} else {
+		key.PublicKey = parsed
+	}
+
+	switch pub := key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		key.Algorithm = AlgorithmRS256
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return nil, errors.New("ECDSA keys must use the P-256 curve")
+		}
+		key.Algorithm = AlgorithmES256
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+
+	return key, nil
+}
+
+// Active returns the key used to sign new tokens
+func (r *KeyRing) Active() *SigningKey {
+	return r.active
+}
+
+// Key returns the key with the given ID
+func (r *KeyRing) Key(kid string) (*SigningKey, bool) {
+	key, ok := r.keys[kid]
+	return key, ok
+}
+
+// Keys returns every key of the ring ordered by key ID
+func (r *KeyRing) Keys() []*SigningKey {
+	keys := make([]*SigningKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].KID < keys[j].KID
+	})
+	return keys
+}
+
+// Algorithms returns the signing algorithms used by the ring
+func (r *KeyRing) Algorithms() []string {
+	seen := make(map[string]bool)
+	var algorithms []string
+	for _, key := range r.Keys() {
+		if !seen[key.Algorithm] {
+			seen[key.Algorithm] = true
+			algorithms = append(algorithms, key.Algorithm)
+		}
+	}
+	return algorithms
+}
+
+// GenerateSigningKey generates a new key pair for the given algorithm
+func GenerateSigningKey(kid, algorithm string) (*SigningKey, error) {
+	var signer crypto.Signer
+	var err error
+	switch algorithm {
+	case AlgorithmRS256:
+		signer, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgorithmES256:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &SigningKey{
+		KID:        kid,
+		Algorithm:  algorithm,
+		PrivateKey: signer,
+		PublicKey:  signer.Public(),
+	}, nil
+}
+
+// NewKeyID returns a key ID that sorts after the IDs of older keys
+func NewKeyID(algorithm string, now time.Time) string {
+	return fmt.Sprintf("%s-%s", now.UTC().Format("20060102T150405Z"), strings.ToLower(algorithm))
+}
+
+// GenerateKeyFile generates a new key and writes it to <dir>/<kid>.pem
+func GenerateKeyFile(dir, algorithm string) (*SigningKey, string, error) {
+	key, err := GenerateSigningKey(NewKeyID(algorithm, time.Now()), algorithm)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, "", err
+	}
+
+	path := filepath.Join(dir, key.KID+".pem")
+	if err := WritePrivateKeyFile(path, key); err != nil {
+		return nil, "", err
+	}
+	return key, path, nil
+}
+
+// WritePrivateKeyFile writes a private key as PKCS#8 PEM, readable by the owner only.
+// Existing files are never overwritten.
+func WritePrivateKeyFile(path string, key *SigningKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return pem.Encode(file, &pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryRevocationStore keeps revocations in process memory.
+// It is meant for development and single instance deployments only.
+type MemoryRevocationStore struct {
+	revoked    map[string]time.Time // Token ID to token expiry
+	validAfter map[int]time.Time    // User ID to revocation time
+	mutex      sync.RWMutex
+}
+
+// NewMemoryRevocationStore creates a new MemoryRevocationStore
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		revoked:    make(map[string]time.Time),
+		validAfter: make(map[int]time.Time),
+	}
+}
+
+// Revoke marks the token with the given ID as revoked until it expires
+func (s *MemoryRevocationStore) Revoke(ctx context.Context, jti string, userID int, expiresAt time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.revoked[jti] = expiresAt
+	return nil
+}
+
+// IsRevoked checks if the token with the given ID has been revoked
+func (s *MemoryRevocationStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	_, exists := s.revoked[jti]
+	return exists, nil
+}
+
+// RevokeAllForUser rejects every token issued to the user before the given time
+func (s *MemoryRevocationStore) RevokeAllForUser(ctx context.Context, userID int, at time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.validAfter[userID] = at.Truncate(time.Second)
+	return nil
+}
+
+// TokensValidAfter returns the time before which the user's tokens are rejected
+func (s *MemoryRevocationStore) TokensValidAfter(ctx context.Context, userID int) (time.Time, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.validAfter[userID], nil
+}
+
+// Prune removes revocations of tokens that have expired anyway
+func (s *MemoryRevocationStore) Prune(ctx context.Context) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	for jti, expiresAt := range s.revoked {
+		if expiresAt.Before(now) {
+			delete(s.revoked, jti)
+		}
+	}
+	return nil
+}
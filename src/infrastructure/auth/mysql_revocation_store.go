@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MySQLRevocationStore stores revocations in the database so they are shared between replicas
+type MySQLRevocationStore struct {
+	db *gorm.DB
+}
+
+// NewMySQLRevocationStore creates a new MySQLRevocationStore
+func NewMySQLRevocationStore(db *gorm.DB) *MySQLRevocationStore {
+	return &MySQLRevocationStore{
+		db: db,
+	}
+}
+
+// Revoke marks the token with the given ID as revoked until it expires
+func (s *MySQLRevocationStore) Revoke(ctx context.Context, jti string, userID int, expiresAt time.Time) error {
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}).Error
+}
+
+// IsRevoked checks if the token with the given ID has been revoked
+func (s *MySQLRevocationStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error
+	return count > 0, err
+}
+
+// RevokeAllForUser rejects every token issued to the user before the given time
+func (s *MySQLRevocationStore) RevokeAllForUser(ctx context.Context, userID int, at time.Time) error {
+	// Written without the User model so that a later Save of a stale user cannot reset it
+	return s.db.Table(models.User{}.TableName()).
+		Where("id = ?", userID).
+		Update("tokens_valid_after", at.Truncate(time.Second)).Error
+}
+
+// TokensValidAfter returns the time before which the user's tokens are rejected
+func (s *MySQLRevocationStore) TokensValidAfter(ctx context.Context, userID int) (time.Time, error) {
+	var row struct {
+		TokensValidAfter *time.Time
+	}
+	result := s.db.Table(models.User{}.TableName()).
+		Select("tokens_valid_after").
+		Where("id = ?", userID).
+		Take(&row)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, result.Error
+	}
+	if row.TokensValidAfter == nil {
+		return time.Time{}, nil
+	}
+	return *row.TokensValidAfter, nil
+}
+
+// Prune removes revocations of tokens that have expired anyway
+func (s *MySQLRevocationStore) Prune(ctx context.Context) error {
+	return s.db.Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{}).Error
+}
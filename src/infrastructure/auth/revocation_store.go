@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vnlab/makeshop-payment/src/infrastructure/config"
+	"gorm.io/gorm"
+)
+
+// RevocationStore keeps track of revoked tokens so that a logout is honoured by every replica.
+// Tokens are revoked individually by their "jti" claim, or all at once per user.
+type RevocationStore interface {
+	// Revoke marks the token with the given ID as revoked until it expires
+	Revoke(ctx context.Context, jti string, userID int, expiresAt time.Time) error
+
+	// IsRevoked checks if the token with the given ID has been revoked
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+
+	// RevokeAllForUser rejects every token issued to the user before the given time
+	RevokeAllForUser(ctx context.Context, userID int, at time.Time) error
+
+	// TokensValidAfter returns the time before which the user's tokens are rejected.
+	// The zero time is returned if the user's tokens were never revoked.
+	TokensValidAfter(ctx context.Context, userID int) (time.Time, error)
+
+	// Prune removes revocations of tokens that have expired anyway
+	Prune(ctx context.Context) error
+}
+
+// NewRevocationStore creates the revocation store selected by the configuration
+func NewRevocationStore(appConfig *config.Config, db *gorm.DB) (RevocationStore, error) {
+	switch appConfig.RevocationStore {
+	case "mysql":
+		return NewMySQLRevocationStore(db), nil
+	case "memory":
+		return NewMemoryRevocationStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown revocation store: %s", appConfig.RevocationStore)
+	}
+}
+
+// RunRevocationPruner prunes the store at the given interval until the context is cancelled
+func RunRevocationPruner(ctx context.Context, store RevocationStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := store.Prune(ctx); err != nil {
+				log.Printf("Failed to prune revoked tokens: %v", err)
+			}
+		}
+	}
+}
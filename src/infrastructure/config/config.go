@@ -12,14 +12,14 @@ type Config struct {
 	// Server configuration
 	ServerHost string
 	ServerPort string
-	
+
 	// Database configuration
 	DBHost     string
 	DBPort     string
 	DBUser     string
 	DBPassword string
 	DBName     string
-	
+
 	GinMode string // Gin mode for the server
 
 	// Logger configuration
@@ -29,39 +29,214 @@ type Config struct {
 	EnableSQLLog  bool   // Whether to log SQL queries
 
 	// Authentication configuration
-	JWTSecret   string
-	JWTDuration int
+	JWTPrivateKeyFile   string // Active signing key (PEM)
+	JWTPreviousKeyFiles string // Comma separated keys still accepted for verification
+	JWTKeyDirectory     string // Directory of <kid>.pem keys, used instead of the files above
+	JWTActiveKID        string // Active key in JWTKeyDirectory, defaults to the newest key
+	JWTAlgorithm        string // RS256 or ES256, used for generated keys
+	AccessTokenMinutes  int    // Lifetime of an access token in minutes
+	RefreshTokenDays    int    // Lifetime of a refresh token in days
+	RevocationStore     string // mysql or memory
+
+	// Impersonation configuration
+	ImpersonationMinutes int // Lifetime of a token issued to an administrator acting as a user
+
+	// OAuth configuration
+	OAuthTokenMinutes int // Lifetime of an access token issued to an OAuth client
+
+	// SSO configuration
+	OIDCEnabled      bool   // Allow signing in with the OpenID Connect provider
+	OIDCIssuerURL    string // Issuer identifier of the provider
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string // Front-end page receiving "code" and "state" from the provider
+	OIDCScopes       string // Space separated scopes requested
+	OIDCGroupsClaim  string // ID token claim listing the user's groups
+	OIDCRoleMapping  string // Comma separated group=ROLE_CODE pairs, the first group matching wins
+	OIDCStateTTL     int    // Minutes a sign-in started with the provider may take
+
+	// GraphQL configuration, zero disables a limit
+	GraphQLUserMaxDepth        int // Deepest nesting of fields for users logged in, whatever their role
+	GraphQLUserMaxComplexity   int // Cost budget of an operation for users logged in
+	GraphQLUserMaxAliases      int // Aliased fields per operation for users logged in
+	GraphQLUserMaxFields       int // Fields selected per operation for users logged in
+	GraphQLClientMaxDepth      int // Same limits for API keys, OAuth clients and anonymous requests
+	GraphQLClientMaxComplexity int
+	GraphQLClientMaxAliases    int
+	GraphQLClientMaxFields     int
+	GraphQLAPQCache            string // memory, or mysql to share the documents between instances
+	GraphQLAPQCacheSize        int    // Documents kept in memory by each instance
+	GraphQLAPQStoreSize        int    // Documents sent by clients kept in the database with mysql
+	GraphQLAPQStoreTTL         int    // Hours the database keeps a document no client has sent again
+	GraphQLAllowlistOnly       bool   // Only execute documents registered with register-queries, required in release mode
+
+	// Password reset configuration
+	PasswordResetTTL        int    // Minutes before a password reset link expires
+	PasswordResetURL        string // Front-end page receiving the token as "token" query parameter
+	PasswordResetMaxPerHour int    // Maximum reset emails sent to the same user per hour
+
+	// Email verification configuration
+	RequireEmailVerification    bool   // Refuse logins until the email address is verified
+	EmailVerificationTTL        int    // Hours before a verification link expires
+	EmailVerificationURL        string // Front-end page receiving the token as "token" query parameter
+	EmailVerificationMaxPerHour int    // Maximum verification emails sent to the same user per hour
+
+	// Login protection configuration
+	LoginMaxFailedAttempts int // Consecutive failed logins before an account is locked
+	LoginLockoutMinutes    int // First lockout duration, doubled for each further lockout
+	LoginMaxLockoutMinutes int // Upper bound of the lockout duration
+	LoginIPMaxFailures     int // Failed logins allowed from one IP address per window
+	LoginIPWindowMinutes   int // Length of the per-IP throttling window in minutes
+
+	// Data retention configuration
+	DeletedRetentionDays int // Days soft-deleted records are kept before purge-deleted removes them
+
+	// MFA configuration
+	MFAIssuer        string // Issuer name shown in authenticator apps
+	MFAEncryptionKey string // Key used to encrypt MFA secrets at rest
+	OTPCodeTTL       int    // Minutes before an emailed or texted one-time code expires
+	OTPMaxAttempts   int    // Wrong guesses allowed per one-time code
+	OTPResendSeconds int    // Minimum seconds between two codes sent to the same user
+	OTPMaxPerHour    int    // Maximum codes sent to the same user per hour and channel
+
+	// Passkey (WebAuthn) configuration
+	WebAuthnRPID         string // Domain passkeys are registered for, the front-end host or a parent domain
+	WebAuthnRPName       string // Name shown by the authenticator
+	WebAuthnOrigins      string // Comma separated front-end origins, e.g. https://app.example.com
+	WebAuthnChallengeTTL int    // Minutes a passkey registration or login may take
+
+	// Mail configuration
+	MailDriver    string // smtp, file or memory
+	MailFrom      string
+	MailFromName  string
+	MailDirectory string // Directory used by the file driver
+	MailLocale    string // Locale of the email templates
+	SMTPHost      string
+	SMTPPort      string
+	SMTPUsername  string
+	SMTPPassword  string
+
+	// SMS configuration
+	SMSDriver        string // twilio or fake
+	TwilioBaseURL    string
+	TwilioAccountSID string
+	TwilioAuthToken  string
+	TwilioFromNumber string
 }
 
 // LoadConfig loads the configuration from environment variables
 func LoadConfig() *Config {
 	// Load .env file if it exists
 	godotenv.Load()
-	
+
 	// Set default values
 	config := &Config{
-		ServerHost:    "0.0.0.0",
-		ServerPort:    "8080",
-		LogLevel:      "info",
-		LogDirectory:  "./logs",
-		EnableConsole: true,
-		EnableSQLLog:  false,
-		JWTDuration:   24, // Hours
+		ServerHost:                  "0.0.0.0",
+		ServerPort:                  "8080",
+		LogLevel:                    "info",
+		LogDirectory:                "./logs",
+		EnableConsole:               true,
+		EnableSQLLog:                false,
+		JWTAlgorithm:                "ES256",
+		AccessTokenMinutes:          15,
+		RefreshTokenDays:            30,
+		ImpersonationMinutes:        30,
+		OAuthTokenMinutes:           60,
+		OIDCScopes:                  "openid email profile",
+		OIDCGroupsClaim:             "groups",
+		OIDCStateTTL:                10, // Minutes
+		RevocationStore:             "mysql",
+		GraphQLUserMaxDepth:         12,
+		GraphQLUserMaxComplexity:    5000,
+		GraphQLUserMaxAliases:       30,
+		GraphQLUserMaxFields:        500,
+		GraphQLClientMaxDepth:       8,
+		GraphQLClientMaxComplexity:  2000,
+		GraphQLClientMaxAliases:     10,
+		GraphQLClientMaxFields:      200,
+		GraphQLAPQCache:             "memory",
+		GraphQLAPQCacheSize:         1000,
+		GraphQLAPQStoreSize:         10000,
+		GraphQLAPQStoreTTL:          24, // Hours
+		PasswordResetTTL:            60, // Minutes
+		PasswordResetURL:            "http://localhost:3000/password/reset",
+		PasswordResetMaxPerHour:     3,
+		EmailVerificationTTL:        24, // Hours
+		EmailVerificationURL:        "http://localhost:3000/email/verify",
+		EmailVerificationMaxPerHour: 3,
+		LoginMaxFailedAttempts:      5,
+		LoginLockoutMinutes:         15,
+		LoginMaxLockoutMinutes:      1440,
+		LoginIPMaxFailures:          20,
+		LoginIPWindowMinutes:        15,
+		DeletedRetentionDays:        90,
+		MFAIssuer:                   "Makeshop Payment",
+		OTPCodeTTL:                  10, // Minutes
+		OTPMaxAttempts:              5,
+		OTPResendSeconds:            60,
+		OTPMaxPerHour:               5,
+		WebAuthnRPID:                "localhost",
+		WebAuthnRPName:              "Makeshop Payment",
+		WebAuthnOrigins:             "http://localhost:3000",
+		WebAuthnChallengeTTL:        5, // Minutes
+		MailDriver:                  "file",
+		MailFrom:                    "no-reply@makeshop-payment.local",
+		MailFromName:                "Makeshop Payment",
+		MailDirectory:               "./tmp/mails",
+		MailLocale:                  "ja",
+		SMTPHost:                    "localhost",
+		SMTPPort:                    "1025",
+		SMSDriver:                   "fake",
+		TwilioBaseURL:               "https://api.twilio.com",
 	}
-	
+
 	// Map of environment variables to configuration fields
 	envVars := map[string]*string{
-		"SERVER_HOST":    &config.ServerHost,
-		"SERVER_PORT":    &config.ServerPort,
-		"GIN_MODE":       &config.GinMode,
-		"DB_HOST":        &config.DBHost,
-		"DB_PORT":        &config.DBPort,
-		"DB_USER":        &config.DBUser,
-		"DB_PASSWORD":    &config.DBPassword,
-		"DB_NAME":        &config.DBName,
-		"LOG_LEVEL":      &config.LogLevel,
-		"LOG_DIRECTORY":  &config.LogDirectory,
-		"JWT_SECRET":     &config.JWTSecret,
+		"SERVER_HOST":            &config.ServerHost,
+		"SERVER_PORT":            &config.ServerPort,
+		"GIN_MODE":               &config.GinMode,
+		"DB_HOST":                &config.DBHost,
+		"DB_PORT":                &config.DBPort,
+		"DB_USER":                &config.DBUser,
+		"DB_PASSWORD":            &config.DBPassword,
+		"DB_NAME":                &config.DBName,
+		"LOG_LEVEL":              &config.LogLevel,
+		"LOG_DIRECTORY":          &config.LogDirectory,
+		"JWT_PRIVATE_KEY_FILE":   &config.JWTPrivateKeyFile,
+		"JWT_PREVIOUS_KEY_FILES": &config.JWTPreviousKeyFiles,
+		"JWT_KEY_DIRECTORY":      &config.JWTKeyDirectory,
+		"JWT_ACTIVE_KID":         &config.JWTActiveKID,
+		"JWT_ALGORITHM":          &config.JWTAlgorithm,
+		"REVOCATION_STORE":       &config.RevocationStore,
+		"GRAPHQL_APQ_CACHE":      &config.GraphQLAPQCache,
+		"OIDC_ISSUER_URL":        &config.OIDCIssuerURL,
+		"OIDC_CLIENT_ID":         &config.OIDCClientID,
+		"OIDC_CLIENT_SECRET":     &config.OIDCClientSecret,
+		"OIDC_REDIRECT_URL":      &config.OIDCRedirectURL,
+		"OIDC_SCOPES":            &config.OIDCScopes,
+		"OIDC_GROUPS_CLAIM":      &config.OIDCGroupsClaim,
+		"OIDC_ROLE_MAPPING":      &config.OIDCRoleMapping,
+		"EMAIL_VERIFICATION_URL": &config.EmailVerificationURL,
+		"PASSWORD_RESET_URL":     &config.PasswordResetURL,
+		"MFA_ISSUER":             &config.MFAIssuer,
+		"MFA_ENCRYPTION_KEY":     &config.MFAEncryptionKey,
+		"WEBAUTHN_RP_ID":         &config.WebAuthnRPID,
+		"WEBAUTHN_RP_NAME":       &config.WebAuthnRPName,
+		"WEBAUTHN_ORIGINS":       &config.WebAuthnOrigins,
+		"MAIL_DRIVER":            &config.MailDriver,
+		"MAIL_FROM":              &config.MailFrom,
+		"MAIL_FROM_NAME":         &config.MailFromName,
+		"MAIL_DIRECTORY":         &config.MailDirectory,
+		"MAIL_LOCALE":            &config.MailLocale,
+		"SMTP_HOST":              &config.SMTPHost,
+		"SMTP_PORT":              &config.SMTPPort,
+		"SMTP_USERNAME":          &config.SMTPUsername,
+		"SMTP_PASSWORD":          &config.SMTPPassword,
+		"SMS_DRIVER":             &config.SMSDriver,
+		"TWILIO_BASE_URL":        &config.TwilioBaseURL,
+		"TWILIO_ACCOUNT_SID":     &config.TwilioAccountSID,
+		"TWILIO_AUTH_TOKEN":      &config.TwilioAuthToken,
+		"TWILIO_FROM_NUMBER":     &config.TwilioFromNumber,
 	}
 
 	// Override string fields with environment variables if they exist
@@ -73,8 +248,11 @@ func LoadConfig() *Config {
 
 	// Override boolean fields
 	boolVars := map[string]*bool{
-		"ENABLE_CONSOLE": &config.EnableConsole,
-		"ENABLE_SQL_LOG": &config.EnableSQLLog,
+		"ENABLE_CONSOLE":             &config.EnableConsole,
+		"ENABLE_SQL_LOG":             &config.EnableSQLLog,
+		"REQUIRE_EMAIL_VERIFICATION": &config.RequireEmailVerification,
+		"OIDC_ENABLED":               &config.OIDCEnabled,
+		"GRAPHQL_ALLOWLIST_ONLY":     &config.GraphQLAllowlistOnly,
 	}
 	for env, field := range boolVars {
 		if val := os.Getenv(env); val != "" {
@@ -86,9 +264,44 @@ func LoadConfig() *Config {
 	}
 
 	// Override integer fields
-	if val := os.Getenv("JWT_DURATION"); val != "" {
-		if duration, err := strconv.Atoi(val); err == nil {
-			config.JWTDuration = duration
+	intVars := map[string]*int{
+		"JWT_ACCESS_TOKEN_MINUTES":        &config.AccessTokenMinutes,
+		"JWT_REFRESH_TOKEN_DAYS":          &config.RefreshTokenDays,
+		"IMPERSONATION_TOKEN_MINUTES":     &config.ImpersonationMinutes,
+		"OAUTH_TOKEN_MINUTES":             &config.OAuthTokenMinutes,
+		"OIDC_STATE_TTL":                  &config.OIDCStateTTL,
+		"GRAPHQL_USER_MAX_DEPTH":          &config.GraphQLUserMaxDepth,
+		"GRAPHQL_USER_MAX_COMPLEXITY":     &config.GraphQLUserMaxComplexity,
+		"GRAPHQL_USER_MAX_ALIASES":        &config.GraphQLUserMaxAliases,
+		"GRAPHQL_USER_MAX_FIELDS":         &config.GraphQLUserMaxFields,
+		"GRAPHQL_CLIENT_MAX_DEPTH":        &config.GraphQLClientMaxDepth,
+		"GRAPHQL_CLIENT_MAX_COMPLEXITY":   &config.GraphQLClientMaxComplexity,
+		"GRAPHQL_CLIENT_MAX_ALIASES":      &config.GraphQLClientMaxAliases,
+		"GRAPHQL_CLIENT_MAX_FIELDS":       &config.GraphQLClientMaxFields,
+		"GRAPHQL_APQ_CACHE_SIZE":          &config.GraphQLAPQCacheSize,
+		"GRAPHQL_APQ_STORE_SIZE":          &config.GraphQLAPQStoreSize,
+		"GRAPHQL_APQ_STORE_TTL":           &config.GraphQLAPQStoreTTL,
+		"EMAIL_VERIFICATION_TTL":          &config.EmailVerificationTTL,
+		"EMAIL_VERIFICATION_MAX_PER_HOUR": &config.EmailVerificationMaxPerHour,
+		"PASSWORD_RESET_TTL":              &config.PasswordResetTTL,
+		"PASSWORD_RESET_MAX_PER_HOUR":     &config.PasswordResetMaxPerHour,
+		"LOGIN_MAX_FAILED_ATTEMPTS":       &config.LoginMaxFailedAttempts,
+		"LOGIN_LOCKOUT_MINUTES":           &config.LoginLockoutMinutes,
+		"LOGIN_MAX_LOCKOUT_MINUTES":       &config.LoginMaxLockoutMinutes,
+		"LOGIN_IP_MAX_FAILURES":           &config.LoginIPMaxFailures,
+		"LOGIN_IP_WINDOW_MINUTES":         &config.LoginIPWindowMinutes,
+		"DELETED_RETENTION_DAYS":          &config.DeletedRetentionDays,
+		"OTP_CODE_TTL":                    &config.OTPCodeTTL,
+		"OTP_MAX_ATTEMPTS":                &config.OTPMaxAttempts,
+		"OTP_RESEND_SECONDS":              &config.OTPResendSeconds,
+		"OTP_MAX_PER_HOUR":                &config.OTPMaxPerHour,
+		"WEBAUTHN_CHALLENGE_TTL":          &config.WebAuthnChallengeTTL,
+	}
+	for env, field := range intVars {
+		if val := os.Getenv(env); val != "" {
+			if parsedVal, err := strconv.Atoi(val); err == nil {
+				*field = parsedVal
+			}
 		}
 	}
 	return config
@@ -97,9 +310,9 @@ func LoadConfig() *Config {
 // GetLoggerConfig returns logger configuration
 func (c *Config) GetLoggerConfig() map[string]interface{} {
 	return map[string]interface{}{
-		"log_level":       c.LogLevel,
-		"log_directory":   c.LogDirectory,
-		"enable_console":  c.EnableConsole,
-		"enable_sql_log":  c.EnableSQLLog,
+		"log_level":      c.LogLevel,
+		"log_directory":  c.LogDirectory,
+		"enable_console": c.EnableConsole,
+		"enable_sql_log": c.EnableSQLLog,
 	}
 }
@@ -20,6 +20,7 @@ const (
 	CodeDatabaseError      = "DATABASE_ERROR"
 	CodeResourceNotFound   = "RESOURCE_NOT_FOUND"
 	CodeInvalidCredentials = "INVALID_CREDENTIALS"
+	CodeTooManyRequests    = "TOO_MANY_REQUESTS"
 )
 
 // New creates a new error with message
@@ -137,3 +138,15 @@ func InvalidCredentials(message string) *middleware.CustomError {
 		Code:    CodeInvalidCredentials,
 	}
 }
+
+// TooManyRequests creates a new rate limit error
+func TooManyRequests(message string) *middleware.CustomError {
+	if message == "" {
+		message = "Too many requests, please try again later"
+	}
+	return &middleware.CustomError{
+		Status:  http.StatusTooManyRequests,
+		Message: message,
+		Code:    CodeTooManyRequests,
+	}
+}
@@ -1,2 +1,104 @@
-// src/infrastructure/dataloader/loader.go
 package loader
+
+import (
+	"context"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7"
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+)
+
+const (
+	// defaultWait is how long a batch collects keys before it is dispatched
+	defaultWait = 2 * time.Millisecond
+
+	// defaultMaxBatch matches the largest page size of the list queries
+	defaultMaxBatch = 100
+)
+
+// Loaders holds the batch loaders of a single request
+type Loaders struct {
+	UserByID *dataloader.Loader[int, *models.User]
+	// UserByIDIncludingDeleted also loads deleted users, for records kept after their owner is deleted
+	UserByIDIncludingDeleted *dataloader.Loader[int, *models.User]
+	RoleByID                 *dataloader.Loader[int, *models.Role]
+	MFATypeByID              *dataloader.Loader[int, *models.MFAType]
+}
+
+// Factory creates the loaders of each request, so that cached values never outlive it
+type Factory struct {
+	userRepo    repositories.UserRepository
+	roleRepo    repositories.RoleRepository
+	mfaTypeRepo repositories.MFATypeRepository
+}
+
+// NewFactory creates a new Factory
+func NewFactory(userRepo repositories.UserRepository, roleRepo repositories.RoleRepository, mfaTypeRepo repositories.MFATypeRepository) *Factory {
+	return &Factory{
+		userRepo:    userRepo,
+		roleRepo:    roleRepo,
+		mfaTypeRepo: mfaTypeRepo,
+	}
+}
+
+// New creates an empty set of loaders
+func (f *Factory) New() *Loaders {
+	return &Loaders{
+		UserByID: newLoader(f.userRepo.FindByIDs, func(user *models.User) int {
+			return user.ID
+		}),
+		UserByIDIncludingDeleted: newLoader(f.userRepo.FindByIDsIncludingDeleted, func(user *models.User) int {
+			return user.ID
+		}),
+		RoleByID: newLoader(f.roleRepo.FindByIDs, func(role *models.Role) int {
+			return role.ID
+		}),
+		MFATypeByID: newLoader(f.mfaTypeRepo.FindByIDs, func(mfaType *models.MFAType) int {
+			return mfaType.ID
+		}),
+	}
+}
+
+// newLoader creates a loader of records by ID from a repository method finding several records
+// at once. IDs without a record resolve to nil.
+func newLoader[V any](findByIDs func(ctx context.Context, ids []int) ([]V, error), idOf func(V) int) *dataloader.Loader[int, V] {
+	batch := func(ctx context.Context, ids []int) []*dataloader.Result[V] {
+		results := make([]*dataloader.Result[V], len(ids))
+		records, err := findByIDs(ctx, ids)
+		if err != nil {
+			for i := range results {
+				results[i] = &dataloader.Result[V]{Error: err}
+			}
+			return results
+		}
+
+		byID := make(map[int]V, len(records))
+		for _, record := range records {
+			byID[idOf(record)] = record
+		}
+		for i, id := range ids {
+			results[i] = &dataloader.Result[V]{Data: byID[id]}
+		}
+		return results
+	}
+
+	return dataloader.NewBatchedLoader(batch,
+		dataloader.WithWait[int, V](defaultWait),
+		dataloader.WithBatchCapacity[int, V](defaultMaxBatch),
+	)
+}
+
+// loadersKey is the context key for Loaders
+type loadersKey struct{}
+
+// WithLoaders returns a context carrying the loaders of the request
+func WithLoaders(ctx context.Context, loaders *Loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, loaders)
+}
+
+// FromContext returns the loaders of the current request, or nil outside of a request
+func FromContext(ctx context.Context) *Loaders {
+	loaders, _ := ctx.Value(loadersKey{}).(*Loaders)
+	return loaders
+}
@@ -0,0 +1,33 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer writes every message as an .eml file into a directory.
+// It is intended for local development where no SMTP server is available.
+type FileMailer struct {
+	directory string
+	from      string
+}
+
+// NewFileMailer creates a new FileMailer, creating the directory if needed
+func NewFileMailer(directory, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	return &FileMailer{
+		directory: directory,
+		from:      from,
+	}, nil
+}
+
+// Send writes the message to a new file
+func (m *FileMailer) Send(ctx context.Context, msg *Message) error {
+	name := fmt.Sprintf("%s.eml", time.Now().Format("20060102T150405.000000000"))
+	return os.WriteFile(filepath.Join(m.directory, name), buildMIME(m.from, msg), 0644)
+}
@@ -0,0 +1,48 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vnlab/makeshop-payment/src/infrastructure/config"
+)
+
+// Mail drivers supported by NewMailer
+const (
+	DriverSMTP   = "smtp"
+	DriverFile   = "file"
+	DriverMemory = "memory"
+)
+
+// Message represents a plain text email
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email messages
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// NewMailer creates the Mailer selected by the MAIL_DRIVER configuration
+func NewMailer(appConfig *config.Config) (Mailer, error) {
+	switch appConfig.MailDriver {
+	case DriverSMTP:
+		return NewSMTPMailer(
+			appConfig.SMTPHost,
+			appConfig.SMTPPort,
+			appConfig.SMTPUsername,
+			appConfig.SMTPPassword,
+			appConfig.MailFrom,
+			appConfig.MailFromName,
+		), nil
+	case DriverFile:
+		return NewFileMailer(appConfig.MailDirectory, appConfig.MailFrom)
+	case DriverMemory:
+		return NewMemoryMailer(), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver: %s", appConfig.MailDriver)
+	}
+}
@@ -0,0 +1,49 @@
+package mail
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryMailer keeps sent messages in memory so they can be inspected by tests
+type MemoryMailer struct {
+	mutex    sync.RWMutex
+	messages []*Message
+}
+
+// NewMemoryMailer creates a new MemoryMailer
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+// Send records the message
+func (m *MemoryMailer) Send(ctx context.Context, msg *Message) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages returns all recorded messages
+func (m *MemoryMailer) Messages() []*Message {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return append([]*Message(nil), m.messages...)
+}
+
+// Last returns the most recently recorded message, or nil if none were sent
+func (m *MemoryMailer) Last() *Message {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if len(m.messages) == 0 {
+		return nil
+	}
+	return m.messages[len(m.messages)-1]
+}
+
+// Reset clears all recorded messages
+func (m *MemoryMailer) Reset() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.messages = nil
+}
@@ -0,0 +1,80 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPMailer sends messages through an SMTP server.
+// Authentication is skipped when no username is configured, which allows
+// local stand-ins such as Mailpit or MailHog to be used in development.
+type SMTPMailer struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     mail.Address
+}
+
+// NewSMTPMailer creates a new SMTPMailer
+func NewSMTPMailer(host, port, username, password, from, fromName string) *SMTPMailer {
+	return &SMTPMailer{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     mail.Address{Name: fromName, Address: from},
+	}
+}
+
+// Send sends the message
+func (m *SMTPMailer) Send(ctx context.Context, msg *Message) error {
+	if len(msg.To) == 0 {
+		return errors.New("message has no recipients")
+	}
+
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	addr := net.JoinHostPort(m.host, m.port)
+	return smtp.SendMail(addr, auth, m.from.Address, msg.To, buildMIME(m.from.String(), msg))
+}
+
+// buildMIME renders a UTF-8 encoded plain text message
+func buildMIME(from string, msg *Message) []byte {
+	var buf bytes.Buffer
+
+	headers := []string{
+		"From: " + from,
+		"To: " + strings.Join(msg.To, ", "),
+		"Subject: " + mime.BEncoding.Encode("UTF-8", msg.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"Content-Transfer-Encoding: base64",
+	}
+	for _, header := range headers {
+		fmt.Fprintf(&buf, "%s\r\n", header)
+	}
+	buf.WriteString("\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(msg.Body))
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+
+	return buf.Bytes()
+}
@@ -0,0 +1,82 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// DefaultLocale is used when no template exists for the configured locale
+const DefaultLocale = "ja"
+
+// Template names
+const (
+	TemplateMFACode                 = "mfa_code"
+	TemplatePasswordReset           = "password_reset"
+	TemplatePasswordChanged         = "password_changed"
+	TemplateEmailVerification       = "email_verification"
+	TemplateEmailChangeConfirmation = "email_change_confirmation"
+	TemplateEmailChangeNotice       = "email_change_notice"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// TemplateRenderer renders email subjects and bodies from embedded templates.
+// Each template file defines a "subject" and a "body" block.
+type TemplateRenderer struct {
+	locale string
+}
+
+// NewTemplateRenderer creates a new TemplateRenderer for the given locale
+func NewTemplateRenderer(locale string) *TemplateRenderer {
+	if locale == "" {
+		locale = DefaultLocale
+	}
+	return &TemplateRenderer{locale: locale}
+}
+
+// Render renders the named template and returns the subject and body
+func (r *TemplateRenderer) Render(name string, data interface{}) (string, string, error) {
+	tmpl, err := r.load(name)
+	if err != nil {
+		return "", "", err
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return "", "", err
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return "", "", err
+	}
+
+	return strings.TrimSpace(subject.String()), strings.TrimSpace(body.String()) + "\n", nil
+}
+
+// Message renders the named template into a message for the given recipient
+func (r *TemplateRenderer) Message(to string, name string, data interface{}) (*Message, error) {
+	subject, body, err := r.Render(name, data)
+	if err != nil {
+		return nil, err
+	}
+	return &Message{
+		To:      []string{to},
+		Subject: subject,
+		Body:    body,
+	}, nil
+}
+
+// load parses the template for the configured locale, falling back to the default locale
+func (r *TemplateRenderer) load(name string) (*template.Template, error) {
+	for _, locale := range []string{r.locale, DefaultLocale} {
+		path := fmt.Sprintf("templates/%s/%s.tmpl", locale, name)
+		if _, err := templateFS.Open(path); err != nil {
+			continue
+		}
+		return template.ParseFS(templateFS, path)
+	}
+	return nil, fmt.Errorf("mail template not found: %s", name)
+}
@@ -0,0 +1,115 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// idTokenLeeway tolerates clock skew between this server and the provider
+const idTokenLeeway = time.Minute
+
+// idTokenAlgorithms are the signature algorithms accepted for ID tokens
+var idTokenAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// IDToken holds the verified claims of an ID token that identify the user
+type IDToken struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+	Name          string
+	Groups        []string
+}
+
+// VerifyIDToken checks the signature of an ID token against the provider keys, its issuer,
+// audience, expiry and nonce (OpenID Connect Core 3.1.3.7), and returns its claims
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*IDToken, error) {
+	doc, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, kid)
+	},
+		jwt.WithValidMethods(idTokenAlgorithms),
+		jwt.WithIssuer(doc.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(idTokenLeeway),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+
+	// A token issued to several audiences must name this client as the authorized party
+	audience, _ := claims.GetAudience()
+	if azp, ok := claims["azp"].(string); (ok || len(audience) > 1) && azp != p.config.ClientID {
+		return nil, errors.New("invalid ID token: authorized party mismatch")
+	}
+	if tokenNonce, _ := claims["nonce"].(string); nonce == "" || tokenNonce != nonce {
+		return nil, errors.New("invalid ID token: nonce mismatch")
+	}
+
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, errors.New("invalid ID token: missing subject")
+	}
+
+	return &IDToken{
+		Issuer:        doc.Issuer,
+		Subject:       subject,
+		Email:         stringClaim(claims, "email"),
+		EmailVerified: boolClaim(claims, "email_verified"),
+		GivenName:     stringClaim(claims, "given_name"),
+		FamilyName:    stringClaim(claims, "family_name"),
+		Name:          stringClaim(claims, "name"),
+		Groups:        listClaim(claims, p.config.GroupsClaim),
+	}, nil
+}
+
+// stringClaim returns a string claim, or "" when it is missing
+func stringClaim(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return strings.TrimSpace(value)
+}
+
+// boolClaim returns a boolean claim. Some providers send booleans as strings.
+func boolClaim(claims jwt.MapClaims, name string) bool {
+	switch value := claims[name].(type) {
+	case bool:
+		return value
+	case string:
+		return value == "true"
+	default:
+		return false
+	}
+}
+
+// listClaim returns a claim holding a list of strings, or a single string
+func listClaim(claims jwt.MapClaims, name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				items = append(items, s)
+			}
+		}
+		return items
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,171 @@
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const (
+	testClientID     = "makeshop-payment"
+	testClientSecret = "secret"
+	testRedirectURL  = "http://localhost:3000/sso/callback"
+)
+
+// newTestProvider starts a MockServer signing the configured user in and returns a
+// Provider registered with it
+func newTestProvider(t *testing.T, config MockConfig) *Provider {
+	t.Helper()
+
+	// The issuer is the URL of the server, known only once it has started
+	var mock *MockServer
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mock.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	config.Issuer = server.URL
+	config.ClientID = testClientID
+	config.ClientSecret = testClientSecret
+	var err error
+	if mock, err = NewMockServer(config); err != nil {
+		t.Fatalf("NewMockServer: %v", err)
+	}
+
+	return NewProvider(Config{
+		IssuerURL:    server.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+		Scopes:       []string{"email", "profile"},
+	})
+}
+
+// signIn follows the authorization code flow with the given nonce and returns the raw ID token
+func signIn(t *testing.T, provider *Provider, nonce string) string {
+	t.Helper()
+	ctx := context.Background()
+
+	verifier, challenge, err := NewPKCE()
+	if err != nil {
+		t.Fatalf("NewPKCE: %v", err)
+	}
+	authURL, err := provider.AuthCodeURL(ctx, "state", nonce, challenge)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: status %d, location %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if state := location.Query().Get("state"); state != "state" {
+		t.Fatalf("authorize returned state %q", state)
+	}
+
+	rawIDToken, err := provider.Exchange(ctx, location.Query().Get("code"), verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	return rawIDToken
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	provider := newTestProvider(t, MockConfig{
+		Email:      "jane@example.com",
+		GivenName:  "Jane",
+		FamilyName: "Doe",
+		Groups:     []string{"payments-admins", "staff"},
+	})
+
+	idToken, err := provider.VerifyIDToken(context.Background(), signIn(t, provider, "nonce-1"), "nonce-1")
+	if err != nil {
+		t.Fatalf("VerifyIDToken: %v", err)
+	}
+	if idToken.Subject != "mock|jane@example.com" || idToken.Email != "jane@example.com" || !idToken.EmailVerified {
+		t.Errorf("unexpected identity %+v", idToken)
+	}
+	if idToken.Name != "Jane Doe" || idToken.GivenName != "Jane" || idToken.FamilyName != "Doe" {
+		t.Errorf("unexpected names %+v", idToken)
+	}
+	if len(idToken.Groups) != 2 || idToken.Groups[0] != "payments-admins" || idToken.Groups[1] != "staff" {
+		t.Errorf("unexpected groups %v", idToken.Groups)
+	}
+}
+
+func TestVerifyIDTokenRefusesMismatchedNonce(t *testing.T) {
+	provider := newTestProvider(t, MockConfig{Email: "jane@example.com"})
+	rawIDToken := signIn(t, provider, "nonce-1")
+
+	for _, nonce := range []string{"nonce-2", ""} {
+		if _, err := provider.VerifyIDToken(context.Background(), rawIDToken, nonce); err == nil ||
+			!strings.Contains(err.Error(), "nonce mismatch") {
+			t.Errorf("nonce %q: expected a nonce mismatch, got %v", nonce, err)
+		}
+	}
+}
+
+func TestVerifyIDTokenRefusesOtherAudiences(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		want   string
+	}{
+		{
+			name:   "other audience",
+			claims: map[string]interface{}{"aud": "other-client"},
+			want:   "invalid ID token",
+		},
+		{
+			name:   "other authorized party",
+			claims: map[string]interface{}{"azp": "other-client"},
+			want:   "authorized party mismatch",
+		},
+		{
+			name:   "several audiences without an authorized party",
+			claims: map[string]interface{}{"aud": []string{testClientID, "other-client"}},
+			want:   "authorized party mismatch",
+		},
+		{
+			name:   "several audiences authorizing another client",
+			claims: map[string]interface{}{"aud": []string{testClientID, "other-client"}, "azp": "other-client"},
+			want:   "authorized party mismatch",
+		},
+		{
+			name:   "other issuer",
+			claims: map[string]interface{}{"iss": "https://attacker.example.com"},
+			want:   "invalid ID token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newTestProvider(t, MockConfig{Email: "jane@example.com", ExtraClaims: tt.claims})
+			_, err := provider.VerifyIDToken(context.Background(), signIn(t, provider, "nonce-1"), "nonce-1")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenAcceptsAuthorizedPartyAmongSeveralAudiences(t *testing.T) {
+	provider := newTestProvider(t, MockConfig{
+		Email:       "jane@example.com",
+		ExtraClaims: map[string]interface{}{"aud": []string{testClientID, "other-client"}, "azp": testClientID},
+	})
+	if _, err := provider.VerifyIDToken(context.Background(), signIn(t, provider, "nonce-1"), "nonce-1"); err != nil {
+		t.Errorf("VerifyIDToken: %v", err)
+	}
+}
@@ -0,0 +1,227 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/auth"
+)
+
+// mockCodeTTL is the lifetime of an authorization code issued by MockServer
+const mockCodeTTL = time.Minute
+
+// MockConfig describes the client accepted by MockServer and the user it signs in
+type MockConfig struct {
+	Issuer       string // Base URL the server is reached at, e.g. http://localhost:9090
+	ClientID     string
+	ClientSecret string
+	Email        string
+	GivenName    string
+	FamilyName   string
+	Groups       []string
+	GroupsClaim  string
+	ExtraClaims  map[string]interface{} // Added to, or replacing, the claims of every ID token
+}
+
+// mockCode is an authorization code waiting to be redeemed
+type mockCode struct {
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+	expiresAt     time.Time
+}
+
+// MockServer is a minimal OpenID Connect provider for local development and tests.
+// It signs the configured user in without asking for credentials.
+type MockServer struct {
+	config  MockConfig
+	keyRing *auth.KeyRing
+	mux     *http.ServeMux
+
+	mutex sync.Mutex
+	codes map[string]mockCode
+}
+
+// NewMockServer creates a MockServer signing ID tokens with a new ES256 key
+func NewMockServer(config MockConfig) (*MockServer, error) {
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+	config.Issuer = strings.TrimSuffix(config.Issuer, "/")
+
+	key, err := auth.GenerateSigningKey(auth.NewKeyID("ES256", time.Now()), "ES256")
+	if err != nil {
+		return nil, err
+	}
+	keyRing, err := auth.NewKeyRing(key)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &MockServer{
+		config:  config,
+		keyRing: keyRing,
+		mux:     http.NewServeMux(),
+		codes:   make(map[string]mockCode),
+	}
+	s.mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	s.mux.HandleFunc("/authorize", s.authorize)
+	s.mux.HandleFunc("/token", s.token)
+	s.mux.HandleFunc("/jwks", s.jwks)
+	return s, nil
+}
+
+// ServeHTTP implements http.Handler
+func (s *MockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// discovery publishes the provider metadata
+func (s *MockServer) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.config.Issuer,
+		"authorization_endpoint":                s.config.Issuer + "/authorize",
+		"token_endpoint":                        s.config.Issuer + "/token",
+		"jwks_uri":                              s.config.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"ES256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+// authorize signs the configured user in and sends the browser back with a code
+func (s *MockServer) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI := query.Get("redirect_uri")
+	if query.Get("client_id") != s.config.ClientID || redirectURI == "" {
+		http.Error(w, "unknown client or missing redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "only the code flow with S256 PKCE is supported", http.StatusBadRequest)
+		return
+	}
+
+	code, err := auth.GenerateRandomToken(32)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.mutex.Lock()
+	s.codes[code] = mockCode{
+		redirectURI:   redirectURI,
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+		expiresAt:     time.Now().Add(mockCodeTTL),
+	}
+	s.mutex.Unlock()
+
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	params := target.Query()
+	params.Set("code", code)
+	params.Set("state", query.Get("state"))
+	target.RawQuery = params.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// token redeems an authorization code for an ID token
+func (s *MockServer) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	clientID, secret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID, secret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+	}
+	if clientID != s.config.ClientID || subtle.ConstantTimeCompare([]byte(secret), []byte(s.config.ClientSecret)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.PostFormValue("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	s.mutex.Lock()
+	code, found := s.codes[r.PostFormValue("code")]
+	delete(s.codes, r.PostFormValue("code"))
+	s.mutex.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !found || time.Now().After(code.expiresAt) || code.redirectURI != r.PostFormValue("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != code.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                s.config.Issuer,
+		"sub":                "mock|" + s.config.Email,
+		"aud":                s.config.ClientID,
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"iat":                now.Unix(),
+		"nonce":              code.nonce,
+		"email":              s.config.Email,
+		"email_verified":     true,
+		"given_name":         s.config.GivenName,
+		"family_name":        s.config.FamilyName,
+		"name":               strings.TrimSpace(s.config.GivenName + " " + s.config.FamilyName),
+		s.config.GroupsClaim: s.config.Groups,
+	}
+	for name, value := range s.config.ExtraClaims {
+		claims[name] = value
+	}
+	key := s.keyRing.Active()
+	token := jwt.NewWithClaims(key.Method(), claims)
+	token.Header["kid"] = key.KID
+	idToken, err := token.SignedString(key.PrivateKey)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	accessToken, err := auth.GenerateRandomToken(32)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+// jwks publishes the key ID tokens are signed with
+func (s *MockServer) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.keyRing.JWKS())
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,264 @@
+// Package oidc signs users in with an external OpenID Connect provider using the
+// authorization code flow with PKCE
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vnlab/makeshop-payment/src/infrastructure/auth"
+)
+
+const (
+	// httpTimeout bounds every request made to the provider
+	httpTimeout = 10 * time.Second
+	// discoveryTTL is how long the provider metadata is cached
+	discoveryTTL = time.Hour
+	// keyRefreshInterval limits how often the provider keys are fetched again for an unknown "kid"
+	keyRefreshInterval = time.Minute
+	// maxResponseSize bounds the responses read from the provider
+	maxResponseSize = 1 << 20
+)
+
+// Config holds the settings of the provider and of this application's registration with it
+type Config struct {
+	IssuerURL    string   // Issuer identifier, the discovery document is read below it
+	ClientID     string   // Client registered with the provider
+	ClientSecret string   // Secret of the client, sent with HTTP Basic authentication
+	RedirectURL  string   // Front-end page the provider sends the authorization code to
+	Scopes       []string // Scopes requested, "openid" is always included
+	GroupsClaim  string   // ID token claim listing the user's groups
+}
+
+// discoveryDocument is the part of the provider metadata used here (OpenID Connect Discovery 1.0)
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// tokenResponse is the response of the token endpoint
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Provider is an OpenID Connect provider. The metadata and keys are fetched on first use
+// so the API starts even when the provider is unreachable.
+type Provider struct {
+	config     Config
+	httpClient *http.Client
+
+	mu            sync.Mutex
+	discovery     *discoveryDocument
+	discoveredAt  time.Time
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+}
+
+// NewProvider creates a new Provider
+func NewProvider(config Config) *Provider {
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+	return &Provider{
+		config:     config,
+		httpClient: &http.Client{Timeout: httpTimeout},
+	}
+}
+
+// NewPKCE returns a code verifier and its S256 code challenge (RFC 7636)
+func NewPKCE() (verifier, challenge string, err error) {
+	verifier, err = auth.GenerateRandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// AuthCodeURL returns the authorization endpoint URL the user is sent to
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	doc, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(p.scopes(), " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(doc.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return doc.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange redeems an authorization code at the token endpoint and returns the raw ID token
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier string) (string, error) {
+	doc, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"code_verifier": {codeVerifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid token response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request refused: %s %s", body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", errors.New("token response has no ID token")
+	}
+	return body.IDToken, nil
+}
+
+// scopes returns the configured scopes with "openid" first
+func (p *Provider) scopes() []string {
+	scopes := []string{"openid"}
+	for _, scope := range p.config.Scopes {
+		if scope != "" && scope != "openid" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// metadata returns the discovery document, fetching it when it is missing or stale
+func (p *Provider) metadata(ctx context.Context) (*discoveryDocument, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil && time.Since(p.discoveredAt) < discoveryTTL {
+		return p.discovery, nil
+	}
+
+	issuer := strings.TrimSuffix(p.config.IssuerURL, "/")
+	var doc discoveryDocument
+	if err := p.getJSON(ctx, issuer+"/.well-known/openid-configuration", &doc); err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	// The issuer must be the one configured, or its tokens could be substituted (OpenID Connect Discovery 4.3)
+	if strings.TrimSuffix(doc.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("OIDC discovery returned issuer %q, expected %q", doc.Issuer, p.config.IssuerURL)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, errors.New("OIDC discovery document is incomplete")
+	}
+
+	p.discovery = &doc
+	p.discoveredAt = time.Now()
+	return p.discovery, nil
+}
+
+// key returns the provider key with the given ID, fetching the keys again when it is unknown
+func (p *Provider) key(ctx context.Context, kid string) (interface{}, error) {
+	doc, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if p.keys != nil && time.Since(p.keysFetchedAt) < keyRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key: %q", kid)
+	}
+
+	var set auth.JWKS
+	if err := p.getJSON(ctx, doc.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch OIDC signing keys: %w", err)
+	}
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// Keys of unsupported types are skipped rather than failing the whole set
+		if key, err := jwk.PublicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key: %q", kid)
+}
+
+// lookupKey finds a cached key. Tokens without a "kid" are accepted when the provider has a single key.
+func (p *Provider) lookupKey(kid string) (interface{}, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// getJSON fetches a JSON document from the provider
+func (p *Provider) getJSON(ctx context.Context, endpoint string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, endpoint)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(v)
+}
@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"gorm.io/gorm"
+)
+
+// APIKeyRepositoryImpl implements the APIKeyRepository interface
+type APIKeyRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyRepository creates a new APIKeyRepository
+func NewAPIKeyRepository(db *gorm.DB) repositories.APIKeyRepository {
+	return &APIKeyRepositoryImpl{
+		db: db,
+	}
+}
+
+// Create stores a new API key
+func (r *APIKeyRepositoryImpl) Create(ctx context.Context, apiKey *models.APIKey) error {
+	return r.db.Create(apiKey).Error
+}
+
+// FindByID finds an API key by ID
+func (r *APIKeyRepositoryImpl) FindByID(ctx context.Context, id int) (*models.APIKey, error) {
+	var apiKey models.APIKey
+	result := r.db.First(&apiKey, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil if API key not found
+		}
+		return nil, result.Error
+	}
+	return &apiKey, nil
+}
+
+// FindByPrefix finds an API key by the public prefix of the key
+func (r *APIKeyRepositoryImpl) FindByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	var apiKey models.APIKey
+	result := r.db.Where("prefix = ?", prefix).First(&apiKey)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil if API key not found
+		}
+		return nil, result.Error
+	}
+	return &apiKey, nil
+}
+
+// ListByUser lists the API keys of a user, newest first
+func (r *APIKeyRepositoryImpl) ListByUser(ctx context.Context, userID int) ([]*models.APIKey, error) {
+	var apiKeys []*models.APIKey
+	err := r.db.Where("user_id = ?", userID).Order("id DESC").Find(&apiKeys).Error
+	return apiKeys, err
+}
+
+// Touch records a use of an API key
+func (r *APIKeyRepositoryImpl) Touch(ctx context.Context, id int, ipAddress string) error {
+	return r.db.Model(&models.APIKey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_used_at": time.Now(),
+		"last_used_ip": ipAddress,
+	}).Error
+}
+
+// Revoke revokes an API key
+func (r *APIKeyRepositoryImpl) Revoke(ctx context.Context, id int) (bool, error) {
+	result := r.db.Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeByUser revokes every API key of a user and returns how many were revoked
+func (r *APIKeyRepositoryImpl) RevokeByUser(ctx context.Context, userID int) (int64, error) {
+	result := r.db.Model(&models.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,26 @@
+package repositories
+
+import (
+	"context"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"gorm.io/gorm"
+)
+
+// AuditLogRepositoryImpl implements the AuditLogRepository interface
+type AuditLogRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewAuditLogRepository creates a new AuditLogRepository
+func NewAuditLogRepository(db *gorm.DB) repositories.AuditLogRepository {
+	return &AuditLogRepositoryImpl{
+		db: db,
+	}
+}
+
+// Create records a new audit log entry
+func (r *AuditLogRepositoryImpl) Create(ctx context.Context, auditLog *models.AuditLog) error {
+	return r.db.Create(auditLog).Error
+}
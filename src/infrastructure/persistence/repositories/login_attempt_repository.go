@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"context"
+	"math"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"gorm.io/gorm"
+)
+
+// LoginAttemptRepositoryImpl implements the LoginAttemptRepository interface
+type LoginAttemptRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewLoginAttemptRepository creates a new LoginAttemptRepository
+func NewLoginAttemptRepository(db *gorm.DB) repositories.LoginAttemptRepository {
+	return &LoginAttemptRepositoryImpl{
+		db: db,
+	}
+}
+
+// Create records a login attempt
+func (r *LoginAttemptRepositoryImpl) Create(ctx context.Context, attempt *models.LoginAttempt) error {
+	return r.db.Create(attempt).Error
+}
+
+// CountFailedByIPSince counts failed attempts from an IP address since the given time
+func (r *LoginAttemptRepositoryImpl) CountFailedByIPSince(ctx context.Context, ipAddress string, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.LoginAttempt{}).
+		Where("ip_address = ? AND success = ? AND created_at >= ?", ipAddress, false, since).
+		Count(&count).Error
+	return count, err
+}
+
+// List lists attempts matching the filter, newest first, with pagination
+func (r *LoginAttemptRepositoryImpl) List(ctx context.Context, filter models.LoginAttemptFilter, page, pageSize int) ([]*models.LoginAttempt, int, error) {
+	var attempts []*models.LoginAttempt
+	var count int64
+
+	query := r.db.Model(&models.LoginAttempt{})
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.Email != "" {
+		query = query.Where("email = ?", filter.Email)
+	}
+	if filter.IPAddress != "" {
+		query = query.Where("ip_address = ?", filter.IPAddress)
+	}
+	if filter.Success != nil {
+		query = query.Where("success = ?", *filter.Success)
+	}
+	if filter.Since != nil {
+		query = query.Where("created_at >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		query = query.Where("created_at < ?", *filter.Until)
+	}
+
+	// Count total records
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Apply pagination
+	offset := (page - 1) * pageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&attempts).Error; err != nil {
+		return nil, 0, err
+	}
+
+	totalPages := int(math.Ceil(float64(count) / float64(pageSize)))
+	return attempts, totalPages, nil
+}
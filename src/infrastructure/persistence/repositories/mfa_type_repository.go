@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"gorm.io/gorm"
+)
+
+// MFATypeRepositoryImpl implements the MFATypeRepository interface
+type MFATypeRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewMFATypeRepository creates a new MFATypeRepository
+func NewMFATypeRepository(db *gorm.DB) repositories.MFATypeRepository {
+	return &MFATypeRepositoryImpl{
+		db: db,
+	}
+}
+
+// FindByID finds an MFA type by ID
+func (r *MFATypeRepositoryImpl) FindByID(ctx context.Context, id int) (*models.MFAType, error) {
+	var mfaType models.MFAType
+	result := r.db.First(&mfaType, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil if MFA type not found
+		}
+		return nil, result.Error
+	}
+	return &mfaType, nil
+}
+
+// FindByIDs finds the MFA types with the given IDs
+func (r *MFATypeRepositoryImpl) FindByIDs(ctx context.Context, ids []int) ([]*models.MFAType, error) {
+	var mfaTypes []*models.MFAType
+	if len(ids) == 0 {
+		return mfaTypes, nil
+	}
+	err := r.db.Where("id IN ?", ids).Find(&mfaTypes).Error
+	return mfaTypes, err
+}
+
+// FindByNo finds an MFA type by its number
+func (r *MFATypeRepositoryImpl) FindByNo(ctx context.Context, no int) (*models.MFAType, error) {
+	var mfaType models.MFAType
+	result := r.db.Where("no = ?", no).First(&mfaType)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil if MFA type not found
+		}
+		return nil, result.Error
+	}
+	return &mfaType, nil
+}
+
+// List lists all MFA types ordered by number
+func (r *MFATypeRepositoryImpl) List(ctx context.Context) ([]*models.MFAType, error) {
+	var mfaTypes []*models.MFAType
+	if err := r.db.Order("no").Find(&mfaTypes).Error; err != nil {
+		return nil, err
+	}
+	return mfaTypes, nil
+}
+
+// PurgeDeleted permanently removes MFA types deleted before the given time
+func (r *MFATypeRepositoryImpl) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.Unscoped().Where("deleted_at < ?", before).Delete(&models.MFAType{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"gorm.io/gorm"
+)
+
+// OAuthClientRepositoryImpl implements the OAuthClientRepository interface
+type OAuthClientRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewOAuthClientRepository creates a new OAuthClientRepository
+func NewOAuthClientRepository(db *gorm.DB) repositories.OAuthClientRepository {
+	return &OAuthClientRepositoryImpl{
+		db: db,
+	}
+}
+
+// Create stores a new OAuth client
+func (r *OAuthClientRepositoryImpl) Create(ctx context.Context, client *models.OAuthClient) error {
+	return r.db.Create(client).Error
+}
+
+// FindByID finds an OAuth client by ID
+func (r *OAuthClientRepositoryImpl) FindByID(ctx context.Context, id int) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	result := r.db.Preload("User").First(&client, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil if client not found
+		}
+		return nil, result.Error
+	}
+	return &client, nil
+}
+
+// FindByClientID finds an OAuth client by its public client identifier
+func (r *OAuthClientRepositoryImpl) FindByClientID(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	result := r.db.Where("client_id = ?", clientID).First(&client)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil if client not found
+		}
+		return nil, result.Error
+	}
+	return &client, nil
+}
+
+// List lists every OAuth client, newest first
+func (r *OAuthClientRepositoryImpl) List(ctx context.Context) ([]*models.OAuthClient, error) {
+	var clients []*models.OAuthClient
+	err := r.db.Order("id DESC").Find(&clients).Error
+	return clients, err
+}
+
+// UpdateSecret replaces the secret hash of an OAuth client
+func (r *OAuthClientRepositoryImpl) UpdateSecret(ctx context.Context, id int, secretHash string) error {
+	return r.db.Model(&models.OAuthClient{}).Where("id = ?", id).Update("secret_hash", secretHash).Error
+}
+
+// Revoke revokes an OAuth client
+func (r *OAuthClientRepositoryImpl) Revoke(ctx context.Context, id int) (bool, error) {
+	result := r.db.Model(&models.OAuthClient{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeByUser revokes every OAuth client of a user and returns how many were revoked
+func (r *OAuthClientRepositoryImpl) RevokeByUser(ctx context.Context, userID int) (int64, error) {
+	result := r.db.Model(&models.OAuthClient{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"gorm.io/gorm"
+)
+
+// OTPCodeRepositoryImpl implements the OTPCodeRepository interface
+type OTPCodeRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewOTPCodeRepository creates a new OTPCodeRepository
+func NewOTPCodeRepository(db *gorm.DB) repositories.OTPCodeRepository {
+	return &OTPCodeRepositoryImpl{
+		db: db,
+	}
+}
+
+// Create stores a new code and invalidates older active codes for the same user, channel and purpose
+func (r *OTPCodeRepositoryImpl) Create(ctx context.Context, code *models.OTPCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.OTPCode{}).
+			Where("user_id = ? AND channel = ? AND purpose = ? AND consumed_at IS NULL", code.UserID, code.Channel, code.Purpose).
+			Update("consumed_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(code).Error
+	})
+}
+
+// FindLatestActive finds the newest unconsumed code for a user, channel and purpose
+func (r *OTPCodeRepositoryImpl) FindLatestActive(ctx context.Context, userID int, channel, purpose string) (*models.OTPCode, error) {
+	var code models.OTPCode
+	result := r.db.Where("user_id = ? AND channel = ? AND purpose = ? AND consumed_at IS NULL", userID, channel, purpose).
+		Order("id DESC").
+		First(&code)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil if no active code exists
+		}
+		return nil, result.Error
+	}
+	return &code, nil
+}
+
+// IncrementAttempts records a failed verification attempt
+func (r *OTPCodeRepositoryImpl) IncrementAttempts(ctx context.Context, id int) error {
+	return r.db.Model(&models.OTPCode{}).
+		Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+}
+
+// CountCreatedSince counts codes sent to a user on a channel since the given time
+func (r *OTPCodeRepositoryImpl) CountCreatedSince(ctx context.Context, userID int, channel string, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.OTPCode{}).
+		Where("user_id = ? AND channel = ? AND created_at >= ?", userID, channel, since).
+		Count(&count).Error
+	return count, err
+}
+
+// MarkConsumed marks a code as used
+func (r *OTPCodeRepositoryImpl) MarkConsumed(ctx context.Context, id int) (bool, error) {
+	result := r.db.Model(&models.OTPCode{}).
+		Where("id = ? AND consumed_at IS NULL", id).
+		Update("consumed_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"gorm.io/gorm"
+)
+
+// PasskeyChallengeRepositoryImpl implements the PasskeyChallengeRepository interface
+type PasskeyChallengeRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewPasskeyChallengeRepository creates a new PasskeyChallengeRepository
+func NewPasskeyChallengeRepository(db *gorm.DB) repositories.PasskeyChallengeRepository {
+	return &PasskeyChallengeRepositoryImpl{
+		db: db,
+	}
+}
+
+// Create stores a new challenge and deletes expired ones
+func (r *PasskeyChallengeRepositoryImpl) Create(ctx context.Context, challenge *models.PasskeyChallenge) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at < ?", time.Now()).Delete(&models.PasskeyChallenge{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(challenge).Error
+	})
+}
+
+// FindByHash finds a challenge by the hash of its value
+func (r *PasskeyChallengeRepositoryImpl) FindByHash(ctx context.Context, challengeHash string) (*models.PasskeyChallenge, error) {
+	var challenge models.PasskeyChallenge
+	result := r.db.Where("challenge_hash = ?", challengeHash).First(&challenge)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil if challenge not found
+		}
+		return nil, result.Error
+	}
+	return &challenge, nil
+}
+
+// MarkUsed marks a challenge as used
+func (r *PasskeyChallengeRepositoryImpl) MarkUsed(ctx context.Context, id int) (bool, error) {
+	result := r.db.Model(&models.PasskeyChallenge{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
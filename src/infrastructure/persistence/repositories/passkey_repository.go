@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"gorm.io/gorm"
+)
+
+// PasskeyRepositoryImpl implements the PasskeyRepository interface
+type PasskeyRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewPasskeyRepository creates a new PasskeyRepository
+func NewPasskeyRepository(db *gorm.DB) repositories.PasskeyRepository {
+	return &PasskeyRepositoryImpl{
+		db: db,
+	}
+}
+
+// Create stores a new passkey
+func (r *PasskeyRepositoryImpl) Create(ctx context.Context, passkey *models.Passkey) error {
+	return r.db.Create(passkey).Error
+}
+
+// FindByID finds a passkey by ID
+func (r *PasskeyRepositoryImpl) FindByID(ctx context.Context, id int) (*models.Passkey, error) {
+	var passkey models.Passkey
+	result := r.db.First(&passkey, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil if passkey not found
+		}
+		return nil, result.Error
+	}
+	return &passkey, nil
+}
+
+// FindByCredentialID finds a passkey by the credential ID the authenticator gave it
+func (r *PasskeyRepositoryImpl) FindByCredentialID(ctx context.Context, credentialID []byte) (*models.Passkey, error) {
+	var passkey models.Passkey
+	result := r.db.Where("credential_id = ?", credentialID).First(&passkey)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil if passkey not found
+		}
+		return nil, result.Error
+	}
+	return &passkey, nil
+}
+
+// ListByUser lists the passkeys of a user, oldest first
+func (r *PasskeyRepositoryImpl) ListByUser(ctx context.Context, userID int) ([]*models.Passkey, error) {
+	var passkeys []*models.Passkey
+	err := r.db.Where("user_id = ?", userID).Order("id ASC").Find(&passkeys).Error
+	return passkeys, err
+}
+
+// UpdateSignCount records a use of a passkey if its signature counter is still the given one
+func (r *PasskeyRepositoryImpl) UpdateSignCount(ctx context.Context, id int, previousCount, signCount uint32) (bool, error) {
+	result := r.db.Model(&models.Passkey{}).
+		Where("id = ? AND sign_count = ?", id, previousCount).
+		Updates(map[string]interface{}{
+			"sign_count":   signCount,
+			"last_used_at": time.Now(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Delete removes a passkey
+func (r *PasskeyRepositoryImpl) Delete(ctx context.Context, id int) error {
+	return r.db.Delete(&models.Passkey{}, id).Error
+}
+
+// DeleteByUserID removes every passkey of a user
+func (r *PasskeyRepositoryImpl) DeleteByUserID(ctx context.Context, userID int) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.Passkey{}).Error
+}
@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"gorm.io/gorm"
+)
+
+// PasswordResetTokenRepositoryImpl implements the PasswordResetTokenRepository interface
+type PasswordResetTokenRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewPasswordResetTokenRepository creates a new PasswordResetTokenRepository
+func NewPasswordResetTokenRepository(db *gorm.DB) repositories.PasswordResetTokenRepository {
+	return &PasswordResetTokenRepositoryImpl{
+		db: db,
+	}
+}
+
+// Create stores a new token and invalidates older unused tokens of the same user
+func (r *PasswordResetTokenRepositoryImpl) Create(ctx context.Context, token *models.PasswordResetToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", token.UserID).
+			Update("used_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+// FindByHash finds a token by the hash of its value
+func (r *PasswordResetTokenRepositoryImpl) FindByHash(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	result := r.db.Where("token_hash = ?", tokenHash).First(&token)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil if token not found
+		}
+		return nil, result.Error
+	}
+	return &token, nil
+}
+
+// CountCreatedSince counts tokens requested for a user since the given time
+func (r *PasswordResetTokenRepositoryImpl) CountCreatedSince(ctx context.Context, userID int, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Count(&count).Error
+	return count, err
+}
+
+// MarkUsed marks a token as used
+func (r *PasswordResetTokenRepositoryImpl) MarkUsed(ctx context.Context, id int) (bool, error) {
+	result := r.db.Model(&models.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PermissionRepositoryImpl implements the PermissionRepository interface
+type PermissionRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewPermissionRepository creates a new PermissionRepository
+func NewPermissionRepository(db *gorm.DB) repositories.PermissionRepository {
+	return &PermissionRepositoryImpl{
+		db: db,
+	}
+}
+
+// List lists every permission ordered by code
+func (r *PermissionRepositoryImpl) List(ctx context.Context) ([]*models.Permission, error) {
+	var permissions []*models.Permission
+	err := r.db.Order("code").Find(&permissions).Error
+	return permissions, err
+}
+
+// FindByCode finds a permission by code
+func (r *PermissionRepositoryImpl) FindByCode(ctx context.Context, code string) (*models.Permission, error) {
+	var permission models.Permission
+	result := r.db.Where("code = ?", code).First(&permission)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil if permission not found
+		}
+		return nil, result.Error
+	}
+	return &permission, nil
+}
+
+// ListByRole lists the permissions granted to a role ordered by code
+func (r *PermissionRepositoryImpl) ListByRole(ctx context.Context, roleID int) ([]*models.Permission, error) {
+	var permissions []*models.Permission
+	err := r.db.
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Where("role_permissions.role_id = ?", roleID).
+		Order("permissions.code").
+		Find(&permissions).Error
+	return permissions, err
+}
+
+// Grant grants a permission to a role
+func (r *PermissionRepositoryImpl) Grant(ctx context.Context, roleID, permissionID int) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.RolePermission{
+		RoleID:       roleID,
+		PermissionID: permissionID,
+	}).Error
+}
+
+// Revoke removes a permission from a role
+func (r *PermissionRepositoryImpl) Revoke(ctx context.Context, roleID, permissionID int) error {
+	return r.db.Where("role_id = ? AND permission_id = ?", roleID, permissionID).
+		Delete(&models.RolePermission{}).Error
+}
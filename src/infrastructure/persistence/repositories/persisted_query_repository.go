@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PersistedQueryRepositoryImpl implements the PersistedQueryRepository interface
+type PersistedQueryRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewPersistedQueryRepository creates a new PersistedQueryRepository
+func NewPersistedQueryRepository(db *gorm.DB) repositories.PersistedQueryRepository {
+	return &PersistedQueryRepositoryImpl{
+		db: db,
+	}
+}
+
+// FindByHash finds a persisted query by the hash of its text
+func (r *PersistedQueryRepositoryImpl) FindByHash(ctx context.Context, hash string) (*models.PersistedQuery, error) {
+	var query models.PersistedQuery
+	result := r.db.Where("hash = ?", hash).First(&query)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil if query not found
+		}
+		return nil, result.Error
+	}
+	return &query, nil
+}
+
+// Save stores a query sent by a client. A known hash keeps its row, which is marked as
+// sent again so that it does not expire.
+func (r *PersistedQueryRepositoryImpl) Save(ctx context.Context, query *models.PersistedQuery) error {
+	return r.db.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"updated_at"}),
+	}).Create(query).Error
+}
+
+// PruneUnregistered removes the queries sent by clients that were last sent before the
+// given time, then the oldest of them beyond the given number. Queries sent in the same
+// second as the last one kept may be removed with it.
+func (r *PersistedQueryRepositoryImpl) PruneUnregistered(ctx context.Context, before time.Time, keep int) (int64, error) {
+	result := r.db.Where("registered = ? AND updated_at < ?", false, before).Delete(&models.PersistedQuery{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	pruned := result.RowsAffected
+
+	var cutoff models.PersistedQuery
+	err := r.db.Select("updated_at").
+		Where("registered = ?", false).
+		Order("updated_at DESC").
+		Offset(keep).
+		Take(&cutoff).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return pruned, nil // No more queries than allowed
+		}
+		return pruned, err
+	}
+
+	result = r.db.Where("registered = ? AND updated_at <= ?", false, cutoff.UpdatedAt).Delete(&models.PersistedQuery{})
+	return pruned + result.RowsAffected, result.Error
+}
+
+// Register adds a query to the allowlist, creating or updating its row
+func (r *PersistedQueryRepositoryImpl) Register(ctx context.Context, query *models.PersistedQuery) error {
+	query.Registered = true
+	return r.db.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"name", "registered", "updated_at"}),
+	}).Create(query).Error
+}
+
+// UnregisterExcept removes every query from the allowlist except the given ones
+func (r *PersistedQueryRepositoryImpl) UnregisterExcept(ctx context.Context, hashes []string) (int64, error) {
+	db := r.db.Model(&models.PersistedQuery{}).Where("registered = ?", true)
+	if len(hashes) > 0 {
+		db = db.Where("hash NOT IN ?", hashes)
+	}
+	result := db.Update("registered", false)
+	return result.RowsAffected, result.Error
+}
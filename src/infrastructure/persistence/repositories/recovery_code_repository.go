@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"gorm.io/gorm"
+)
+
+// RecoveryCodeRepositoryImpl implements the RecoveryCodeRepository interface
+type RecoveryCodeRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewRecoveryCodeRepository creates a new RecoveryCodeRepository
+func NewRecoveryCodeRepository(db *gorm.DB) repositories.RecoveryCodeRepository {
+	return &RecoveryCodeRepositoryImpl{
+		db: db,
+	}
+}
+
+// ReplaceForUser deletes all existing codes of a user and stores the new ones
+func (r *RecoveryCodeRepositoryImpl) ReplaceForUser(ctx context.Context, userID int, codes []*models.RecoveryCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// FindUnusedByHash finds an unused recovery code of a user by its hash
+func (r *RecoveryCodeRepositoryImpl) FindUnusedByHash(ctx context.Context, userID int, codeHash string) (*models.RecoveryCode, error) {
+	var code models.RecoveryCode
+	result := r.db.Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).First(&code)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil if code not found
+		}
+		return nil, result.Error
+	}
+	return &code, nil
+}
+
+// MarkUsed marks a recovery code as used
+func (r *RecoveryCodeRepositoryImpl) MarkUsed(ctx context.Context, id int) (bool, error) {
+	result := r.db.Model(&models.RecoveryCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// DeleteByUserID removes all recovery codes of a user
+func (r *RecoveryCodeRepositoryImpl) DeleteByUserID(ctx context.Context, userID int) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+}
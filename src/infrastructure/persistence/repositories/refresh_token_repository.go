@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"gorm.io/gorm"
+)
+
+// RefreshTokenRepositoryImpl implements the RefreshTokenRepository interface
+type RefreshTokenRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewRefreshTokenRepository creates a new RefreshTokenRepository
+func NewRefreshTokenRepository(db *gorm.DB) repositories.RefreshTokenRepository {
+	return &RefreshTokenRepositoryImpl{
+		db: db,
+	}
+}
+
+// Create stores a new refresh token
+func (r *RefreshTokenRepositoryImpl) Create(ctx context.Context, token *models.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+// FindByHash finds a refresh token by the hash of its value
+func (r *RefreshTokenRepositoryImpl) FindByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	result := r.db.Where("token_hash = ?", tokenHash).First(&token)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil if token not found
+		}
+		return nil, result.Error
+	}
+	return &token, nil
+}
+
+// MarkRotated marks a token as exchanged
+func (r *RefreshTokenRepositoryImpl) MarkRotated(ctx context.Context, id int) (bool, error) {
+	result := r.db.Model(&models.RefreshToken{}).
+		Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", id).
+		Update("rotated_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeFamily revokes every token descending from the same login
+func (r *RefreshTokenRepositoryImpl) RevokeFamily(ctx context.Context, familyID string) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeAllForUser revokes every refresh token of a user
+func (r *RefreshTokenRepositoryImpl) RevokeAllForUser(ctx context.Context, userID int) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"errors"
+	"time"
 
 	models "github.com/vnlab/makeshop-payment/src/domain/models"
 	"github.com/vnlab/makeshop-payment/src/domain/repositories"
@@ -34,6 +35,16 @@ func (r *RoleRepositoryImpl) FindByID(ctx context.Context, id int) (*models.Role
 	return &role, nil
 }
 
+// FindByIDs finds the roles with the given IDs
+func (r *RoleRepositoryImpl) FindByIDs(ctx context.Context, ids []int) ([]*models.Role, error) {
+	var roles []*models.Role
+	if len(ids) == 0 {
+		return roles, nil
+	}
+	err := r.db.Where("id IN ?", ids).Find(&roles).Error
+	return roles, err
+}
+
 // FindByCode finds a role by code
 func (r *RoleRepositoryImpl) FindByCode(ctx context.Context, code string) (*models.Role, error) {
 	var role models.Role
@@ -46,3 +57,20 @@ func (r *RoleRepositoryImpl) FindByCode(ctx context.Context, code string) (*mode
 	}
 	return &role, nil
 }
+
+// List lists every role ordered by ID
+func (r *RoleRepositoryImpl) List(ctx context.Context) ([]*models.Role, error) {
+	var roles []*models.Role
+	err := r.db.Order("id").Find(&roles).Error
+	return roles, err
+}
+
+// PurgeDeleted permanently removes roles deleted before the given time
+// that no user, including deleted users, still refers to
+func (r *RoleRepositoryImpl) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.Unscoped().
+		Where("deleted_at < ?", before).
+		Where("NOT EXISTS (SELECT 1 FROM users WHERE users.role_id = roles.id)").
+		Delete(&models.Role{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"gorm.io/gorm"
+)
+
+// SessionRepositoryImpl implements the SessionRepository interface
+type SessionRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository creates a new SessionRepository
+func NewSessionRepository(db *gorm.DB) repositories.SessionRepository {
+	return &SessionRepositoryImpl{
+		db: db,
+	}
+}
+
+// Create stores a new session
+func (r *SessionRepositoryImpl) Create(ctx context.Context, session *models.Session) error {
+	return r.db.Create(session).Error
+}
+
+// FindByID finds a session by ID
+func (r *SessionRepositoryImpl) FindByID(ctx context.Context, id string) (*models.Session, error) {
+	var session models.Session
+	result := r.db.Where("id = ?", id).First(&session)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil if session not found
+		}
+		return nil, result.Error
+	}
+	return &session, nil
+}
+
+// ListActiveByUser lists the active sessions of a user, most recently used first
+func (r *SessionRepositoryImpl) ListActiveByUser(ctx context.Context, userID int) ([]*models.Session, error) {
+	var sessions []*models.Session
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// Touch records activity on a session
+func (r *SessionRepositoryImpl) Touch(ctx context.Context, id string, ipAddress string, expiresAt *time.Time) error {
+	updates := map[string]interface{}{
+		"last_seen_at": time.Now(),
+	}
+	if ipAddress != "" {
+		updates["ip_address"] = ipAddress
+	}
+	if expiresAt != nil {
+		updates["expires_at"] = *expiresAt
+	}
+	return r.db.Model(&models.Session{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// Revoke revokes a session
+func (r *SessionRepositoryImpl) Revoke(ctx context.Context, id string) (bool, error) {
+	result := r.db.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeAllForUser revokes every session of a user except the given one
+func (r *SessionRepositoryImpl) RevokeAllForUser(ctx context.Context, userID int, exceptID string) ([]string, error) {
+	var ids []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL", userID)
+		if exceptID != "" {
+			query = query.Where("id <> ?", exceptID)
+		}
+		if err := query.Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		return tx.Model(&models.Session{}).
+			Where("id IN ?", ids).
+			Update("revoked_at", time.Now()).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"gorm.io/gorm"
+)
+
+// SSOStateRepositoryImpl implements the SSOStateRepository interface
+type SSOStateRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewSSOStateRepository creates a new SSOStateRepository
+func NewSSOStateRepository(db *gorm.DB) repositories.SSOStateRepository {
+	return &SSOStateRepositoryImpl{
+		db: db,
+	}
+}
+
+// Create stores a new state and deletes expired ones
+func (r *SSOStateRepositoryImpl) Create(ctx context.Context, state *models.SSOState) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at < ?", time.Now()).Delete(&models.SSOState{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(state).Error
+	})
+}
+
+// FindByHash finds a state by the hash of its value
+func (r *SSOStateRepositoryImpl) FindByHash(ctx context.Context, stateHash string) (*models.SSOState, error) {
+	var state models.SSOState
+	result := r.db.Where("state_hash = ?", stateHash).First(&state)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil if state not found
+		}
+		return nil, result.Error
+	}
+	return &state, nil
+}
+
+// MarkUsed marks a state as used
+func (r *SSOStateRepositoryImpl) MarkUsed(ctx context.Context, id int) (bool, error) {
+	result := r.db.Model(&models.SSOState{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
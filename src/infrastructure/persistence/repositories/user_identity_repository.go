@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"gorm.io/gorm"
+)
+
+// UserIdentityRepositoryImpl implements the UserIdentityRepository interface
+type UserIdentityRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewUserIdentityRepository creates a new UserIdentityRepository
+func NewUserIdentityRepository(db *gorm.DB) repositories.UserIdentityRepository {
+	return &UserIdentityRepositoryImpl{
+		db: db,
+	}
+}
+
+// Create links an external identity to a user
+func (r *UserIdentityRepositoryImpl) Create(ctx context.Context, identity *models.UserIdentity) error {
+	return r.db.Create(identity).Error
+}
+
+// FindByIssuerAndSubject finds the identity of a provider account
+func (r *UserIdentityRepositoryImpl) FindByIssuerAndSubject(ctx context.Context, issuer, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	result := r.db.Where("issuer = ? AND subject = ?", issuer, subject).First(&identity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil if identity not found
+		}
+		return nil, result.Error
+	}
+	return &identity, nil
+}
+
+// TouchLastLogin records that the identity was used to sign in
+func (r *UserIdentityRepositoryImpl) TouchLastLogin(ctx context.Context, id int) error {
+	return r.db.Model(&models.UserIdentity{}).
+		Where("id = ?", id).
+		Update("last_login_at", time.Now()).Error
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"math"
+	"time"
 
 	models "github.com/vnlab/makeshop-payment/src/domain/models"
 	"github.com/vnlab/makeshop-payment/src/domain/repositories"
@@ -22,10 +23,40 @@ func NewUserRepository(db *gorm.DB) repositories.UserRepository {
 	}
 }
 
-// FindByID finds a user by ID
+// FindByID finds a user by ID, ignoring deleted users
 func (r *UserRepositoryImpl) FindByID(ctx context.Context, id int) (*models.User, error) {
+	return r.findByID(r.db, id)
+}
+
+// FindByIDIncludingDeleted finds a user by ID, deleted or not
+func (r *UserRepositoryImpl) FindByIDIncludingDeleted(ctx context.Context, id int) (*models.User, error) {
+	return r.findByID(r.db.Unscoped(), id)
+}
+
+// FindByIDs finds the users with the given IDs, ignoring deleted users
+func (r *UserRepositoryImpl) FindByIDs(ctx context.Context, ids []int) ([]*models.User, error) {
+	var users []*models.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := r.db.Where("id IN ?", ids).Find(&users).Error
+	return users, err
+}
+
+// FindByIDsIncludingDeleted finds the users with the given IDs, deleted or not
+func (r *UserRepositoryImpl) FindByIDsIncludingDeleted(ctx context.Context, ids []int) ([]*models.User, error) {
+	var users []*models.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := r.db.Unscoped().Where("id IN ?", ids).Find(&users).Error
+	return users, err
+}
+
+// findByID finds a user by ID within the given scope
+func (r *UserRepositoryImpl) findByID(db *gorm.DB, id int) (*models.User, error) {
 	var user models.User
-	result := r.db.Preload("Role").First(&user, id)
+	result := db.Preload("Role").Preload("MFAType").First(&user, id)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, nil // Return nil if user not found
@@ -35,10 +66,20 @@ func (r *UserRepositoryImpl) FindByID(ctx context.Context, id int) (*models.User
 	return &user, nil
 }
 
-// FindByEmail finds a user by email
+// FindByEmail finds a user by email, ignoring deleted users
 func (r *UserRepositoryImpl) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findByEmail(r.db, email)
+}
+
+// FindByEmailIncludingDeleted finds a user by email, deleted or not
+func (r *UserRepositoryImpl) FindByEmailIncludingDeleted(ctx context.Context, email string) (*models.User, error) {
+	return r.findByEmail(r.db.Unscoped(), email)
+}
+
+// findByEmail finds a user by email within the given scope
+func (r *UserRepositoryImpl) findByEmail(db *gorm.DB, email string) (*models.User, error) {
 	var user models.User
-	result := r.db.Preload("Role").Where("email = ?", email).First(&user)
+	result := db.Preload("Role").Preload("MFAType").Where("email = ?", email).First(&user)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, nil // Return nil if user not found
@@ -63,6 +104,66 @@ func (r *UserRepositoryImpl) Delete(ctx context.Context, id int) error {
 	return r.db.Delete(&models.User{}, id).Error
 }
 
+// SetDisabled disables an account at the given time, or enables it when at is nil
+func (r *UserRepositoryImpl) SetDisabled(ctx context.Context, id int, at *time.Time) error {
+	return r.db.Table(models.User{}.TableName()).
+		Where("id = ?", id).
+		UpdateColumn("disabled_at", at).Error
+}
+
+// Restore restores a deleted user
+func (r *UserRepositoryImpl) Restore(ctx context.Context, id int) error {
+	return r.db.Unscoped().Model(&models.User{}).Where("id = ?", id).UpdateColumn("deleted_at", nil).Error
+}
+
+// CountActiveByRole counts the users of a role that are neither disabled nor deleted
+func (r *UserRepositoryImpl) CountActiveByRole(ctx context.Context, roleID int) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).
+		Where("role_id = ? AND disabled_at IS NULL", roleID).
+		Count(&count).Error
+	return count, err
+}
+
+// IncrementFailedLogins records a failed login and returns the number of consecutive failures
+func (r *UserRepositoryImpl) IncrementFailedLogins(ctx context.Context, id int) (int, error) {
+	// Lockout fields are read-only on the model, so they are written by table name
+	err := r.db.Table(models.User{}.TableName()).
+		Where("id = ?", id).
+		UpdateColumn("failed_login_attempts", gorm.Expr("failed_login_attempts + 1")).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var user models.User
+	if err := r.db.Select("failed_login_attempts").Where("id = ?", id).Take(&user).Error; err != nil {
+		return 0, err
+	}
+	return user.FailedLoginAttempts, nil
+}
+
+// Lock locks an account until the given time and resets the failure counter
+func (r *UserRepositoryImpl) Lock(ctx context.Context, id int, until time.Time) error {
+	return r.db.Table(models.User{}.TableName()).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"locked_until":          until,
+			"lockout_count":         gorm.Expr("lockout_count + 1"),
+			"failed_login_attempts": 0,
+		}).Error
+}
+
+// ResetLoginFailures clears the failure counter, the lockout history and any lock
+func (r *UserRepositoryImpl) ResetLoginFailures(ctx context.Context, id int) error {
+	return r.db.Table(models.User{}.TableName()).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"locked_until":          nil,
+			"lockout_count":         0,
+			"failed_login_attempts": 0,
+		}).Error
+}
+
 // List lists all users with pagination
 func (r *UserRepositoryImpl) List(ctx context.Context, page, pageSize int) ([]*models.User, int, error) {
 	var users []*models.User
@@ -75,10 +176,41 @@ func (r *UserRepositoryImpl) List(ctx context.Context, page, pageSize int) ([]*m
 
 	// Apply pagination
 	offset := (page - 1) * pageSize
-	if err := r.db.Preload("Role").Offset(offset).Limit(pageSize).Find(&users).Error; err != nil {
+	if err := r.db.Offset(offset).Limit(pageSize).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+
+	totalPages := int(math.Ceil(float64(count) / float64(pageSize)))
+	return users, totalPages, nil
+}
+
+// ListDeleted lists deleted users with pagination, most recently deleted first
+func (r *UserRepositoryImpl) ListDeleted(ctx context.Context, page, pageSize int) ([]*models.User, int, error) {
+	var users []*models.User
+	var count int64
+
+	if err := r.db.Unscoped().Model(&models.User{}).Where("deleted_at IS NOT NULL").Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := r.db.Unscoped().
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&users).Error
+	if err != nil {
 		return nil, 0, err
 	}
 
 	totalPages := int(math.Ceil(float64(count) / float64(pageSize)))
 	return users, totalPages, nil
 }
+
+// PurgeDeleted permanently removes users deleted before the given time.
+// Their tokens, sessions and MFA secrets are removed by cascading foreign keys.
+func (r *UserRepositoryImpl) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.Unscoped().Where("deleted_at < ?", before).Delete(&models.User{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"gorm.io/gorm"
+)
+
+// UserTOTPRepositoryImpl implements the UserTOTPRepository interface
+type UserTOTPRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewUserTOTPRepository creates a new UserTOTPRepository
+func NewUserTOTPRepository(db *gorm.DB) repositories.UserTOTPRepository {
+	return &UserTOTPRepositoryImpl{
+		db: db,
+	}
+}
+
+// FindByUserID finds the TOTP secret of a user
+func (r *UserTOTPRepositoryImpl) FindByUserID(ctx context.Context, userID int) (*models.UserTOTP, error) {
+	var totp models.UserTOTP
+	result := r.db.Where("user_id = ?", userID).First(&totp)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil if no secret is enrolled
+		}
+		return nil, result.Error
+	}
+	return &totp, nil
+}
+
+// Save creates or updates a TOTP secret
+func (r *UserTOTPRepositoryImpl) Save(ctx context.Context, totp *models.UserTOTP) error {
+	return r.db.Save(totp).Error
+}
+
+// UpdateLastUsedStep records the last accepted time step if it is newer than the stored one
+func (r *UserTOTPRepositoryImpl) UpdateLastUsedStep(ctx context.Context, id int, step int64) (bool, error) {
+	result := r.db.Model(&models.UserTOTP{}).
+		Where("id = ? AND last_used_step < ?", id, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// DeleteByUserID removes the TOTP secret of a user
+func (r *UserTOTPRepositoryImpl) DeleteByUserID(ctx context.Context, userID int) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.UserTOTP{}).Error
+}
@@ -0,0 +1,23 @@
+package pubsub
+
+import (
+	"context"
+)
+
+// Message is a payload published on a topic
+type Message struct {
+	Topic   string
+	Payload []byte
+}
+
+// Broker delivers the messages published on a topic to its current subscribers.
+// Payloads are opaque bytes so that a broker shared between instances can carry them as is.
+// Delivery is best effort: subscribers only receive messages published while they listen.
+type Broker interface {
+	// Publish sends a payload to the current subscribers of the topic
+	Publish(ctx context.Context, topic string, payload []byte) error
+
+	// Subscribe receives the messages of the topic until the context is cancelled,
+	// then closes the returned channel
+	Subscribe(ctx context.Context, topic string) (<-chan Message, error)
+}
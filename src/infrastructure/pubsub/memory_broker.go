@@ -0,0 +1,66 @@
+package pubsub
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// subscriberBuffer is the number of messages a subscriber may lag behind before messages are dropped
+const subscriberBuffer = 16
+
+// MemoryBroker delivers messages to the subscribers of the same instance only
+type MemoryBroker struct {
+	mu     sync.RWMutex
+	topics map[string]map[chan Message]struct{}
+}
+
+// NewMemoryBroker creates a new MemoryBroker
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		topics: make(map[string]map[chan Message]struct{}),
+	}
+}
+
+// Publish sends a payload to the current subscribers of the topic.
+// A subscriber too slow to keep up misses the message rather than blocking the publisher.
+func (b *MemoryBroker) Publish(ctx context.Context, topic string, payload []byte) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.topics[topic] {
+		select {
+		case ch <- Message{Topic: topic, Payload: payload}:
+		default:
+			log.Printf("Dropped message on %s for a slow subscriber", topic)
+		}
+	}
+	return nil
+}
+
+// Subscribe receives the messages of the topic until the context is cancelled
+func (b *MemoryBroker) Subscribe(ctx context.Context, topic string) (<-chan Message, error) {
+	ch := make(chan Message, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.topics[topic] == nil {
+		b.topics[topic] = make(map[chan Message]struct{})
+	}
+	b.topics[topic][ch] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		b.mu.Lock()
+		delete(b.topics[topic], ch)
+		if len(b.topics[topic]) == 0 {
+			delete(b.topics, topic)
+		}
+		b.mu.Unlock()
+
+		close(ch)
+	}()
+
+	return ch, nil
+}
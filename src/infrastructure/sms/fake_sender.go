@@ -0,0 +1,52 @@
+package sms
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// SentMessage is a message recorded by FakeSender
+type SentMessage struct {
+	To   string
+	Body string
+}
+
+// FakeSender logs messages instead of sending them and keeps them in memory
+// so they can be inspected in local development and tests
+type FakeSender struct {
+	mutex    sync.RWMutex
+	messages []SentMessage
+}
+
+// NewFakeSender creates a new FakeSender
+func NewFakeSender() *FakeSender {
+	return &FakeSender{}
+}
+
+// Send records the message
+func (s *FakeSender) Send(ctx context.Context, to string, body string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.messages = append(s.messages, SentMessage{To: to, Body: body})
+	log.Printf("[fake SMS] to=%s body=%q", to, body)
+	return nil
+}
+
+// Messages returns all recorded messages
+func (s *FakeSender) Messages() []SentMessage {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return append([]SentMessage(nil), s.messages...)
+}
+
+// Last returns the most recently recorded message, or nil if none were sent
+func (s *FakeSender) Last() *SentMessage {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if len(s.messages) == 0 {
+		return nil
+	}
+	msg := s.messages[len(s.messages)-1]
+	return &msg
+}
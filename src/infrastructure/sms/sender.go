@@ -0,0 +1,41 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vnlab/makeshop-payment/src/infrastructure/config"
+)
+
+// SMS drivers supported by NewSender
+const (
+	DriverTwilio = "twilio"
+	DriverFake   = "fake"
+)
+
+// SMSSender delivers text messages to E.164 formatted phone numbers
+type SMSSender interface {
+	Send(ctx context.Context, to string, body string) error
+}
+
+// NewSender creates the SMSSender selected by the SMS_DRIVER configuration
+func NewSender(appConfig *config.Config) (SMSSender, error) {
+	switch appConfig.SMSDriver {
+	case DriverTwilio:
+		return NewTwilioSender(
+			appConfig.TwilioBaseURL,
+			appConfig.TwilioAccountSID,
+			appConfig.TwilioAuthToken,
+			appConfig.TwilioFromNumber,
+		), nil
+	case DriverFake:
+		return NewFakeSender(), nil
+	default:
+		return nil, fmt.Errorf("unknown SMS driver: %s", appConfig.SMSDriver)
+	}
+}
+
+// MFACodeMessage builds the text of a verification code message
+func MFACodeMessage(code string, expiresInMinutes int) string {
+	return fmt.Sprintf("【Makeshop Payment】確認コード：%s\n有効期限は%d分です。このコードを他人に教えないでください。", code, expiresInMinutes)
+}
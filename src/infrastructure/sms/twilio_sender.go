@@ -0,0 +1,77 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TwilioSender sends messages through the Twilio Messages REST API.
+// The base URL is configurable so compatible providers or a local mock can be used.
+type TwilioSender struct {
+	baseURL    string
+	accountSID string
+	authToken  string
+	from       string
+	client     *http.Client
+}
+
+// NewTwilioSender creates a new TwilioSender
+func NewTwilioSender(baseURL, accountSID, authToken, from string) *TwilioSender {
+	if baseURL == "" {
+		baseURL = "https://api.twilio.com"
+	}
+	return &TwilioSender{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		accountSID: accountSID,
+		authToken:  authToken,
+		from:       from,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// twilioError is the error body returned by the API
+type twilioError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Send sends the message
+func (s *TwilioSender) Send(ctx context.Context, to string, body string) error {
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", s.baseURL, url.PathEscape(s.accountSID))
+
+	form := url.Values{}
+	form.Set("To", to)
+	form.Set("From", s.from)
+	form.Set("Body", body)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(s.accountSID, s.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send SMS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var apiErr twilioError
+	if json.Unmarshal(respBody, &apiErr) == nil && apiErr.Message != "" {
+		return fmt.Errorf("failed to send SMS: %s (code %d)", apiErr.Message, apiErr.Code)
+	}
+	return fmt.Errorf("failed to send SMS: unexpected status %d", resp.StatusCode)
+}
@@ -0,0 +1,164 @@
+package totp
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed of RFC 6238 Appendix B, the ASCII string "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateCodeRFC6238(t *testing.T) {
+	// RFC 6238 Appendix B gives 8 digit codes, the last 6 digits are the 6 digit codes
+	tests := []struct {
+		unix int64
+		step int64
+		code string
+	}{
+		{59, 0x1, "94287082"},
+		{1111111109, 0x23523EC, "07081804"},
+		{1111111111, 0x23523ED, "14050471"},
+		{1234567890, 0x273EF07, "89005924"},
+		{2000000000, 0x3F940AA, "69279037"},
+		{20000000000, 0x27BC86AA, "65353130"},
+	}
+
+	for _, tt := range tests {
+		at := time.Unix(tt.unix, 0).UTC()
+		if step := Step(at); step != tt.step {
+			t.Errorf("%d: step %X, want %X", tt.unix, step, tt.step)
+		}
+		code, err := GenerateCode(rfcSecret, at)
+		if err != nil {
+			t.Fatalf("%d: %v", tt.unix, err)
+		}
+		if want := tt.code[len(tt.code)-Digits:]; code != want {
+			t.Errorf("%d: code %s, want %s", tt.unix, code, want)
+		}
+		if step, ok := Validate(code, rfcSecret, at); !ok || step != tt.step {
+			t.Errorf("%d: Validate returned %X, %v", tt.unix, step, ok)
+		}
+	}
+}
+
+func TestGenerateCodeNormalizesSecret(t *testing.T) {
+	at := time.Unix(59, 0)
+	for _, secret := range []string{strings.ToLower(rfcSecret), " " + rfcSecret + "\n"} {
+		if code, err := GenerateCode(secret, at); err != nil || code != "287082" {
+			t.Errorf("%q: got %s, %v", secret, code, err)
+		}
+	}
+	if _, err := GenerateCode("not base32!", at); err == nil {
+		t.Errorf("expected an invalid secret to be refused")
+	}
+}
+
+func TestValidateSkewWindow(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := Step(now)
+
+	tests := []struct {
+		name   string
+		offset time.Duration
+		ok     bool
+	}{
+		{"current step", 0, true},
+		{"previous step", -Period * time.Second, true},
+		{"next step", Period * time.Second, true},
+		{"two steps before", -2 * Period * time.Second, false},
+		{"two steps after", 2 * Period * time.Second, false},
+		{"an hour before", -time.Hour, false},
+	}
+
+	for _, tt := range tests {
+		codeTime := now.Add(tt.offset)
+		code, err := GenerateCode(rfcSecret, codeTime)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		step, ok := Validate(code, rfcSecret, now)
+		if ok != tt.ok {
+			t.Errorf("%s: Validate returned %v, want %v", tt.name, ok, tt.ok)
+			continue
+		}
+		// The step of the code is returned, not the current one
+		if ok && step != Step(codeTime) {
+			t.Errorf("%s: step %d, want %d (current %d)", tt.name, step, Step(codeTime), current)
+		}
+	}
+}
+
+func TestValidateRefusesMalformedCodes(t *testing.T) {
+	now := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "2870820", "94287082", "28708a", "287 082", "-87082"} {
+		if _, ok := Validate(code, rfcSecret, now); ok {
+			t.Errorf("%q: expected to be refused", code)
+		}
+	}
+	if _, ok := Validate(" 287082 ", rfcSecret, now); !ok {
+		t.Errorf("expected surrounding spaces to be ignored")
+	}
+	if _, ok := Validate("287082", "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJR", now); ok {
+		t.Errorf("expected a code of another secret to be refused")
+	}
+	if _, ok := Validate("287082", "not base32!", now); ok {
+		t.Errorf("expected an invalid secret to be refused")
+	}
+}
+
+// Validate accepts a code for as long as it stays in the window. Replays are refused by
+// recording the returned step and only accepting later ones, which relies on the step
+// identifying the code whatever time it is validated at.
+func TestValidateStepIdentifiesCode(t *testing.T) {
+	issued := time.Unix(1111111109, 0)
+	code, err := GenerateCode(rfcSecret, issued)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var steps []int64
+	for _, at := range []time.Time{issued.Add(-Period * time.Second), issued, issued.Add(Period * time.Second)} {
+		step, ok := Validate(code, rfcSecret, at)
+		if !ok {
+			t.Fatalf("code refused at %d", at.Unix())
+		}
+		steps = append(steps, step)
+	}
+	for _, step := range steps {
+		if step != Step(issued) {
+			t.Errorf("steps %v, want %d each time", steps, Step(issued))
+		}
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := encoding.DecodeString(secret)
+	if err != nil || len(key) != secretSize {
+		t.Errorf("secret %q decodes to %d bytes: %v", secret, len(key), err)
+	}
+	other, _ := GenerateSecret()
+	if other == secret {
+		t.Errorf("expected random secrets")
+	}
+}
+
+func TestKeyURI(t *testing.T) {
+	uri, err := url.Parse(KeyURI("MakeShop Payment", "jane@example.com", rfcSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/MakeShop Payment:jane@example.com" {
+		t.Errorf("unexpected URI %s", uri)
+	}
+	query := uri.Query()
+	if query.Get("secret") != rfcSecret || query.Get("issuer") != "MakeShop Payment" || query.Get("algorithm") != "SHA1" ||
+		query.Get("digits") != "6" || query.Get("period") != "30" {
+		t.Errorf("unexpected parameters %v", query)
+	}
+}
@@ -0,0 +1,67 @@
+// Package useragent derives a human readable device label from a User-Agent header
+package useragent
+
+import (
+	"strings"
+)
+
+// signature maps a token found in a User-Agent header to a display name
+type signature struct {
+	token string
+	name  string
+}
+
+// browsers are matched in order, so tokens also sent by other browsers come last
+var browsers = []signature{
+	{"Edg/", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"Firefox/", "Firefox"},
+	{"FxiOS/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Safari/", "Safari"},
+}
+
+// platforms are matched in order, so iOS and Android are found before macOS and Linux
+var platforms = []signature{
+	{"iPhone", "iPhone"},
+	{"iPad", "iPad"},
+	{"Android", "Android"},
+	{"Windows", "Windows"},
+	{"Mac OS X", "macOS"},
+	{"Macintosh", "macOS"},
+	{"CrOS", "ChromeOS"},
+	{"Linux", "Linux"},
+}
+
+// DeviceLabel returns a label such as "Chrome on Windows", or "Unknown device"
+func DeviceLabel(userAgent string) string {
+	browser := match(userAgent, browsers)
+	platform := match(userAgent, platforms)
+
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return platform
+	}
+
+	// API clients such as "curl/8.0" are labelled by their product name
+	if product, _, found := strings.Cut(userAgent, "/"); found && product != "" && !strings.Contains(product, " ") {
+		return product
+	}
+	return "Unknown device"
+}
+
+// match returns the name of the first token found in the user agent
+func match(userAgent string, candidates []signature) string {
+	for _, candidate := range candidates {
+		if strings.Contains(userAgent, candidate.token) {
+			return candidate.name
+		}
+	}
+	return ""
+}
@@ -47,7 +47,16 @@ func (v *CustomValidator) ValidateStruct(obj interface{}) error {
 	return v.validate.Struct(obj)
 }
 
+// defaultValidator is shared by Gin and the use cases, so that requests are checked the same way
+// whichever API they come through
+var defaultValidator = &CustomValidator{}
+
+// Validate validates a struct against its binding tags
+func Validate(obj interface{}) error {
+	return defaultValidator.ValidateStruct(obj)
+}
+
 // Setup sets up the validator for Gin
 func Setup() {
-	binding.Validator = &CustomValidator{}
+	binding.Validator = defaultValidator
 }
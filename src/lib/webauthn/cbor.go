@@ -0,0 +1,137 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// maxCBORDepth bounds the nesting of decoded CBOR items
+const maxCBORDepth = 16
+
+var errInvalidCBOR = errors.New("invalid CBOR data")
+
+// decodeCBOR decodes the first CBOR item of data (RFC 8949) and returns the remaining bytes.
+// Only the subset produced by authenticators is supported: definite lengths, integers as
+// int64, byte strings as []byte, text strings, arrays, maps keyed by int64 or string,
+// booleans and null. Tags are skipped.
+func decodeCBOR(data []byte) (interface{}, []byte, error) {
+	return decodeCBORItem(data, 0)
+}
+
+func decodeCBORItem(data []byte, depth int) (interface{}, []byte, error) {
+	if depth > maxCBORDepth || len(data) == 0 {
+		return nil, nil, errInvalidCBOR
+	}
+
+	major := data[0] >> 5
+	info := data[0] & 0x1f
+	data = data[1:]
+
+	// Simple values and floats carry their value in the additional information
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, data, nil
+		case 21:
+			return true, data, nil
+		case 22, 23:
+			return nil, data, nil
+		case 26:
+			if len(data) < 4 {
+				return nil, nil, errInvalidCBOR
+			}
+			return float64(math.Float32frombits(binary.BigEndian.Uint32(data))), data[4:], nil
+		case 27:
+			if len(data) < 8 {
+				return nil, nil, errInvalidCBOR
+			}
+			return math.Float64frombits(binary.BigEndian.Uint64(data)), data[8:], nil
+		}
+		return nil, nil, errInvalidCBOR
+	}
+
+	argument, data, err := cborArgument(info, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case 0:
+		if argument > math.MaxInt64 {
+			return nil, nil, errInvalidCBOR
+		}
+		return int64(argument), data, nil
+	case 1:
+		if argument > math.MaxInt64 {
+			return nil, nil, errInvalidCBOR
+		}
+		return -1 - int64(argument), data, nil
+	case 2, 3:
+		if argument > uint64(len(data)) {
+			return nil, nil, errInvalidCBOR
+		}
+		value := data[:argument]
+		if major == 3 {
+			return string(value), data[argument:], nil
+		}
+		return append([]byte(nil), value...), data[argument:], nil
+	case 4:
+		if argument > uint64(len(data)) {
+			return nil, nil, errInvalidCBOR
+		}
+		items := make([]interface{}, 0, argument)
+		for i := uint64(0); i < argument; i++ {
+			var item interface{}
+			item, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+		return items, data, nil
+	case 5:
+		if argument > uint64(len(data)) {
+			return nil, nil, errInvalidCBOR
+		}
+		entries := make(map[interface{}]interface{}, argument)
+		for i := uint64(0); i < argument; i++ {
+			var key, value interface{}
+			key, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, errInvalidCBOR
+			}
+			value, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			entries[key] = value
+		}
+		return entries, data, nil
+	case 6:
+		return decodeCBORItem(data, depth+1)
+	}
+	return nil, nil, errInvalidCBOR
+}
+
+// cborArgument reads the argument of an item header. Indefinite lengths are refused.
+func cborArgument(info byte, data []byte) (uint64, []byte, error) {
+	switch {
+	case info < 24:
+		return uint64(info), data, nil
+	case info == 24 && len(data) >= 1:
+		return uint64(data[0]), data[1:], nil
+	case info == 25 && len(data) >= 2:
+		return uint64(binary.BigEndian.Uint16(data)), data[2:], nil
+	case info == 26 && len(data) >= 4:
+		return uint64(binary.BigEndian.Uint32(data)), data[4:], nil
+	case info == 27 && len(data) >= 8:
+		return binary.BigEndian.Uint64(data), data[8:], nil
+	}
+	return 0, nil, errInvalidCBOR
+}
@@ -0,0 +1,101 @@
+package webauthn
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	// Examples of RFC 8949 Appendix A within the supported subset
+	tests := []struct {
+		hex  string
+		want interface{}
+	}{
+		{"00", int64(0)},
+		{"17", int64(23)},
+		{"1818", int64(24)},
+		{"1903e8", int64(1000)},
+		{"1a000f4240", int64(1000000)},
+		{"1b000000e8d4a51000", int64(1000000000000)},
+		{"20", int64(-1)},
+		{"3903e7", int64(-1000)},
+		{"f4", false},
+		{"f5", true},
+		{"f6", nil},
+		{"fa47c35000", float64(100000)},
+		{"fb3ff199999999999a", 1.1},
+		{"40", []byte(nil)},
+		{"4401020304", []byte{1, 2, 3, 4}},
+		{"60", ""},
+		{"6161", "a"},
+		{"64f0908591", "\U00010151"},
+		{"80", []interface{}{}},
+		{"83010203", []interface{}{int64(1), int64(2), int64(3)}},
+		{"8301820203820405", []interface{}{int64(1), []interface{}{int64(2), int64(3)}, []interface{}{int64(4), int64(5)}}},
+		{"a0", map[interface{}]interface{}{}},
+		{"a201020304", map[interface{}]interface{}{int64(1): int64(2), int64(3): int64(4)}},
+		{"a26161016162820203", map[interface{}]interface{}{"a": int64(1), "b": []interface{}{int64(2), int64(3)}}},
+		{"c11a514b67b0", int64(1363896240)},
+		{"d74401020304", []byte{1, 2, 3, 4}},
+	}
+
+	for _, tt := range tests {
+		data, _ := hex.DecodeString(tt.hex)
+		got, rest, err := decodeCBOR(data)
+		if err != nil {
+			t.Errorf("%s: %v", tt.hex, err)
+			continue
+		}
+		if len(rest) != 0 {
+			t.Errorf("%s: %d bytes left", tt.hex, len(rest))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.hex, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeCBORReturnsRemainingBytes(t *testing.T) {
+	got, rest, err := decodeCBOR([]byte{0x01, 0x02, 0x03})
+	if err != nil || got != int64(1) || !bytes.Equal(rest, []byte{0x02, 0x03}) {
+		t.Errorf("got %v, rest %x, err %v", got, rest, err)
+	}
+}
+
+func TestDecodeCBORRefusesInvalidData(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+	}{
+		{"empty", ""},
+		{"truncated argument", "1903"},
+		{"truncated 64-bit argument", "1b000000e8d4a510"},
+		{"truncated byte string", "44010203"},
+		{"truncated text string", "646162"},
+		{"truncated array", "830102"},
+		{"truncated map", "a2010203"},
+		{"truncated float", "fa47c350"},
+		{"byte string longer than the data", "5affffffff00"},
+		{"array longer than the data", "9bffffffffffffffff00"},
+		{"map longer than the data", "baffffffff00"},
+		{"unsigned integer above int64", "1bffffffffffffffff"},
+		{"negative integer below int64", "3bffffffffffffffff"},
+		{"indefinite length byte string", "5f42010243030405ff"},
+		{"indefinite length array", "9f018202039f0405ffff"},
+		{"reserved additional information", "1c"},
+		{"unassigned simple value", "f0"},
+		{"byte string map key", "a1420102f5"},
+		{"array map key", "a18001f5"},
+		{"nesting too deep", "818181818181818181818181818181818101"},
+		{"tag without content", "c1"},
+	}
+
+	for _, tt := range tests {
+		data, _ := hex.DecodeString(tt.hex)
+		if got, _, err := decodeCBOR(data); err == nil {
+			t.Errorf("%s: expected an error, got %#v", tt.name, got)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithm identifiers of the supported credential keys (RFC 9053)
+const (
+	AlgES256 = -7
+	AlgEdDSA = -8
+	AlgRS256 = -257
+)
+
+// SupportedAlgorithms are offered to authenticators in order of preference
+var SupportedAlgorithms = []int{AlgES256, AlgEdDSA, AlgRS256}
+
+// COSE key parameters (RFC 9052 section 7 and RFC 9053 section 7)
+const (
+	coseKeyType  = 1
+	coseKeyAlg   = 3
+	coseCurve    = -1 // Also the RSA modulus n
+	coseX        = -2 // Also the RSA exponent e
+	coseY        = -3
+	coseKTypeOKP = 1
+	coseKTypeEC2 = 2
+	coseKTypeRSA = 3
+	coseP256     = 1
+	coseEd25519  = 6
+)
+
+// minRSABits is the smallest RSA modulus accepted for a credential key
+const minRSABits = 2048
+
+// PublicKey is a credential public key and the algorithm its signatures use
+type PublicKey struct {
+	Algorithm int
+	Key       crypto.PublicKey
+}
+
+// ParsePublicKey parses a COSE_Key as stored for a credential
+func ParsePublicKey(coseKey []byte) (*PublicKey, error) {
+	value, rest, err := decodeCBOR(coseKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("trailing data after COSE key")
+	}
+	return publicKeyFromCOSE(value)
+}
+
+// publicKeyFromCOSE converts a decoded COSE_Key map
+func publicKeyFromCOSE(value interface{}) (*PublicKey, error) {
+	params, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("COSE key is not a map")
+	}
+	kty, _ := params[int64(coseKeyType)].(int64)
+	alg, _ := params[int64(coseKeyAlg)].(int64)
+
+	switch {
+	case kty == coseKTypeEC2 && alg == AlgES256:
+		if crv, _ := params[int64(coseCurve)].(int64); crv != coseP256 {
+			return nil, fmt.Errorf("unsupported EC2 curve %d", crv)
+		}
+		x, _ := params[int64(coseX)].([]byte)
+		y, _ := params[int64(coseY)].([]byte)
+		if len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("invalid P-256 key coordinates")
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		// Converting to ECDH fails when the point is not on the curve
+		if _, err := key.ECDH(); err != nil {
+			return nil, fmt.Errorf("invalid P-256 key: %w", err)
+		}
+		return &PublicKey{Algorithm: AlgES256, Key: key}, nil
+
+	case kty == coseKTypeOKP && alg == AlgEdDSA:
+		if crv, _ := params[int64(coseCurve)].(int64); crv != coseEd25519 {
+			return nil, fmt.Errorf("unsupported OKP curve %d", crv)
+		}
+		x, _ := params[int64(coseX)].([]byte)
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return &PublicKey{Algorithm: AlgEdDSA, Key: ed25519.PublicKey(x)}, nil
+
+	case kty == coseKTypeRSA && alg == AlgRS256:
+		n, _ := params[int64(coseCurve)].([]byte)
+		e, _ := params[int64(coseX)].([]byte)
+		modulus := new(big.Int).SetBytes(n)
+		exponent := new(big.Int).SetBytes(e)
+		if modulus.BitLen() < minRSABits || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA key")
+		}
+		return &PublicKey{Algorithm: AlgRS256, Key: &rsa.PublicKey{N: modulus, E: int(exponent.Int64())}}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported COSE key type %d with algorithm %d", kty, alg)
+}
+
+// Verify checks a signature made by the credential over data
+func (k *PublicKey) Verify(data, signature []byte) bool {
+	switch key := k.Key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return ecdsa.VerifyASN1(key, digest[:], signature)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, data, signature)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	}
+	return false
+}
@@ -0,0 +1,463 @@
+// Package webauthn implements the relying party side of the WebAuthn registration and
+// authentication ceremonies (https://www.w3.org/TR/webauthn-3/) used for passkeys.
+// Attestation is not requested, so authenticators are trusted as self-attested.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// challengeSize is the entropy of a ceremony challenge (at least 16 bytes per WebAuthn 13.4.3)
+	challengeSize = 32
+	// maxCredentialIDSize is the largest credential ID allowed by WebAuthn
+	maxCredentialIDSize = 1023
+)
+
+// Authenticator data flags (WebAuthn 6.1)
+const (
+	flagUserPresent       = 0x01
+	flagUserVerified      = 0x04
+	flagBackupEligible    = 0x08
+	flagBackupState       = 0x10
+	flagAttestedData      = 0x40
+	flagExtensionData     = 0x80
+	authenticatorDataSize = 37 // rpIdHash, flags and signCount
+)
+
+// User verification requirements
+const (
+	UserVerificationRequired  = "required"
+	UserVerificationPreferred = "preferred"
+)
+
+// ErrSignCountRegressed is returned when an authenticator reports a signature counter that is
+// not greater than the stored one, which suggests that the credential has been cloned
+var ErrSignCountRegressed = errors.New("authenticator signature counter did not increase")
+
+// encoding is the base64url encoding without padding used by the WebAuthn JSON forms
+var encoding = base64.RawURLEncoding
+
+// RelyingParty is the site credentials are registered with
+type RelyingParty struct {
+	ID      string        // Domain credentials are scoped to, e.g. example.com
+	Name    string        // Name shown by the authenticator
+	Origins []string      // Origins the front-end runs on, e.g. https://app.example.com
+	Timeout time.Duration // Time the browser gives the user to complete a ceremony
+}
+
+// UserEntity identifies the account a credential is created for
+type UserEntity struct {
+	ID          string `json:"id"` // base64url user handle, which must not contain personal data
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// CredentialDescriptor identifies an existing credential
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"` // base64url credential ID
+	Transports []string `json:"transports,omitempty"`
+}
+
+// CredentialParameter is a key type accepted for new credentials
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+// AuthenticatorSelection states the required authenticator capabilities
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// RelyingPartyEntity describes the site to the authenticator
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// CreationOptions is the PublicKeyCredentialCreationOptionsJSON given to
+// PublicKeyCredential.parseCreationOptionsFromJSON() by the front-end
+type CreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RP                     RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions is the PublicKeyCredentialRequestOptionsJSON given to
+// PublicKeyCredential.parseRequestOptionsFromJSON() by the front-end
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// ClientData is the client data collected by the browser (WebAuthn 5.8.1)
+type ClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// Registration is a credential returned by navigator.credentials.create(), not yet verified
+type Registration struct {
+	CredentialID      []byte
+	ClientData        ClientData
+	Transports        []string
+	clientDataJSON    []byte
+	attestationObject []byte
+}
+
+// Assertion is a signature returned by navigator.credentials.get(), not yet verified
+type Assertion struct {
+	CredentialID      []byte
+	UserHandle        []byte // Set by discoverable credentials
+	ClientData        ClientData
+	clientDataJSON    []byte
+	authenticatorData []byte
+	signature         []byte
+}
+
+// Credential is a verified new credential to store for the user
+type Credential struct {
+	ID             []byte
+	PublicKey      []byte // COSE_Key, parsed again with ParsePublicKey to verify assertions
+	SignCount      uint32
+	Transports     []string
+	BackupEligible bool // The credential can be synced to other devices, e.g. a passkey in a password manager
+}
+
+// authenticatorData is the parsed authenticator data (WebAuthn 6.1)
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	publicKey    []byte
+}
+
+// NewChallenge returns a random base64url encoded challenge
+func NewChallenge() (string, error) {
+	buf := make([]byte, challengeSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(buf), nil
+}
+
+// EncodeID encodes a credential ID or user handle for the JSON options
+func EncodeID(id []byte) string {
+	return encoding.EncodeToString(id)
+}
+
+// CreationOptions returns the options of a registration ceremony. Existing credentials of
+// the user are excluded so the same authenticator is not registered twice.
+func (rp *RelyingParty) CreationOptions(challenge string, user UserEntity, exclude []CredentialDescriptor) *CreationOptions {
+	params := make([]CredentialParameter, 0, len(SupportedAlgorithms))
+	for _, alg := range SupportedAlgorithms {
+		params = append(params, CredentialParameter{Type: "public-key", Alg: alg})
+	}
+	if exclude == nil {
+		exclude = []CredentialDescriptor{}
+	}
+
+	return &CreationOptions{
+		Challenge:          challenge,
+		RP:                 RelyingPartyEntity{ID: rp.ID, Name: rp.Name},
+		User:               user,
+		PubKeyCredParams:   params,
+		Timeout:            rp.Timeout.Milliseconds(),
+		ExcludeCredentials: exclude,
+		// Discoverable credentials are preferred so the passkey can also be used without a password
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: UserVerificationPreferred,
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions returns the options of an authentication ceremony. Without allowed
+// credentials the browser offers every discoverable credential of the site.
+func (rp *RelyingParty) RequestOptions(challenge string, allow []CredentialDescriptor, userVerification string) *RequestOptions {
+	if allow == nil {
+		allow = []CredentialDescriptor{}
+	}
+	return &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          rp.Timeout.Milliseconds(),
+		RPID:             rp.ID,
+		AllowCredentials: allow,
+		UserVerification: userVerification,
+	}
+}
+
+// ParseRegistration parses the RegistrationResponseJSON of a new credential
+func ParseRegistration(data string) (*Registration, error) {
+	var body struct {
+		RawID    string `json:"rawId"`
+		Type     string `json:"type"`
+		Response struct {
+			ClientDataJSON    string   `json:"clientDataJSON"`
+			AttestationObject string   `json:"attestationObject"`
+			Transports        []string `json:"transports"`
+		} `json:"response"`
+	}
+	if err := json.Unmarshal([]byte(data), &body); err != nil {
+		return nil, fmt.Errorf("invalid credential: %w", err)
+	}
+	if body.Type != "public-key" {
+		return nil, errors.New("invalid credential type")
+	}
+
+	reg := &Registration{Transports: body.Response.Transports}
+	var err error
+	if reg.CredentialID, err = decodeField(body.RawID, "rawId"); err != nil {
+		return nil, err
+	}
+	if reg.clientDataJSON, err = decodeField(body.Response.ClientDataJSON, "clientDataJSON"); err != nil {
+		return nil, err
+	}
+	if reg.attestationObject, err = decodeField(body.Response.AttestationObject, "attestationObject"); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(reg.clientDataJSON, &reg.ClientData); err != nil {
+		return nil, fmt.Errorf("invalid client data: %w", err)
+	}
+	return reg, nil
+}
+
+// ParseAssertion parses the AuthenticationResponseJSON of a credential
+func ParseAssertion(data string) (*Assertion, error) {
+	var body struct {
+		RawID    string `json:"rawId"`
+		Type     string `json:"type"`
+		Response struct {
+			ClientDataJSON    string `json:"clientDataJSON"`
+			AuthenticatorData string `json:"authenticatorData"`
+			Signature         string `json:"signature"`
+			UserHandle        string `json:"userHandle"`
+		} `json:"response"`
+	}
+	if err := json.Unmarshal([]byte(data), &body); err != nil {
+		return nil, fmt.Errorf("invalid credential: %w", err)
+	}
+	if body.Type != "public-key" {
+		return nil, errors.New("invalid credential type")
+	}
+
+	assertion := &Assertion{}
+	var err error
+	if assertion.CredentialID, err = decodeField(body.RawID, "rawId"); err != nil {
+		return nil, err
+	}
+	if assertion.clientDataJSON, err = decodeField(body.Response.ClientDataJSON, "clientDataJSON"); err != nil {
+		return nil, err
+	}
+	if assertion.authenticatorData, err = decodeField(body.Response.AuthenticatorData, "authenticatorData"); err != nil {
+		return nil, err
+	}
+	if assertion.signature, err = decodeField(body.Response.Signature, "signature"); err != nil {
+		return nil, err
+	}
+	if body.Response.UserHandle != "" {
+		if assertion.UserHandle, err = decodeField(body.Response.UserHandle, "userHandle"); err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(assertion.clientDataJSON, &assertion.ClientData); err != nil {
+		return nil, fmt.Errorf("invalid client data: %w", err)
+	}
+	return assertion, nil
+}
+
+// VerifyRegistration checks a new credential against the challenge of the ceremony
+// (WebAuthn 7.1) and returns the credential to store
+func (rp *RelyingParty) VerifyRegistration(reg *Registration, challenge string, requireUserVerification bool) (*Credential, error) {
+	if err := rp.verifyClientData(&reg.ClientData, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	value, rest, err := decodeCBOR(reg.attestationObject)
+	if err != nil || len(rest) != 0 {
+		return nil, errors.New("invalid attestation object")
+	}
+	attestation, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("invalid attestation object")
+	}
+	rawAuthData, _ := attestation["authData"].([]byte)
+	if _, ok := attestation["fmt"].(string); !ok {
+		return nil, errors.New("invalid attestation format")
+	}
+
+	authData, err := rp.verifyAuthenticatorData(rawAuthData, requireUserVerification)
+	if err != nil {
+		return nil, err
+	}
+	if authData.flags&flagAttestedData == 0 || authData.publicKey == nil {
+		return nil, errors.New("authenticator data has no credential")
+	}
+	if !bytes.Equal(authData.credentialID, reg.CredentialID) {
+		return nil, errors.New("credential ID mismatch")
+	}
+	if _, err := ParsePublicKey(authData.publicKey); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:             authData.credentialID,
+		PublicKey:      authData.publicKey,
+		SignCount:      authData.signCount,
+		Transports:     reg.Transports,
+		BackupEligible: authData.flags&flagBackupEligible != 0,
+	}, nil
+}
+
+// VerifyAssertion checks an assertion against the challenge of the ceremony and the stored
+// credential (WebAuthn 7.2), and returns the new signature counter to store
+func (rp *RelyingParty) VerifyAssertion(assertion *Assertion, challenge string, publicKey []byte, signCount uint32, requireUserVerification bool) (uint32, error) {
+	if err := rp.verifyClientData(&assertion.ClientData, "webauthn.get", challenge); err != nil {
+		return 0, err
+	}
+
+	authData, err := rp.verifyAuthenticatorData(assertion.authenticatorData, requireUserVerification)
+	if err != nil {
+		return 0, err
+	}
+
+	key, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(assertion.clientDataJSON)
+	signed := append(append([]byte(nil), assertion.authenticatorData...), clientDataHash[:]...)
+	if !key.Verify(signed, assertion.signature) {
+		return 0, errors.New("invalid signature")
+	}
+
+	// Authenticators without a counter always report zero
+	if (authData.signCount != 0 || signCount != 0) && authData.signCount <= signCount {
+		return 0, ErrSignCountRegressed
+	}
+	return authData.signCount, nil
+}
+
+// verifyClientData checks the ceremony type, challenge and origin of the client data
+func (rp *RelyingParty) verifyClientData(clientData *ClientData, ceremony, challenge string) error {
+	if clientData.Type != ceremony {
+		return errors.New("unexpected ceremony type")
+	}
+	if challenge == "" || subtle.ConstantTimeCompare([]byte(strings.TrimRight(clientData.Challenge, "=")), []byte(challenge)) != 1 {
+		return errors.New("challenge mismatch")
+	}
+	if clientData.CrossOrigin {
+		return errors.New("cross-origin ceremonies are not allowed")
+	}
+	for _, origin := range rp.Origins {
+		if clientData.Origin == origin {
+			return nil
+		}
+	}
+	return fmt.Errorf("origin %q is not allowed", clientData.Origin)
+}
+
+// verifyAuthenticatorData parses authenticator data and checks that it was produced for this
+// relying party with the user present, and verified when required
+func (rp *RelyingParty) verifyAuthenticatorData(data []byte, requireUserVerification bool) (*authenticatorData, error) {
+	authData, err := parseAuthenticatorData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if subtle.ConstantTimeCompare(authData.rpIDHash, rpIDHash[:]) != 1 {
+		return nil, errors.New("credential belongs to another relying party")
+	}
+	if authData.flags&flagUserPresent == 0 {
+		return nil, errors.New("user presence is required")
+	}
+	if requireUserVerification && authData.flags&flagUserVerified == 0 {
+		return nil, errors.New("user verification is required")
+	}
+	// A credential cannot be backed up without being eligible for backup
+	if authData.flags&flagBackupState != 0 && authData.flags&flagBackupEligible == 0 {
+		return nil, errors.New("invalid backup flags")
+	}
+	return authData, nil
+}
+
+// parseAuthenticatorData parses the binary authenticator data (WebAuthn 6.1)
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < authenticatorDataSize {
+		return nil, errors.New("authenticator data is too short")
+	}
+
+	authData := &authenticatorData{
+		rpIDHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[authenticatorDataSize:]
+
+	if authData.flags&flagAttestedData != 0 {
+		// aaguid (16 bytes), credentialIdLength (2 bytes), credentialId, credentialPublicKey
+		if len(rest) < 18 {
+			return nil, errors.New("invalid attested credential data")
+		}
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLength == 0 || idLength > maxCredentialIDSize || len(rest) < idLength {
+			return nil, errors.New("invalid credential ID")
+		}
+		authData.credentialID = append([]byte(nil), rest[:idLength]...)
+		rest = rest[idLength:]
+
+		_, afterKey, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, errors.New("invalid credential public key")
+		}
+		authData.publicKey = append([]byte(nil), rest[:len(rest)-len(afterKey)]...)
+		rest = afterKey
+	}
+
+	if authData.flags&flagExtensionData != 0 {
+		var err error
+		if _, rest, err = decodeCBOR(rest); err != nil {
+			return nil, errors.New("invalid authenticator extensions")
+		}
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("trailing data after authenticator data")
+	}
+	return authData, nil
+}
+
+// decodeField decodes a base64url field of a credential, tolerating padding
+func decodeField(value, name string) ([]byte, error) {
+	decoded, err := encoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil || len(decoded) == 0 {
+		return nil, fmt.Errorf("invalid %s", name)
+	}
+	return decoded, nil
+}
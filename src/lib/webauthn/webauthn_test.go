@@ -0,0 +1,387 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Ceremonies captured from browsers on https://webauthn.io, the assertion with macOS Touch ID
+const (
+	capturedRegistration = `{
+		"id": "6xrtBhJQW6QU4tOaB4rrHaS2Ks0yDDL_q8jDC16DEjZ-VLVf4kCRkvl2xp2D71sTPYns-exsHQHTy3G-zJRK8g",
+		"rawId": "6xrtBhJQW6QU4tOaB4rrHaS2Ks0yDDL_q8jDC16DEjZ-VLVf4kCRkvl2xp2D71sTPYns-exsHQHTy3G-zJRK8g",
+		"type": "public-key",
+		"response": {
+			"attestationObject": "o2NmbXRkbm9uZWdhdHRTdG10oGhhdXRoRGF0YVjEdKbqkhPJnC90siSSsyDPQCYqlMGpUKA5fyklC2CEHvBBAAAAAAAAAAAAAAAAAAAAAAAAAAAAQOsa7QYSUFukFOLTmgeK6x2ktirNMgwy_6vIwwtegxI2flS1X-JAkZL5dsadg-9bEz2J7PnsbB0B08txvsyUSvKlAQIDJiABIVggLKF5xS0_BntttUIrm2Z2tgZ4uQDwllbdIfrrBMABCNciWCDHwin8Zdkr56iSIh0MrB5qZiEzYLQpEOREhMUkY6q4Vw",
+			"clientDataJSON": "eyJjaGFsbGVuZ2UiOiJXOEd6RlU4cEdqaG9SYldyTERsYW1BZnFfeTRTMUNaRzFWdW9lUkxBUnJFIiwib3JpZ2luIjoiaHR0cHM6Ly93ZWJhdXRobi5pbyIsInR5cGUiOiJ3ZWJhdXRobi5jcmVhdGUifQ",
+			"transports": ["internal"]
+		}
+	}`
+	capturedRegistrationChallenge = "W8GzFU8pGjhoRbWrLDlamAfq_y4S1CZG1VuoeRLARrE"
+
+	capturedAssertion = `{
+		"id": "AI7D5q2P0LS-Fal9ZT7CHM2N5BLbUunF92T8b6iYC199bO2kagSuU05-5dZGqb1SP0A0lyTWng",
+		"rawId": "AI7D5q2P0LS-Fal9ZT7CHM2N5BLbUunF92T8b6iYC199bO2kagSuU05-5dZGqb1SP0A0lyTWng",
+		"type": "public-key",
+		"response": {
+			"authenticatorData": "dKbqkhPJnC90siSSsyDPQCYqlMGpUKA5fyklC2CEHvBFXJJiGa3OAAI1vMYKZIsLJfHwVQMANwCOw-atj9C0vhWpfWU-whzNjeQS21Lpxfdk_G-omAtffWztpGoErlNOfuXWRqm9Uj9ANJck1p6lAQIDJiABIVggKAhfsdHcBIc0KPgAcRyAIK_-Vi-nCXHkRHPNaCMBZ-4iWCBxB8fGYQSBONi9uvq0gv95dGWlhJrBwCsj_a4LJQKVHQ",
+			"clientDataJSON": "eyJjaGFsbGVuZ2UiOiJFNFBUY0lIX0hmWDFwQzZTaWdrMVNDOU5BbGdlenROMDQzOXZpOHpfYzlrIiwibmV3X2tleXNfbWF5X2JlX2FkZGVkX2hlcmUiOiJkbyBub3QgY29tcGFyZSBjbGllbnREYXRhSlNPTiBhZ2FpbnN0IGEgdGVtcGxhdGUuIFNlZSBodHRwczovL2dvby5nbC95YWJQZXgiLCJvcmlnaW4iOiJodHRwczovL3dlYmF1dGhuLmlvIiwidHlwZSI6IndlYmF1dGhuLmdldCJ9",
+			"signature": "MEUCIBtIVOQxzFYdyWQyxaLR0tik1TnuPhGVhXVSNgFwLmN5AiEAnxXdCq0UeAVGWxOaFcjBZ_mEZoXqNboY5IkQDdlWZYc",
+			"userHandle": "0ToAAAAAAAAAAA"
+		}
+	}`
+	capturedAssertionChallenge = "E4PTcIH_HfX1pC6Sigk1SC9NAlgeztN0439vi8z_c9k"
+	capturedAssertionPublicKey = "pQMmIAEhWCAoCF-x0dwEhzQo-ABxHIAgr_5WL6cJceREc81oIwFn7iJYIHEHx8ZhBIE42L26-rSC_3l0ZaWEmsHAKyP9rgslApUdAQI"
+	capturedAssertionSignCount = 1553097241
+)
+
+// testRP is the relying party of the captured ceremonies
+var testRP = &RelyingParty{ID: "webauthn.io", Name: "webauthn.io", Origins: []string{"https://webauthn.io"}}
+
+func TestVerifyRegistrationCapture(t *testing.T) {
+	reg, err := ParseRegistration(capturedRegistration)
+	if err != nil {
+		t.Fatalf("ParseRegistration: %v", err)
+	}
+
+	credential, err := testRP.VerifyRegistration(reg, capturedRegistrationChallenge, false)
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+	if EncodeID(credential.ID) != "6xrtBhJQW6QU4tOaB4rrHaS2Ks0yDDL_q8jDC16DEjZ-VLVf4kCRkvl2xp2D71sTPYns-exsHQHTy3G-zJRK8g" {
+		t.Errorf("unexpected credential ID %s", EncodeID(credential.ID))
+	}
+	if credential.SignCount != 0 || credential.BackupEligible || len(credential.Transports) != 1 {
+		t.Errorf("unexpected credential %+v", credential)
+	}
+	key, err := ParsePublicKey(credential.PublicKey)
+	if err != nil || key.Algorithm != AlgES256 {
+		t.Errorf("unexpected public key %+v: %v", key, err)
+	}
+}
+
+func TestVerifyRegistrationCaptureRefusals(t *testing.T) {
+	tests := []struct {
+		name                    string
+		rp                      *RelyingParty
+		challenge               string
+		requireUserVerification bool
+		want                    string
+	}{
+		{"other challenge", testRP, "W8GzFU8pGjhoRbWrLDlamAfq_y4S1CZG1VuoeRLARrF", false, "challenge mismatch"},
+		{"no challenge", testRP, "", false, "challenge mismatch"},
+		{"other origin", &RelyingParty{ID: "webauthn.io", Origins: []string{"https://example.com"}}, capturedRegistrationChallenge, false, "is not allowed"},
+		{"other relying party", &RelyingParty{ID: "example.com", Origins: []string{"https://webauthn.io"}}, capturedRegistrationChallenge, false, "another relying party"},
+		// The authenticator did not report user verification for this registration
+		{"user verification required", testRP, capturedRegistrationChallenge, true, "user verification is required"},
+	}
+
+	for _, tt := range tests {
+		reg, err := ParseRegistration(capturedRegistration)
+		if err != nil {
+			t.Fatalf("ParseRegistration: %v", err)
+		}
+		if _, err := tt.rp.VerifyRegistration(reg, tt.challenge, tt.requireUserVerification); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestVerifyRegistrationRefusesMalformedAttestation(t *testing.T) {
+	reg, err := ParseRegistration(capturedRegistration)
+	if err != nil {
+		t.Fatalf("ParseRegistration: %v", err)
+	}
+	attestationObject := reg.attestationObject
+
+	tests := []struct {
+		name              string
+		attestationObject []byte
+	}{
+		{"truncated", attestationObject[:len(attestationObject)-10]},
+		{"trailing data", append(append([]byte(nil), attestationObject...), 0x00)},
+		{"not a map", []byte{0x83, 0x01, 0x02, 0x03}},
+		{"no authenticator data", []byte{0xa1, 0x63, 'f', 'm', 't', 0x64, 'n', 'o', 'n', 'e'}},
+	}
+
+	for _, tt := range tests {
+		reg.attestationObject = tt.attestationObject
+		if _, err := testRP.VerifyRegistration(reg, capturedRegistrationChallenge, false); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestVerifyRegistrationRefusesOtherCredentialID(t *testing.T) {
+	reg, err := ParseRegistration(capturedRegistration)
+	if err != nil {
+		t.Fatalf("ParseRegistration: %v", err)
+	}
+	reg.CredentialID[0] ^= 0xff
+	if _, err := testRP.VerifyRegistration(reg, capturedRegistrationChallenge, false); err == nil || !strings.Contains(err.Error(), "credential ID mismatch") {
+		t.Errorf("expected a credential ID mismatch, got %v", err)
+	}
+}
+
+func TestVerifyAssertionCapture(t *testing.T) {
+	assertion, err := ParseAssertion(capturedAssertion)
+	if err != nil {
+		t.Fatalf("ParseAssertion: %v", err)
+	}
+	if EncodeID(assertion.UserHandle) != "0ToAAAAAAAAAAA" {
+		t.Errorf("unexpected user handle %s", EncodeID(assertion.UserHandle))
+	}
+	publicKey, _ := encoding.DecodeString(capturedAssertionPublicKey)
+
+	signCount, err := testRP.VerifyAssertion(assertion, capturedAssertionChallenge, publicKey, 0, true)
+	if err != nil {
+		t.Fatalf("VerifyAssertion: %v", err)
+	}
+	if signCount != capturedAssertionSignCount {
+		t.Errorf("expected sign count %d, got %d", capturedAssertionSignCount, signCount)
+	}
+}
+
+func TestVerifyAssertionCaptureRefusals(t *testing.T) {
+	publicKey, _ := encoding.DecodeString(capturedAssertionPublicKey)
+	otherKey := newTestKey(t)
+
+	tests := []struct {
+		name      string
+		rp        *RelyingParty
+		challenge string
+		publicKey []byte
+		signCount uint32
+		want      string
+	}{
+		{"other challenge", testRP, "E4PTcIH_HfX1pC6Sigk1SC9NAlgeztN0439vi8z_c9l", publicKey, 0, "challenge mismatch"},
+		{"other origin", &RelyingParty{ID: "webauthn.io", Origins: []string{"https://evil.webauthn.io"}}, capturedAssertionChallenge, publicKey, 0, "is not allowed"},
+		{"other relying party", &RelyingParty{ID: "evil.example.com", Origins: []string{"https://webauthn.io"}}, capturedAssertionChallenge, publicKey, 0, "another relying party"},
+		{"other credential", testRP, capturedAssertionChallenge, otherKey.coseKey(), 0, "invalid signature"},
+		{"replayed sign count", testRP, capturedAssertionChallenge, publicKey, capturedAssertionSignCount, ErrSignCountRegressed.Error()},
+		{"regressed sign count", testRP, capturedAssertionChallenge, publicKey, capturedAssertionSignCount + 1, ErrSignCountRegressed.Error()},
+	}
+
+	for _, tt := range tests {
+		assertion, err := ParseAssertion(capturedAssertion)
+		if err != nil {
+			t.Fatalf("ParseAssertion: %v", err)
+		}
+		if _, err := tt.rp.VerifyAssertion(assertion, tt.challenge, tt.publicKey, tt.signCount, true); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestVerifyAssertionRefusesRegistrationClientData(t *testing.T) {
+	key := newTestKey(t)
+	assertion := key.assert(t, "webauthn.create", flagUserPresent|flagUserVerified, 1)
+	if _, err := testRP.VerifyAssertion(assertion, "challenge", key.coseKey(), 0, true); err == nil || !strings.Contains(err.Error(), "ceremony type") {
+		t.Errorf("expected a ceremony type error, got %v", err)
+	}
+}
+
+func TestVerifyAssertionFlags(t *testing.T) {
+	tests := []struct {
+		name                    string
+		flags                   byte
+		requireUserVerification bool
+		want                    string
+	}{
+		{"user present and verified", flagUserPresent | flagUserVerified, true, ""},
+		{"user present", flagUserPresent, false, ""},
+		{"backed up passkey", flagUserPresent | flagUserVerified | flagBackupEligible | flagBackupState, true, ""},
+		{"user not present", flagUserVerified, true, "user presence is required"},
+		{"user not present nor verified", 0, false, "user presence is required"},
+		{"user not verified", flagUserPresent, true, "user verification is required"},
+		{"backed up but not eligible", flagUserPresent | flagBackupState, false, "invalid backup flags"},
+	}
+
+	key := newTestKey(t)
+	for _, tt := range tests {
+		assertion := key.assert(t, "webauthn.get", tt.flags, 1)
+		_, err := testRP.VerifyAssertion(assertion, "challenge", key.coseKey(), 0, tt.requireUserVerification)
+		if tt.want == "" && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestVerifyAssertionSignCount(t *testing.T) {
+	tests := []struct {
+		name          string
+		stored, given uint32
+		regressed     bool
+	}{
+		{"increased", 5, 6, false},
+		{"first use", 0, 1, false},
+		{"authenticator without a counter", 0, 0, false},
+		{"unchanged", 5, 5, true},
+		{"decreased", 5, 4, true},
+		{"reset to zero", 5, 0, true},
+	}
+
+	key := newTestKey(t)
+	for _, tt := range tests {
+		assertion := key.assert(t, "webauthn.get", flagUserPresent|flagUserVerified, tt.given)
+		signCount, err := testRP.VerifyAssertion(assertion, "challenge", key.coseKey(), tt.stored, true)
+		if tt.regressed {
+			if !errors.Is(err, ErrSignCountRegressed) {
+				t.Errorf("%s: expected ErrSignCountRegressed, got %v", tt.name, err)
+			}
+			continue
+		}
+		if err != nil || signCount != tt.given {
+			t.Errorf("%s: got %d, %v", tt.name, signCount, err)
+		}
+	}
+}
+
+func TestVerifyAssertionRefusesTamperedData(t *testing.T) {
+	key := newTestKey(t)
+
+	assertion := key.assert(t, "webauthn.get", flagUserPresent, 1)
+	assertion.signature[len(assertion.signature)-1] ^= 0x01
+	if _, err := testRP.VerifyAssertion(assertion, "challenge", key.coseKey(), 0, false); err == nil {
+		t.Errorf("expected a tampered signature to be refused")
+	}
+
+	// The counter is covered by the signature
+	assertion = key.assert(t, "webauthn.get", flagUserPresent, 1)
+	assertion.authenticatorData[36] = 2
+	if _, err := testRP.VerifyAssertion(assertion, "challenge", key.coseKey(), 0, false); err == nil || !strings.Contains(err.Error(), "invalid signature") {
+		t.Errorf("expected a tampered counter to be refused, got %v", err)
+	}
+}
+
+func TestParseAuthenticatorDataRefusesMalformedData(t *testing.T) {
+	rpIDHash := sha256.Sum256([]byte(testRP.ID))
+	header := func(flags byte) []byte {
+		return append(append([]byte(nil), rpIDHash[:]...), flags, 0, 0, 0, 1)
+	}
+	attested := func(idLength uint16, rest ...byte) []byte {
+		data := append(header(flagUserPresent|flagAttestedData), make([]byte, 16)...)
+		data = binary.BigEndian.AppendUint16(data, idLength)
+		return append(data, rest...)
+	}
+	coseKey := newTestKey(t).coseKey()
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated", header(flagUserPresent)[:36]},
+		{"trailing data", append(header(flagUserPresent), 0x00)},
+		{"truncated attested credential data", append(header(flagUserPresent|flagAttestedData), make([]byte, 10)...)},
+		{"empty credential ID", attested(0)},
+		{"oversized credential ID", attested(maxCredentialIDSize+1, make([]byte, maxCredentialIDSize+1)...)},
+		{"credential ID longer than the data", attested(64, 1, 2, 3)},
+		{"missing public key", attested(2, 1, 2)},
+		{"truncated public key", attested(2, append([]byte{1, 2}, coseKey[:len(coseKey)-1]...)...)},
+		{"missing extensions", header(flagUserPresent | flagExtensionData)},
+		{"truncated extensions", append(header(flagUserPresent|flagExtensionData), 0xa1, 0x61)},
+	}
+
+	for _, tt := range tests {
+		if authData, err := parseAuthenticatorData(tt.data); err == nil {
+			t.Errorf("%s: expected an error, got %+v", tt.name, authData)
+		}
+	}
+
+	// Well formed attested data and extensions are accepted
+	data := attested(2, append([]byte{1, 2}, coseKey...)...)
+	data[32] |= flagExtensionData
+	data = append(data, 0xa0)
+	if authData, err := parseAuthenticatorData(data); err != nil || len(authData.credentialID) != 2 || len(authData.publicKey) != len(coseKey) {
+		t.Errorf("expected attested data with extensions to parse, got %+v, %v", authData, err)
+	}
+}
+
+func TestParsePublicKeyRefusesInvalidKeys(t *testing.T) {
+	coseKey := newTestKey(t).coseKey()
+	offCurve := append([]byte(nil), coseKey...)
+	offCurve[len(offCurve)-1] ^= 0x01
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"truncated", coseKey[:len(coseKey)-1]},
+		{"trailing data", append(append([]byte(nil), coseKey...), 0x00)},
+		{"not a map", []byte{0x80}},
+		{"point not on the curve", offCurve},
+		{"unsupported algorithm", []byte{0xa2, 0x01, 0x02, 0x03, 0x38, 0x22}}, // EC2 with ES384
+		{"short coordinates", []byte{0xa5, 0x01, 0x02, 0x03, 0x26, 0x20, 0x01, 0x21, 0x41, 0x00, 0x22, 0x41, 0x00}},
+		{"short Ed25519 key", []byte{0xa4, 0x01, 0x01, 0x03, 0x27, 0x20, 0x06, 0x21, 0x41, 0x00}},
+	}
+
+	for _, tt := range tests {
+		if key, err := ParsePublicKey(tt.data); err == nil {
+			t.Errorf("%s: expected an error, got %+v", tt.name, key)
+		}
+	}
+}
+
+// testKey is a P-256 credential key signing assertions for testRP
+type testKey struct {
+	private *ecdsa.PrivateKey
+}
+
+func newTestKey(t *testing.T) *testKey {
+	t.Helper()
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return &testKey{private: private}
+}
+
+// coseKey returns the public key as a COSE_Key {1: 2, 3: -7, -1: 1, -2: x, -3: y}
+func (k *testKey) coseKey() []byte {
+	x := k.private.PublicKey.X.FillBytes(make([]byte, 32))
+	y := k.private.PublicKey.Y.FillBytes(make([]byte, 32))
+	data := []byte{0xa5, 0x01, 0x02, 0x03, 0x26, 0x20, 0x01, 0x21, 0x58, 0x20}
+	data = append(data, x...)
+	data = append(data, 0x22, 0x58, 0x20)
+	return append(data, y...)
+}
+
+// assert returns an assertion of the ceremony for the challenge "challenge", signed with the key
+func (k *testKey) assert(t *testing.T, ceremony string, flags byte, signCount uint32) *Assertion {
+	t.Helper()
+
+	clientData := ClientData{Type: ceremony, Challenge: "challenge", Origin: testRP.Origins[0]}
+	clientDataJSON, err := json.Marshal(clientData)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	rpIDHash := sha256.Sum256([]byte(testRP.ID))
+	authData := append(append([]byte(nil), rpIDHash[:]...), flags)
+	authData = binary.BigEndian.AppendUint32(authData, signCount)
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, k.private, digest[:])
+	if err != nil {
+		t.Fatalf("SignASN1: %v", err)
+	}
+
+	return &Assertion{
+		CredentialID:      []byte{1, 2, 3, 4},
+		ClientData:        clientData,
+		clientDataJSON:    clientDataJSON,
+		authenticatorData: authData,
+		signature:         signature,
+	}
+}
@@ -8,10 +8,12 @@ import (
 	"github.com/joho/godotenv"
 	_ "github.com/vnlab/makeshop-payment/docs"
 	"github.com/vnlab/makeshop-payment/src/api"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/auth"
 	"github.com/vnlab/makeshop-payment/src/infrastructure/config"
 	"github.com/vnlab/makeshop-payment/src/infrastructure/logger"
 	"github.com/vnlab/makeshop-payment/src/infrastructure/persistence/mysql"
 	"github.com/vnlab/makeshop-payment/src/infrastructure/persistence/repositories"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/pubsub"
 )
 
 func init() {
@@ -67,9 +69,59 @@ func main() {
 	// Initialize repositories
 	userRepo := repositories.NewUserRepository(db)
 	roleRepo := repositories.NewRoleRepository(db)
+	mfaTypeRepo := repositories.NewMFATypeRepository(db)
+	totpRepo := repositories.NewUserTOTPRepository(db)
+	recoveryCodeRepo := repositories.NewRecoveryCodeRepository(db)
+	otpCodeRepo := repositories.NewOTPCodeRepository(db)
+	auditLogRepo := repositories.NewAuditLogRepository(db)
+	refreshTokenRepo := repositories.NewRefreshTokenRepository(db)
+	passwordResetTokenRepo := repositories.NewPasswordResetTokenRepository(db)
+	emailVerificationTokenRepo := repositories.NewEmailVerificationTokenRepository(db)
+	loginAttemptRepo := repositories.NewLoginAttemptRepository(db)
+	sessionRepo := repositories.NewSessionRepository(db)
+	permissionRepo := repositories.NewPermissionRepository(db)
+	apiKeyRepo := repositories.NewAPIKeyRepository(db)
+	oauthClientRepo := repositories.NewOAuthClientRepository(db)
+	userIdentityRepo := repositories.NewUserIdentityRepository(db)
+	ssoStateRepo := repositories.NewSSOStateRepository(db)
+	passkeyRepo := repositories.NewPasskeyRepository(db)
+	passkeyChallengeRepo := repositories.NewPasskeyChallengeRepository(db)
+	persistedQueryRepo := repositories.NewPersistedQueryRepository(db)
+
+	revocationStore, err := auth.NewRevocationStore(appConfig, db)
+	if err != nil {
+		log.Fatalf("Failed to initialize token revocation store: %v", err)
+	}
 
 	// Create and start API server
-	server := api.NewServer(userRepo, roleRepo)
+	server, err := api.NewServer(
+		appConfig,
+		userRepo,
+		roleRepo,
+		mfaTypeRepo,
+		totpRepo,
+		recoveryCodeRepo,
+		otpCodeRepo,
+		auditLogRepo,
+		refreshTokenRepo,
+		passwordResetTokenRepo,
+		emailVerificationTokenRepo,
+		loginAttemptRepo,
+		sessionRepo,
+		permissionRepo,
+		apiKeyRepo,
+		oauthClientRepo,
+		userIdentityRepo,
+		ssoStateRepo,
+		passkeyRepo,
+		passkeyChallengeRepo,
+		persistedQueryRepo,
+		revocationStore,
+		pubsub.NewMemoryBroker(),
+	)
+	if err != nil {
+		log.Fatalf("Failed to create server: %v", err)
+	}
 	if err := server.Start(); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
@@ -0,0 +1,449 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	apperrors "github.com/vnlab/makeshop-payment/src/infrastructure/errors"
+)
+
+// Admin user management errors
+var (
+	ErrUserNotFound        = errors.New("user not found")
+	ErrLastAdmin           = errors.New("at least one active system administrator is required")
+	ErrCannotModifySelf    = errors.New("administrators cannot disable or delete their own account")
+	ErrCannotChangeOwnRole = errors.New("administrators cannot change the role of their own account")
+	ErrAdminRoleRequired   = errors.New("only system administrators can grant or remove the system administrator role")
+)
+
+// AdminUserUsecase handles the management of user accounts by administrators
+type AdminUserUsecase struct {
+	userRepo        repositories.UserRepository
+	roleRepo        repositories.RoleRepository
+	auditLogRepo    repositories.AuditLogRepository
+	apiKeyRepo      repositories.APIKeyRepository
+	oauthClientRepo repositories.OAuthClientRepository
+	tokenUsecase    *TokenUsecase
+	mfaUsecase      *MFAUsecase
+
+	emailVerificationUsecase *EmailVerificationUsecase
+}
+
+// NewAdminUserUseCase creates a new AdminUserUsecase
+func NewAdminUserUseCase(
+	userRepo repositories.UserRepository,
+	roleRepo repositories.RoleRepository,
+	auditLogRepo repositories.AuditLogRepository,
+	apiKeyRepo repositories.APIKeyRepository,
+	oauthClientRepo repositories.OAuthClientRepository,
+	tokenUsecase *TokenUsecase,
+	mfaUsecase *MFAUsecase,
+	emailVerificationUsecase *EmailVerificationUsecase,
+) *AdminUserUsecase {
+	return &AdminUserUsecase{
+		userRepo:        userRepo,
+		roleRepo:        roleRepo,
+		auditLogRepo:    auditLogRepo,
+		apiKeyRepo:      apiKeyRepo,
+		oauthClientRepo: oauthClientRepo,
+		tokenUsecase:    tokenUsecase,
+		mfaUsecase:      mfaUsecase,
+
+		emailVerificationUsecase: emailVerificationUsecase,
+	}
+}
+
+// AdminCreateUserRequest represents a user created by an administrator
+type AdminCreateUserRequest struct {
+	Email         string
+	Password      string
+	FirstName     string
+	LastName      string
+	FirstNameKana string
+	LastNameKana  string
+	RoleID        *int // Defaults to the general user role
+}
+
+// AdminUpdateUserRequest represents changes to a user made by an administrator.
+// Nil fields are left unchanged.
+type AdminUpdateUserRequest struct {
+	RoleID        *int
+	FirstName     *string
+	LastName      *string
+	FirstNameKana *string
+	LastNameKana  *string
+	EnabledMFA    *bool
+	MFATypeID     *int
+	// RequirePasskey makes passkeys the only second factor the user can use,
+	// e.g. for staff handling payouts. The user must have registered one.
+	RequirePasskey *bool
+}
+
+// CreateUser creates a user with the given role and sends them a verification email.
+// Only system administrators may create another system administrator.
+func (uc *AdminUserUsecase) CreateUser(ctx context.Context, adminID int, req AdminCreateUserRequest) (*models.User, error) {
+	email := strings.TrimSpace(req.Email)
+	if err := ensureEmailAvailable(ctx, uc.userRepo, email); err != nil {
+		return nil, err
+	}
+
+	var role *models.Role
+	var err error
+	if req.RoleID != nil {
+		role, err = uc.roleRepo.FindByID(ctx, *req.RoleID)
+	} else {
+		role, err = uc.roleRepo.FindByCode(ctx, string(models.RoleCodeNormalUser))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, ErrRoleNotFound
+	}
+	if role.IsAdmin() {
+		if err := uc.ensureAdminCaller(ctx, adminID); err != nil {
+			return nil, err
+		}
+	}
+
+	user, err := models.NewUser(
+		email,
+		req.Password,
+		req.FirstName,
+		req.LastName,
+		req.FirstNameKana,
+		req.LastNameKana,
+		role.ID,
+	)
+	if err != nil {
+		return nil, apperrors.Validation(err.Error(), nil)
+	}
+
+	if err := uc.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	err = recordAudit(ctx, uc.auditLogRepo, models.AuditActionUserCreated, adminID, user.ID, map[string]interface{}{
+		"email":     user.Email,
+		"role_code": role.Code,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Reload user to get the role relationship
+	user, err = uc.userRepo.FindByID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.emailVerificationUsecase.SendVerification(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// UpdateUser changes the role, name, MFA settings or passkey requirement of a user.
+// A role change ends every session so the new permissions apply at once. Administrators
+// cannot change their own role, and only system administrators may grant or remove
+// the system administrator role.
+func (uc *AdminUserUsecase) UpdateUser(ctx context.Context, adminID, userID int, req AdminUpdateUserRequest) (*models.User, error) {
+	user, err := uc.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]interface{})
+
+	roleChanged := false
+	if req.RoleID != nil && *req.RoleID != user.RoleID {
+		if adminID == userID {
+			return nil, ErrCannotChangeOwnRole
+		}
+		role, err := uc.roleRepo.FindByID(ctx, *req.RoleID)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			return nil, ErrRoleNotFound
+		}
+		if role.IsAdmin() || user.IsAdmin() {
+			if err := uc.ensureAdminCaller(ctx, adminID); err != nil {
+				return nil, err
+			}
+		}
+		if !role.IsAdmin() {
+			if err := uc.ensureOtherAdmin(ctx, user); err != nil {
+				return nil, err
+			}
+		}
+
+		changes["previous_role_id"] = user.RoleID
+		changes["role_id"] = role.ID
+		user.RoleID = role.ID
+		user.Role = role
+		roleChanged = true
+	}
+
+	if req.FirstName != nil || req.LastName != nil || req.FirstNameKana != nil || req.LastNameKana != nil {
+		err := user.UpdateProfile(
+			valueOr(req.FirstName, user.FirstName),
+			valueOr(req.LastName, user.LastName),
+			valueOr(req.FirstNameKana, user.FirstNameKana),
+			valueOr(req.LastNameKana, user.LastNameKana),
+		)
+		if err != nil {
+			return nil, apperrors.Validation(err.Error(), nil)
+		}
+		changes["profile"] = true
+	}
+
+	// Applied first so MFA settings given together are checked against the new requirement
+	if req.RequirePasskey != nil && *req.RequirePasskey != user.PasskeyRequired {
+		changes["previous_passkey_required"] = user.PasskeyRequired
+		changes["previous_mfa_enabled"] = user.EnabledMFA
+		changes["previous_mfa_type_id"] = user.MFATypeID
+		if err := uc.mfaUsecase.applyPasskeyRequirement(ctx, user, *req.RequirePasskey); err != nil {
+			return nil, err
+		}
+		changes["passkey_required"] = user.PasskeyRequired
+		changes["mfa_enabled"] = user.EnabledMFA
+		changes["mfa_type_id"] = user.MFATypeID
+	}
+
+	if req.EnabledMFA != nil || req.MFATypeID != nil {
+		enabled := user.EnabledMFA
+		if req.EnabledMFA != nil {
+			enabled = *req.EnabledMFA
+		}
+		typeID := user.MFATypeID
+		if req.MFATypeID != nil {
+			typeID = req.MFATypeID
+		}
+
+		if _, ok := changes["previous_mfa_enabled"]; !ok {
+			changes["previous_mfa_enabled"] = user.EnabledMFA
+			changes["previous_mfa_type_id"] = user.MFATypeID
+		}
+		if err := uc.mfaUsecase.applyMFASettings(ctx, user, enabled, typeID); err != nil {
+			return nil, err
+		}
+		changes["mfa_enabled"] = user.EnabledMFA
+		changes["mfa_type_id"] = user.MFATypeID
+	}
+
+	if len(changes) == 0 {
+		return user, nil
+	}
+
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if roleChanged {
+		if err := uc.tokenUsecase.RevokeAllForUser(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := recordAudit(ctx, uc.auditLogRepo, models.AuditActionUserUpdated, adminID, user.ID, changes); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// DisableUser prevents a user from logging in and ends every session
+func (uc *AdminUserUsecase) DisableUser(ctx context.Context, adminID, userID int, reason string) (*models.User, error) {
+	if adminID == userID {
+		return nil, ErrCannotModifySelf
+	}
+
+	user, err := uc.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsDisabled() {
+		return user, nil
+	}
+	if err := uc.ensureOtherAdmin(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if err := uc.userRepo.SetDisabled(ctx, user.ID, timePtr(time.Now())); err != nil {
+		return nil, err
+	}
+	if err := uc.tokenUsecase.RevokeAllForUser(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	err = recordAudit(ctx, uc.auditLogRepo, models.AuditActionUserDisabled, adminID, user.ID, map[string]interface{}{
+		"reason": reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.userRepo.FindByID(ctx, user.ID)
+}
+
+// EnableUser allows a disabled user to log in again
+func (uc *AdminUserUsecase) EnableUser(ctx context.Context, adminID, userID int) (*models.User, error) {
+	user, err := uc.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsDisabled() {
+		return user, nil
+	}
+
+	if err := uc.userRepo.SetDisabled(ctx, user.ID, nil); err != nil {
+		return nil, err
+	}
+
+	err = recordAudit(ctx, uc.auditLogRepo, models.AuditActionUserEnabled, adminID, user.ID, map[string]interface{}{
+		"disabled_at": user.DisabledAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.userRepo.FindByID(ctx, user.ID)
+}
+
+// DeleteUser soft-deletes a user, ends every session and revokes their API keys and OAuth clients
+func (uc *AdminUserUsecase) DeleteUser(ctx context.Context, adminID, userID int) error {
+	if adminID == userID {
+		return ErrCannotModifySelf
+	}
+
+	user, err := uc.findUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := uc.ensureOtherAdmin(ctx, user); err != nil {
+		return err
+	}
+
+	if err := uc.userRepo.Delete(ctx, user.ID); err != nil {
+		return err
+	}
+	if err := uc.tokenUsecase.RevokeAllForUser(ctx, user.ID); err != nil {
+		return err
+	}
+
+	// Keys and clients act for the account, so they stop with it. Restoring the account
+	// does not bring them back.
+	revokedAPIKeys, err := uc.apiKeyRepo.RevokeByUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	revokedOAuthClients, err := uc.oauthClientRepo.RevokeByUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	return recordAudit(ctx, uc.auditLogRepo, models.AuditActionUserDeleted, adminID, user.ID, map[string]interface{}{
+		"email":                 user.Email,
+		"revoked_api_keys":      revokedAPIKeys,
+		"revoked_oauth_clients": revokedOAuthClients,
+	})
+}
+
+// RestoreUser restores a deleted user
+func (uc *AdminUserUsecase) RestoreUser(ctx context.Context, adminID, userID int) (*models.User, error) {
+	user, err := uc.userRepo.FindByIDIncludingDeleted(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if !user.IsDeleted() {
+		return user, nil
+	}
+
+	if err := uc.userRepo.Restore(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	err = recordAudit(ctx, uc.auditLogRepo, models.AuditActionUserRestored, adminID, user.ID, map[string]interface{}{
+		"deleted_at": user.DeletedAt.Time,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.userRepo.FindByID(ctx, user.ID)
+}
+
+// ListDeletedUsers lists deleted users that have not been purged yet, with pagination
+func (uc *AdminUserUsecase) ListDeletedUsers(ctx context.Context, page, pageSize int) ([]*models.User, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	return uc.userRepo.ListDeleted(ctx, page, pageSize)
+}
+
+// findUser loads a user that has not been deleted or returns ErrUserNotFound
+func (uc *AdminUserUsecase) findUser(ctx context.Context, userID int) (*models.User, error) {
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// ensureAdminCaller refuses the change unless the caller is a system administrator.
+// users.manage can be granted to any role, so it alone must not lead to the administrator role.
+func (uc *AdminUserUsecase) ensureAdminCaller(ctx context.Context, adminID int) error {
+	caller, err := uc.userRepo.FindByID(ctx, adminID)
+	if err != nil {
+		return err
+	}
+	if caller == nil || !caller.IsAdmin() {
+		return ErrAdminRoleRequired
+	}
+	return nil
+}
+
+// ensureOtherAdmin refuses to remove an active system administrator when no other one is left
+func (uc *AdminUserUsecase) ensureOtherAdmin(ctx context.Context, user *models.User) error {
+	if user.Role == nil || !user.Role.IsAdmin() || !user.CanLogin() {
+		return nil
+	}
+
+	count, err := uc.userRepo.CountActiveByRole(ctx, user.RoleID)
+	if err != nil {
+		return err
+	}
+	if count <= 1 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+// valueOr returns the pointed value, or fallback when the pointer is nil
+func valueOr(value *string, fallback string) string {
+	if value == nil {
+		return fallback
+	}
+	return *value
+}
+
+// timePtr returns a pointer to t
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// newAdminUserTest returns an AdminUserUsecase on the fixture repositories
+func newAdminUserTest(t *testing.T) (*AdminUserUsecase, *fixture) {
+	t.Helper()
+	f := newFixture(t)
+	uc := NewAdminUserUseCase(f.users, f.roles, f.auditLogs, nil, nil, f.tokenUsecase(), nil, nil)
+	return uc, f
+}
+
+func TestUpdateUserAdminRoleRequiresAdminCaller(t *testing.T) {
+	uc, f := newAdminUserTest(t)
+	ctx := context.Background()
+	// The role of the manager has been granted users.manage
+	manager := f.addUser(t, "manager@example.com", f.businessRole)
+	user := f.addUser(t, "jane@example.com", f.generalRole)
+	admin := f.addUser(t, "admin@example.com", f.adminRole)
+	f.addUser(t, "other-admin@example.com", f.adminRole)
+
+	if _, err := uc.UpdateUser(ctx, manager.ID, user.ID, AdminUpdateUserRequest{RoleID: &f.adminRole.ID}); !errors.Is(err, ErrAdminRoleRequired) {
+		t.Errorf("promoting another user: expected ErrAdminRoleRequired, got %v", err)
+	}
+	if _, err := uc.UpdateUser(ctx, manager.ID, admin.ID, AdminUpdateUserRequest{RoleID: &f.generalRole.ID}); !errors.Is(err, ErrAdminRoleRequired) {
+		t.Errorf("demoting an administrator: expected ErrAdminRoleRequired, got %v", err)
+	}
+	if user.RoleID != f.generalRole.ID || admin.RoleID != f.adminRole.ID {
+		t.Fatalf("roles changed to %d and %d", user.RoleID, admin.RoleID)
+	}
+
+	// Roles other than the administrator one stay open to users.manage
+	if _, err := uc.UpdateUser(ctx, manager.ID, user.ID, AdminUpdateUserRequest{RoleID: &f.businessRole.ID}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if _, err := uc.UpdateUser(ctx, admin.ID, user.ID, AdminUpdateUserRequest{RoleID: &f.adminRole.ID}); err != nil {
+		t.Fatalf("UpdateUser by an administrator: %v", err)
+	}
+	if user.RoleID != f.adminRole.ID {
+		t.Errorf("expected the administrator role, got %d", user.RoleID)
+	}
+}
+
+func TestUpdateUserRefusesOwnRoleChange(t *testing.T) {
+	uc, f := newAdminUserTest(t)
+	ctx := context.Background()
+	manager := f.addUser(t, "manager@example.com", f.businessRole)
+	admin := f.addUser(t, "admin@example.com", f.adminRole)
+	f.addUser(t, "other-admin@example.com", f.adminRole)
+
+	if _, err := uc.UpdateUser(ctx, manager.ID, manager.ID, AdminUpdateUserRequest{RoleID: &f.adminRole.ID}); !errors.Is(err, ErrCannotChangeOwnRole) {
+		t.Errorf("self-promotion: expected ErrCannotChangeOwnRole, got %v", err)
+	}
+	if _, err := uc.UpdateUser(ctx, admin.ID, admin.ID, AdminUpdateUserRequest{RoleID: &f.generalRole.ID}); !errors.Is(err, ErrCannotChangeOwnRole) {
+		t.Errorf("self-demotion: expected ErrCannotChangeOwnRole, got %v", err)
+	}
+	if manager.RoleID != f.businessRole.ID || admin.RoleID != f.adminRole.ID {
+		t.Errorf("roles changed to %d and %d", manager.RoleID, admin.RoleID)
+	}
+}
+
+func TestCreateUserAdminRoleRequiresAdminCaller(t *testing.T) {
+	uc, f := newAdminUserTest(t)
+	manager := f.addUser(t, "manager@example.com", f.businessRole)
+
+	_, err := uc.CreateUser(context.Background(), manager.ID, AdminCreateUserRequest{
+		Email:         "new-admin@example.com",
+		Password:      "Password123!",
+		FirstName:     "Jane",
+		LastName:      "Doe",
+		FirstNameKana: "ジェーン",
+		LastNameKana:  "ドウ",
+		RoleID:        &f.adminRole.ID,
+	})
+	if !errors.Is(err, ErrAdminRoleRequired) {
+		t.Errorf("expected ErrAdminRoleRequired, got %v", err)
+	}
+	if len(f.users.users) != 1 {
+		t.Errorf("the administrator was created")
+	}
+}
@@ -0,0 +1,212 @@
+package usecase
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/auth"
+	apperrors "github.com/vnlab/makeshop-payment/src/infrastructure/errors"
+)
+
+const (
+	// apiKeyPrefixBytes is the size of the public part of a key used to look it up
+	apiKeyPrefixBytes = 6
+	// apiKeySecretBytes is the entropy of the secret part of a key
+	apiKeySecretBytes = 32
+	// apiKeyTouchInterval limits how often the last-used time of a key is written
+	apiKeyTouchInterval = time.Minute
+)
+
+// API key errors
+var (
+	ErrInvalidAPIKey  = errors.New("invalid API key")
+	ErrAPIKeyNotFound = errors.New("API key not found")
+)
+
+// CreateAPIKeyRequest represents a new API key
+type CreateAPIKeyRequest struct {
+	Name       string
+	Scopes     []string
+	AllowedIPs []string   // IP addresses or CIDR ranges, empty allows any address
+	ExpiresAt  *time.Time // Nil for a key that does not expire
+}
+
+// APIKeyUsecase manages the API keys merchant backends use instead of a login
+type APIKeyUsecase struct {
+	apiKeyRepo        repositories.APIKeyRepository
+	userRepo          repositories.UserRepository
+	auditLogRepo      repositories.AuditLogRepository
+	permissionUsecase *PermissionUsecase
+}
+
+// NewAPIKeyUseCase creates a new APIKeyUsecase
+func NewAPIKeyUseCase(
+	apiKeyRepo repositories.APIKeyRepository,
+	userRepo repositories.UserRepository,
+	auditLogRepo repositories.AuditLogRepository,
+	permissionUsecase *PermissionUsecase,
+) *APIKeyUsecase {
+	return &APIKeyUsecase{
+		apiKeyRepo:        apiKeyRepo,
+		userRepo:          userRepo,
+		auditLogRepo:      auditLogRepo,
+		permissionUsecase: permissionUsecase,
+	}
+}
+
+// CreateAPIKey creates an API key for a user and returns it with the full key,
+// which is shown only once. Scopes are limited to payment permissions of the user's role.
+func (uc *APIKeyUsecase) CreateAPIKey(ctx context.Context, userID int, req CreateAPIKeyRequest) (*models.APIKey, string, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, "", apperrors.Validation("API key name is required", nil)
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, "", apperrors.Validation("API key expiry must be in the future", nil)
+	}
+	allowedIPs := make([]string, 0, len(req.AllowedIPs))
+	for _, rule := range req.AllowedIPs {
+		rule = strings.TrimSpace(rule)
+		if err := models.ValidateIPRule(rule); err != nil {
+			return nil, "", apperrors.Validation(err.Error(), nil)
+		}
+		allowedIPs = append(allowedIPs, rule)
+	}
+
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if user == nil {
+		return nil, "", ErrUserNotFound
+	}
+
+	if err := uc.permissionUsecase.ValidateScopes(ctx, user.RoleID, req.Scopes); err != nil {
+		return nil, "", err
+	}
+
+	prefix, err := auth.GenerateRandomHex(apiKeyPrefixBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := auth.GenerateRandomToken(apiKeySecretBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	key := models.APIKeyPrefix + prefix + "_" + secret
+
+	apiKey := &models.APIKey{
+		UserID:     user.ID,
+		Name:       truncate(name, 100),
+		Prefix:     prefix,
+		KeyHash:    auth.HashToken(key),
+		Scopes:     strings.Join(req.Scopes, ","),
+		AllowedIPs: strings.Join(allowedIPs, ","),
+		ExpiresAt:  req.ExpiresAt,
+	}
+	if err := uc.apiKeyRepo.Create(ctx, apiKey); err != nil {
+		return nil, "", err
+	}
+
+	err = recordAudit(ctx, uc.auditLogRepo, models.AuditActionAPIKeyCreated, user.ID, user.ID, map[string]interface{}{
+		"api_key_id": apiKey.ID,
+		"prefix":     apiKey.Prefix,
+		"scopes":     apiKey.ScopeList(),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return apiKey, key, nil
+}
+
+// ListAPIKeys lists the API keys of a user
+func (uc *APIKeyUsecase) ListAPIKeys(ctx context.Context, userID int) ([]*models.APIKey, error) {
+	return uc.apiKeyRepo.ListByUser(ctx, userID)
+}
+
+// RevokeAPIKey revokes one of the user's API keys
+func (uc *APIKeyUsecase) RevokeAPIKey(ctx context.Context, userID, id int) error {
+	apiKey, err := uc.apiKeyRepo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if apiKey == nil || apiKey.UserID != userID {
+		return ErrAPIKeyNotFound
+	}
+
+	revoked, err := uc.apiKeyRepo.Revoke(ctx, apiKey.ID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return nil
+	}
+
+	return recordAudit(ctx, uc.auditLogRepo, models.AuditActionAPIKeyRevoked, userID, apiKey.UserID, map[string]interface{}{
+		"api_key_id": apiKey.ID,
+		"prefix":     apiKey.Prefix,
+	})
+}
+
+// Authenticate checks an API key presented by a client and returns it with the permissions
+// it grants: its scopes that the owner's role still holds. Every failure returns ErrInvalidAPIKey.
+func (uc *APIKeyUsecase) Authenticate(ctx context.Context, key string) (*models.APIKey, []string, error) {
+	prefix, ok := parseAPIKey(key)
+	if !ok {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	apiKey, err := uc.apiKeyRepo.FindByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, nil, err
+	}
+	if apiKey == nil || subtle.ConstantTimeCompare([]byte(apiKey.KeyHash), []byte(auth.HashToken(key))) != 1 {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	ipAddress := ClientInfoFromContext(ctx).IPAddress
+	if !apiKey.IsActive() || !apiKey.AllowsIP(ipAddress) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	owner, err := uc.userRepo.FindByID(ctx, apiKey.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if owner == nil || !owner.CanLogin() {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	permissions, err := uc.permissionUsecase.DelegatedPermissions(ctx, owner.RoleID, apiKey.ScopeList())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if apiKey.LastUsedAt == nil || time.Since(*apiKey.LastUsedAt) >= apiKeyTouchInterval {
+		if err := uc.apiKeyRepo.Touch(ctx, apiKey.ID, ipAddress); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return apiKey, permissions, nil
+}
+
+// parseAPIKey returns the prefix of a key in the "msp_<prefix>_<secret>" format
+func parseAPIKey(key string) (string, bool) {
+	if !strings.HasPrefix(key, models.APIKeyPrefix) {
+		return "", false
+	}
+	rest := strings.TrimPrefix(key, models.APIKeyPrefix)
+
+	prefixLength := apiKeyPrefixBytes * 2
+	if len(rest) <= prefixLength+1 || rest[prefixLength] != '_' {
+		return "", false
+	}
+	return rest[:prefixLength], true
+}
@@ -0,0 +1,50 @@
+package usecase
+
+import (
+	"context"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+)
+
+// recordAudit stores an audit log entry enriched with the client of the current request
+func recordAudit(
+	ctx context.Context,
+	auditLogRepo repositories.AuditLogRepository,
+	action string,
+	actorUserID, targetUserID int,
+	metadata map[string]interface{},
+) error {
+	return recordAuditEntry(ctx, auditLogRepo, action, &actorUserID, &targetUserID, metadata)
+}
+
+// recordAuditEntry is recordAudit for changes that do not target a user
+func recordAuditEntry(
+	ctx context.Context,
+	auditLogRepo repositories.AuditLogRepository,
+	action string,
+	actorUserID, targetUserID *int,
+	metadata map[string]interface{},
+) error {
+	auditLog, err := models.NewAuditLog(action, actorUserID, targetUserID, metadata)
+	if err != nil {
+		return err
+	}
+
+	client := ClientInfoFromContext(ctx)
+	auditLog.IPAddress = client.IPAddress
+	auditLog.UserAgent = truncate(client.UserAgent, 255)
+
+	return auditLogRepo.Create(ctx, auditLog)
+}
+
+// truncate shortens s to at most n bytes without splitting a UTF-8 sequence
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && s[n]&0xC0 == 0x80 {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,25 @@
+package usecase
+
+import (
+	"context"
+)
+
+// ClientInfo describes the client that issued the current request
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+}
+
+// clientInfoKey is the context key for ClientInfo
+type clientInfoKey struct{}
+
+// WithClientInfo returns a context carrying the client information
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+// ClientInfoFromContext returns the client information of the current request, if any
+func ClientInfoFromContext(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info
+}
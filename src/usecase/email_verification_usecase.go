@@ -0,0 +1,269 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/auth"
+	apperrors "github.com/vnlab/makeshop-payment/src/infrastructure/errors"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/mail"
+)
+
+// emailVerificationTokenBytes is the entropy of an email verification token
+const emailVerificationTokenBytes = 32
+
+// Email verification errors
+var (
+	ErrInvalidEmailVerificationToken = errors.New("invalid or expired email verification token")
+	ErrEmailNotVerified              = errors.New("email address is not verified")
+	ErrEmailVerificationTooMany      = errors.New("too many verification emails, please try again later")
+)
+
+// EmailVerificationConfig holds the settings of email verification
+type EmailVerificationConfig struct {
+	Required   bool          // Refuse logins until the email address is verified
+	TokenTTL   time.Duration // Lifetime of a verification link
+	VerifyURL  string        // Front-end page receiving the token
+	MaxPerHour int           // Maximum verification emails sent to the same user per hour
+}
+
+// EmailVerificationUsecase handles verification of email addresses and changes of email
+type EmailVerificationUsecase struct {
+	userRepo      repositories.UserRepository
+	tokenRepo     repositories.EmailVerificationTokenRepository
+	auditLogRepo  repositories.AuditLogRepository
+	mailer        mail.Mailer
+	mailTemplates *mail.TemplateRenderer
+	config        EmailVerificationConfig
+}
+
+// NewEmailVerificationUseCase creates a new EmailVerificationUsecase
+func NewEmailVerificationUseCase(
+	userRepo repositories.UserRepository,
+	tokenRepo repositories.EmailVerificationTokenRepository,
+	auditLogRepo repositories.AuditLogRepository,
+	mailer mail.Mailer,
+	mailTemplates *mail.TemplateRenderer,
+	config EmailVerificationConfig,
+) *EmailVerificationUsecase {
+	return &EmailVerificationUsecase{
+		userRepo:      userRepo,
+		tokenRepo:     tokenRepo,
+		auditLogRepo:  auditLogRepo,
+		mailer:        mailer,
+		mailTemplates: mailTemplates,
+		config:        config,
+	}
+}
+
+// RequiresVerification checks if the user must verify their email address before logging in
+func (uc *EmailVerificationUsecase) RequiresVerification(user *models.User) bool {
+	return uc.config.Required && !user.IsEmailVerified()
+}
+
+// SendVerification emails a verification link for the user's current address
+func (uc *EmailVerificationUsecase) SendVerification(ctx context.Context, user *models.User) error {
+	if user.IsEmailVerified() {
+		return nil
+	}
+
+	token, err := uc.issueToken(ctx, user.ID, user.Email, models.EmailVerificationPurposeVerify)
+	if err != nil {
+		return err
+	}
+
+	msg, err := uc.mailTemplates.Message(user.Email, mail.TemplateEmailVerification, map[string]interface{}{
+		"LastName":       user.LastName,
+		"FirstName":      user.FirstName,
+		"VerifyURL":      uc.verifyURL(token),
+		"ExpiresInHours": int(uc.config.TokenTTL.Hours()),
+	})
+	if err != nil {
+		return err
+	}
+
+	sendMailInBackground(ctx, uc.mailer, msg)
+	return nil
+}
+
+// ResendVerification sends a new verification link to an unverified account.
+// It behaves the same whether or not the email exists so accounts cannot be enumerated.
+func (uc *EmailVerificationUsecase) ResendVerification(ctx context.Context, email string) error {
+	user, err := uc.userRepo.FindByEmail(ctx, strings.TrimSpace(email))
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+
+	err = uc.SendVerification(ctx, user)
+	if errors.Is(err, ErrEmailVerificationTooMany) {
+		return nil
+	}
+	return err
+}
+
+// RequestEmailChange sends a confirmation link to a new address and a notice to the current one.
+// The address is only switched once the link is opened.
+func (uc *EmailVerificationUsecase) RequestEmailChange(ctx context.Context, userID int, newEmail, currentPassword string) error {
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	if !user.VerifyPassword(currentPassword) {
+		return apperrors.InvalidCredentials("current password is incorrect")
+	}
+
+	newEmail = strings.TrimSpace(newEmail)
+	if err := models.ValidateEmail(newEmail); err != nil {
+		return apperrors.Validation(err.Error(), nil)
+	}
+	if strings.EqualFold(newEmail, user.Email) {
+		return apperrors.Validation("new email is the same as the current email", nil)
+	}
+
+	if err := ensureEmailAvailable(ctx, uc.userRepo, newEmail); err != nil {
+		return err
+	}
+
+	token, err := uc.issueToken(ctx, user.ID, newEmail, models.EmailVerificationPurposeChange)
+	if err != nil {
+		return err
+	}
+
+	data := map[string]interface{}{
+		"LastName":       user.LastName,
+		"FirstName":      user.FirstName,
+		"NewEmail":       newEmail,
+		"VerifyURL":      uc.verifyURL(token),
+		"ExpiresInHours": int(uc.config.TokenTTL.Hours()),
+	}
+
+	confirmation, err := uc.mailTemplates.Message(newEmail, mail.TemplateEmailChangeConfirmation, data)
+	if err != nil {
+		return err
+	}
+	notice, err := uc.mailTemplates.Message(user.Email, mail.TemplateEmailChangeNotice, data)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.mailer.Send(ctx, confirmation); err != nil {
+		return err
+	}
+	// The current owner is warned even if someone else started the change
+	if err := uc.mailer.Send(ctx, notice); err != nil {
+		return err
+	}
+	return nil
+}
+
+// VerifyEmail consumes a verification token. Tokens sent after registration mark the
+// current address as verified; tokens sent by RequestEmailChange switch to the new address.
+func (uc *EmailVerificationUsecase) VerifyEmail(ctx context.Context, token string) (*models.User, error) {
+	verificationToken, err := uc.tokenRepo.FindByHash(ctx, auth.HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if verificationToken == nil || verificationToken.IsUsed() || verificationToken.IsExpired() {
+		return nil, ErrInvalidEmailVerificationToken
+	}
+
+	user, err := uc.userRepo.FindByID(ctx, verificationToken.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidEmailVerificationToken
+	}
+
+	previousEmail := user.Email
+	switch verificationToken.Purpose {
+	case models.EmailVerificationPurposeVerify:
+		// The address may have changed since the link was sent
+		if verificationToken.Email != user.Email {
+			return nil, ErrInvalidEmailVerificationToken
+		}
+		user.MarkEmailVerified()
+	case models.EmailVerificationPurposeChange:
+		if err := ensureEmailAvailable(ctx, uc.userRepo, verificationToken.Email); err != nil {
+			return nil, err
+		}
+		if err := user.ChangeEmail(verificationToken.Email); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrInvalidEmailVerificationToken
+	}
+
+	used, err := uc.tokenRepo.MarkUsed(ctx, verificationToken.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, ErrInvalidEmailVerificationToken
+	}
+
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if verificationToken.Purpose == models.EmailVerificationPurposeChange {
+		err = recordAudit(ctx, uc.auditLogRepo, models.AuditActionEmailChanged, user.ID, user.ID, map[string]interface{}{
+			"previous_email": previousEmail,
+			"email":          user.Email,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// issueToken stores a new verification token for an address, subject to the hourly limit
+func (uc *EmailVerificationUsecase) issueToken(ctx context.Context, userID int, email, purpose string) (string, error) {
+	count, err := uc.tokenRepo.CountCreatedSince(ctx, userID, time.Now().Add(-time.Hour))
+	if err != nil {
+		return "", err
+	}
+	if count >= int64(uc.config.MaxPerHour) {
+		return "", ErrEmailVerificationTooMany
+	}
+
+	token, err := auth.GenerateRandomToken(emailVerificationTokenBytes)
+	if err != nil {
+		return "", err
+	}
+
+	err = uc.tokenRepo.Create(ctx, &models.EmailVerificationToken{
+		UserID:    userID,
+		Email:     email,
+		Purpose:   purpose,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(uc.config.TokenTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// verifyURL builds the link to the verification page carrying the token
+func (uc *EmailVerificationUsecase) verifyURL(token string) string {
+	separator := "?"
+	if strings.Contains(uc.config.VerifyURL, "?") {
+		separator = "&"
+	}
+	return uc.config.VerifyURL + separator + "token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,301 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/auth"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/config"
+)
+
+// fixture holds the in-memory repositories the use case tests are built on, with an
+// administrator, a general and a business role. The fakes embed the repository interface
+// so that calling a method a test is not expected to use panics.
+type fixture struct {
+	users         *fakeUserRepo
+	roles         *fakeRoleRepo
+	auditLogs     *fakeAuditLogRepo
+	attempts      *fakeLoginAttemptRepo
+	sessions      *fakeSessionRepo
+	refreshTokens *fakeRefreshTokenRepo
+	totp          *fakeTOTPRepo
+	jwtService    *auth.JWTService
+
+	adminRole    *models.Role
+	generalRole  *models.Role
+	businessRole *models.Role
+}
+
+// newFixture returns empty repositories and a JWT service signing with a fresh key
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	key, err := auth.GenerateSigningKey(auth.NewKeyID("ES256", time.Now()), "ES256")
+	if err != nil {
+		t.Fatalf("GenerateSigningKey: %v", err)
+	}
+	keyRing, err := auth.NewKeyRing(key)
+	if err != nil {
+		t.Fatalf("NewKeyRing: %v", err)
+	}
+
+	f := &fixture{
+		auditLogs:     &fakeAuditLogRepo{},
+		attempts:      &fakeLoginAttemptRepo{},
+		sessions:      &fakeSessionRepo{},
+		refreshTokens: &fakeRefreshTokenRepo{},
+		totp:          &fakeTOTPRepo{},
+		jwtService:    auth.NewJWTService(&config.Config{}, keyRing, auth.NewMemoryRevocationStore()),
+		adminRole:     &models.Role{ID: 1, Code: string(models.RoleCodeAdmin)},
+		generalRole:   &models.Role{ID: 2, Code: string(models.RoleCodeNormalUser)},
+		businessRole:  &models.Role{ID: 3, Code: string(models.RoleCodeBusinessUser)},
+	}
+	f.roles = &fakeRoleRepo{roles: []*models.Role{f.adminRole, f.generalRole, f.businessRole}}
+	f.users = &fakeUserRepo{roles: f.roles, users: make(map[int]*models.User)}
+	return f
+}
+
+// addUser stores an existing user of the application with the password Password123!
+func (f *fixture) addUser(t *testing.T, email string, role *models.Role) *models.User {
+	t.Helper()
+	user, err := models.NewUser(email, "Password123!", "Jane", "Doe", "ジェーン", "ドウ", role.ID)
+	if err != nil {
+		t.Fatalf("NewUser: %v", err)
+	}
+	if err := f.users.Create(context.Background(), user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return user
+}
+
+// tokenUsecase returns a TokenUsecase issuing tokens on the fixture repositories
+func (f *fixture) tokenUsecase() *TokenUsecase {
+	return NewTokenUseCase(f.users, f.refreshTokens, f.sessions, f.auditLogs, f.jwtService, time.Hour)
+}
+
+// loginAttemptUsecase returns a LoginAttemptUsecase recording attempts in the fixture
+func (f *fixture) loginAttemptUsecase(config LoginProtectionConfig) *LoginAttemptUsecase {
+	return NewLoginAttemptUseCase(f.users, f.attempts, f.auditLogs, config)
+}
+
+// lastFailure returns the failure reason of the last recorded login attempt
+func (f *fixture) lastFailure() string {
+	if len(f.attempts.attempts) == 0 {
+		return ""
+	}
+	return f.attempts.attempts[len(f.attempts.attempts)-1].FailureReason
+}
+
+type fakeUserRepo struct {
+	repositories.UserRepository
+	roles *fakeRoleRepo
+	users map[int]*models.User
+}
+
+func (r *fakeUserRepo) FindByID(ctx context.Context, id int) (*models.User, error) {
+	user, found := r.users[id]
+	if !found || user.IsDeleted() {
+		return nil, nil
+	}
+	user.Role = r.roles.byID(user.RoleID)
+	return user, nil
+}
+
+func (r *fakeUserRepo) CountActiveByRole(ctx context.Context, roleID int) (int64, error) {
+	var count int64
+	for _, user := range r.users {
+		if user.RoleID == roleID && !user.IsDeleted() && user.CanLogin() {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *fakeUserRepo) FindByEmailIncludingDeleted(ctx context.Context, email string) (*models.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			user.Role = r.roles.byID(user.RoleID)
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeUserRepo) IncrementFailedLogins(ctx context.Context, id int) (int, error) {
+	r.users[id].FailedLoginAttempts++
+	return r.users[id].FailedLoginAttempts, nil
+}
+
+func (r *fakeUserRepo) Lock(ctx context.Context, id int, until time.Time) error {
+	r.users[id].LockedUntil = &until
+	r.users[id].LockoutCount++
+	r.users[id].FailedLoginAttempts = 0
+	return nil
+}
+
+func (r *fakeUserRepo) Create(ctx context.Context, user *models.User) error {
+	user.ID = len(r.users) + 1
+	r.users[user.ID] = user
+	return nil
+}
+
+func (r *fakeUserRepo) Update(ctx context.Context, user *models.User) error {
+	r.users[user.ID] = user
+	return nil
+}
+
+type fakeRoleRepo struct {
+	repositories.RoleRepository
+	roles []*models.Role
+}
+
+func (r *fakeRoleRepo) FindByID(ctx context.Context, id int) (*models.Role, error) {
+	return r.byID(id), nil
+}
+
+func (r *fakeRoleRepo) FindByCode(ctx context.Context, code string) (*models.Role, error) {
+	for _, role := range r.roles {
+		if role.Code == code {
+			return role, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRoleRepo) byID(id int) *models.Role {
+	for _, role := range r.roles {
+		if role.ID == id {
+			return role
+		}
+	}
+	return nil
+}
+
+type fakeIdentityRepo struct {
+	repositories.UserIdentityRepository
+	identities []*models.UserIdentity
+}
+
+func (r *fakeIdentityRepo) Create(ctx context.Context, identity *models.UserIdentity) error {
+	identity.ID = len(r.identities) + 1
+	r.identities = append(r.identities, identity)
+	return nil
+}
+
+func (r *fakeIdentityRepo) FindByIssuerAndSubject(ctx context.Context, issuer, subject string) (*models.UserIdentity, error) {
+	for _, identity := range r.identities {
+		if identity.Issuer == issuer && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeIdentityRepo) TouchLastLogin(ctx context.Context, id int) error {
+	now := time.Now()
+	r.identities[id-1].LastLoginAt = &now
+	return nil
+}
+
+type fakeSSOStateRepo struct {
+	repositories.SSOStateRepository
+	states []*models.SSOState
+}
+
+func (r *fakeSSOStateRepo) Create(ctx context.Context, state *models.SSOState) error {
+	state.ID = len(r.states) + 1
+	r.states = append(r.states, state)
+	return nil
+}
+
+func (r *fakeSSOStateRepo) FindByHash(ctx context.Context, stateHash string) (*models.SSOState, error) {
+	for _, state := range r.states {
+		if state.StateHash == stateHash {
+			return state, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeSSOStateRepo) MarkUsed(ctx context.Context, id int) (bool, error) {
+	state := r.states[id-1]
+	if state.UsedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	state.UsedAt = &now
+	return true, nil
+}
+
+type fakeAuditLogRepo struct {
+	repositories.AuditLogRepository
+	actions []string
+}
+
+func (r *fakeAuditLogRepo) Create(ctx context.Context, auditLog *models.AuditLog) error {
+	r.actions = append(r.actions, auditLog.Action)
+	return nil
+}
+
+type fakeLoginAttemptRepo struct {
+	repositories.LoginAttemptRepository
+	attempts []*models.LoginAttempt
+}
+
+func (r *fakeLoginAttemptRepo) Create(ctx context.Context, attempt *models.LoginAttempt) error {
+	r.attempts = append(r.attempts, attempt)
+	return nil
+}
+
+type fakeSessionRepo struct {
+	repositories.SessionRepository
+	created        int
+	revokedForUser []int
+}
+
+func (r *fakeSessionRepo) Create(ctx context.Context, session *models.Session) error {
+	r.created++
+	return nil
+}
+
+func (r *fakeSessionRepo) RevokeAllForUser(ctx context.Context, userID int, exceptID string) ([]string, error) {
+	r.revokedForUser = append(r.revokedForUser, userID)
+	return nil, nil
+}
+
+type fakeRefreshTokenRepo struct {
+	repositories.RefreshTokenRepository
+}
+
+func (r *fakeRefreshTokenRepo) Create(ctx context.Context, token *models.RefreshToken) error {
+	return nil
+}
+
+func (r *fakeRefreshTokenRepo) RevokeAllForUser(ctx context.Context, userID int) error {
+	return nil
+}
+
+// fakeTOTPRepo keeps a single TOTP secret in memory
+type fakeTOTPRepo struct {
+	repositories.UserTOTPRepository
+	record *models.UserTOTP
+}
+
+func (r *fakeTOTPRepo) FindByUserID(ctx context.Context, userID int) (*models.UserTOTP, error) {
+	if r.record == nil || r.record.UserID != userID {
+		return nil, nil
+	}
+	return r.record, nil
+}
+
+// UpdateLastUsedStep has the semantics of the SQL update guarded by last_used_step < step
+func (r *fakeTOTPRepo) UpdateLastUsedStep(ctx context.Context, id int, step int64) (bool, error) {
+	if r.record == nil || r.record.ID != id || r.record.LastUsedStep >= step {
+		return false, nil
+	}
+	r.record.LastUsedStep = step
+	return true, nil
+}
@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+)
+
+// Impersonation errors
+var (
+	ErrImpersonationNotAllowed = errors.New("this user cannot be impersonated")
+	ErrImpersonationReason     = errors.New("a reason is required to impersonate a user")
+)
+
+// ImpersonationUsecase lets administrators act as a user to reproduce their problems.
+// Everything done while impersonating is recorded in the audit log.
+type ImpersonationUsecase struct {
+	userRepo     repositories.UserRepository
+	auditLogRepo repositories.AuditLogRepository
+	tokenUsecase *TokenUsecase
+	tokenTTL     time.Duration
+}
+
+// NewImpersonationUseCase creates a new ImpersonationUsecase
+func NewImpersonationUseCase(
+	userRepo repositories.UserRepository,
+	auditLogRepo repositories.AuditLogRepository,
+	tokenUsecase *TokenUsecase,
+	tokenTTL time.Duration,
+) *ImpersonationUsecase {
+	return &ImpersonationUsecase{
+		userRepo:     userRepo,
+		auditLogRepo: auditLogRepo,
+		tokenUsecase: tokenUsecase,
+		tokenTTL:     tokenTTL,
+	}
+}
+
+// ImpersonateUser issues a short-lived access token with which the administrator acts as the user.
+// Other administrators and accounts that cannot log in cannot be impersonated.
+func (uc *ImpersonationUsecase) ImpersonateUser(ctx context.Context, adminID, userID int, reason string) (*LoginResponse, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrImpersonationReason
+	}
+	if adminID == userID {
+		return nil, ErrImpersonationNotAllowed
+	}
+
+	admin, err := uc.userRepo.FindByID(ctx, adminID)
+	if err != nil {
+		return nil, err
+	}
+	if admin == nil {
+		return nil, ErrUserNotFound
+	}
+
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if !user.CanLogin() || (user.Role != nil && user.Role.IsAdmin()) {
+		return nil, ErrImpersonationNotAllowed
+	}
+
+	resp, err := uc.tokenUsecase.IssueImpersonationToken(ctx, user, admin, uc.tokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordAudit(ctx, uc.auditLogRepo, models.AuditActionImpersonationStarted, admin.ID, user.ID, map[string]interface{}{
+		"reason":     reason,
+		"expires_at": resp.ExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// RecordRequest records a GraphQL operation made by an administrator acting as a user
+func (uc *ImpersonationUsecase) RecordRequest(ctx context.Context, actorID, userID int, sessionID, operation, operationName string, fields []string) error {
+	return recordAudit(ctx, uc.auditLogRepo, models.AuditActionImpersonatedRequest, actorID, userID, map[string]interface{}{
+		"session_id":     sessionID,
+		"operation":      operation,
+		"operation_name": operationName,
+		"fields":         fields,
+	})
+}
@@ -0,0 +1,169 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	apperrors "github.com/vnlab/makeshop-payment/src/infrastructure/errors"
+)
+
+// LoginProtectionConfig holds the brute-force protection thresholds
+type LoginProtectionConfig struct {
+	MaxFailedAttempts int           // Consecutive failed logins before an account is locked
+	LockoutDuration   time.Duration // First lockout duration, doubled for each further lockout
+	MaxLockoutTime    time.Duration // Upper bound of the lockout duration
+	IPMaxFailures     int           // Failed logins allowed from one IP address per window
+	IPWindow          time.Duration // Length of the per-IP throttling window
+}
+
+// LoginAttemptUsecase records login attempts, locks accounts and throttles IP addresses
+type LoginAttemptUsecase struct {
+	userRepo         repositories.UserRepository
+	loginAttemptRepo repositories.LoginAttemptRepository
+	auditLogRepo     repositories.AuditLogRepository
+	config           LoginProtectionConfig
+}
+
+// NewLoginAttemptUseCase creates a new LoginAttemptUsecase
+func NewLoginAttemptUseCase(
+	userRepo repositories.UserRepository,
+	loginAttemptRepo repositories.LoginAttemptRepository,
+	auditLogRepo repositories.AuditLogRepository,
+	config LoginProtectionConfig,
+) *LoginAttemptUsecase {
+	return &LoginAttemptUsecase{
+		userRepo:         userRepo,
+		loginAttemptRepo: loginAttemptRepo,
+		auditLogRepo:     auditLogRepo,
+		config:           config,
+	}
+}
+
+// CheckIP refuses the request when too many logins failed from the client's IP address
+func (uc *LoginAttemptUsecase) CheckIP(ctx context.Context, email string) error {
+	ipAddress := ClientInfoFromContext(ctx).IPAddress
+	if ipAddress == "" || uc.config.IPMaxFailures <= 0 {
+		return nil
+	}
+
+	count, err := uc.loginAttemptRepo.CountFailedByIPSince(ctx, ipAddress, time.Now().Add(-uc.config.IPWindow))
+	if err != nil {
+		return err
+	}
+	if count < int64(uc.config.IPMaxFailures) {
+		return nil
+	}
+
+	if err := uc.record(ctx, email, nil, false, models.LoginFailureIPThrottled); err != nil {
+		return err
+	}
+	return apperrors.TooManyRequests("Too many failed login attempts, please try again later")
+}
+
+// RecordFailure records a failed login and locks the account once the threshold is reached.
+// Each new lockout of the same account lasts twice as long as the previous one.
+func (uc *LoginAttemptUsecase) RecordFailure(ctx context.Context, email string, user *models.User, reason string) error {
+	var userID *int
+	if user != nil {
+		userID = &user.ID
+	}
+	if err := uc.record(ctx, email, userID, false, reason); err != nil {
+		return err
+	}
+
+	// Attempts on unknown, already locked or disabled accounts do not move the counters, nor do
+	// refused single sign-ons as no credential of this application was guessed, nor logins
+	// refused for an unverified email as the credentials were right
+	if user == nil || reason == models.LoginFailureAccountLocked || reason == models.LoginFailureAccountDisabled ||
+		reason == models.LoginFailureSSODenied || reason == models.LoginFailureEmailUnverified ||
+		uc.config.MaxFailedAttempts <= 0 {
+		return nil
+	}
+
+	failures, err := uc.userRepo.IncrementFailedLogins(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if failures < uc.config.MaxFailedAttempts {
+		return nil
+	}
+
+	return uc.userRepo.Lock(ctx, user.ID, time.Now().Add(uc.lockoutDuration(user.LockoutCount)))
+}
+
+// RecordSuccess records a successful login and clears the failed login counter
+func (uc *LoginAttemptUsecase) RecordSuccess(ctx context.Context, user *models.User) error {
+	if err := uc.record(ctx, user.Email, &user.ID, true, ""); err != nil {
+		return err
+	}
+
+	if user.FailedLoginAttempts == 0 && user.LockoutCount == 0 && user.LockedUntil == nil {
+		return nil
+	}
+	return uc.userRepo.ResetLoginFailures(ctx, user.ID)
+}
+
+// UnlockUser lifts the lockout of an account and resets its counters
+func (uc *LoginAttemptUsecase) UnlockUser(ctx context.Context, adminID, userID int) (*models.User, error) {
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	if err := uc.userRepo.ResetLoginFailures(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	err = recordAudit(ctx, uc.auditLogRepo, models.AuditActionUserUnlocked, adminID, user.ID, map[string]interface{}{
+		"failed_login_attempts": user.FailedLoginAttempts,
+		"locked_until":          user.LockedUntil,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.userRepo.FindByID(ctx, user.ID)
+}
+
+// ListAttempts lists login attempts matching the filter with pagination
+func (uc *LoginAttemptUsecase) ListAttempts(ctx context.Context, filter models.LoginAttemptFilter, page, pageSize int) ([]*models.LoginAttempt, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	return uc.loginAttemptRepo.List(ctx, filter, page, pageSize)
+}
+
+// lockoutDuration returns the duration of the next lockout after previous lockouts
+func (uc *LoginAttemptUsecase) lockoutDuration(previousLockouts int) time.Duration {
+	duration := uc.config.LockoutDuration
+	for i := 0; i < previousLockouts && duration < uc.config.MaxLockoutTime; i++ {
+		duration *= 2
+	}
+	if uc.config.MaxLockoutTime > 0 && duration > uc.config.MaxLockoutTime {
+		duration = uc.config.MaxLockoutTime
+	}
+	return duration
+}
+
+// record stores a login attempt enriched with the client of the current request
+func (uc *LoginAttemptUsecase) record(ctx context.Context, email string, userID *int, success bool, reason string) error {
+	client := ClientInfoFromContext(ctx)
+	return uc.loginAttemptRepo.Create(ctx, &models.LoginAttempt{
+		UserID:        userID,
+		Email:         truncate(strings.TrimSpace(email), 255),
+		IPAddress:     client.IPAddress,
+		UserAgent:     truncate(client.UserAgent, 255),
+		Success:       success,
+		FailureReason: reason,
+	})
+}
@@ -0,0 +1,50 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+)
+
+func TestRecordFailureLocksAfterBadPasswords(t *testing.T) {
+	f := newFixture(t)
+	uc := f.loginAttemptUsecase(LoginProtectionConfig{MaxFailedAttempts: 3, LockoutDuration: time.Minute})
+	user := f.addUser(t, "jane@example.com", f.generalRole)
+
+	for i := 0; i < 3; i++ {
+		if err := uc.RecordFailure(context.Background(), user.Email, user, models.LoginFailureBadPassword); err != nil {
+			t.Fatalf("RecordFailure: %v", err)
+		}
+	}
+	if !user.IsLocked() {
+		t.Errorf("expected the account to be locked after 3 bad passwords")
+	}
+}
+
+func TestRecordFailureSkipsFailuresWithoutGuessedCredentials(t *testing.T) {
+	reasons := []string{
+		models.LoginFailureEmailUnverified,
+		models.LoginFailureAccountLocked,
+		models.LoginFailureAccountDisabled,
+		models.LoginFailureSSODenied,
+	}
+	for _, reason := range reasons {
+		f := newFixture(t)
+		uc := f.loginAttemptUsecase(LoginProtectionConfig{MaxFailedAttempts: 3, LockoutDuration: time.Minute})
+		user := f.addUser(t, "jane@example.com", f.generalRole)
+
+		for i := 0; i < 5; i++ {
+			if err := uc.RecordFailure(context.Background(), user.Email, user, reason); err != nil {
+				t.Fatalf("%s: RecordFailure: %v", reason, err)
+			}
+		}
+		if user.FailedLoginAttempts != 0 || user.IsLocked() {
+			t.Errorf("%s: moved the counters to %d failures, locked %v", reason, user.FailedLoginAttempts, user.IsLocked())
+		}
+		if f.lastFailure() != reason {
+			t.Errorf("%s: expected the attempt to be recorded, got %q", reason, f.lastFailure())
+		}
+	}
+}
@@ -0,0 +1,485 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/auth"
+	apperrors "github.com/vnlab/makeshop-payment/src/infrastructure/errors"
+	"github.com/vnlab/makeshop-payment/src/lib/webauthn"
+)
+
+const (
+	// passkeyUserHandleSize is the length of the random handle identifying a user to authenticators
+	passkeyUserHandleSize = 32
+	// passkeyNameMaxLength is the longest name a passkey can be given
+	passkeyNameMaxLength = 100
+)
+
+// Passkey errors
+var (
+	ErrInvalidPasskey  = errors.New("the passkey could not be verified, please try again")
+	ErrPasskeyRequired = errors.New("this account must use a passkey as its second factor")
+)
+
+// RegisterPasskeyRequest represents a passkey created by the browser.
+// Users who already have MFA enabled must re-authenticate with MFACode.
+type RegisterPasskeyRequest struct {
+	Credential      string // RegistrationResponseJSON of navigator.credentials.create()
+	Name            string // Defaults to "Passkey <n>"
+	CurrentPassword string
+	MFACode         string
+}
+
+// BeginPasskeyRegistration returns the options to pass to navigator.credentials.create()
+// to add a passkey to the user's account
+func (uc *MFAUsecase) BeginPasskeyRegistration(ctx context.Context, userID int) (*webauthn.CreationOptions, error) {
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if _, err := uc.passkeyType(ctx); err != nil {
+		return nil, err
+	}
+
+	passkeys, err := uc.passkeyRepo.ListByUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every passkey of a user shares the handle so authenticators keep one entry per account
+	var userHandle []byte
+	exclude := make([]webauthn.CredentialDescriptor, 0, len(passkeys))
+	for _, passkey := range passkeys {
+		userHandle = passkey.UserHandle
+		exclude = append(exclude, passkeyDescriptor(passkey))
+	}
+	if userHandle == nil {
+		userHandle = make([]byte, passkeyUserHandleSize)
+		if _, err := rand.Read(userHandle); err != nil {
+			return nil, err
+		}
+	}
+
+	challenge, err := uc.createPasskeyChallenge(ctx, models.PasskeyPurposeRegistration, &user.ID, userHandle)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.relyingParty.CreationOptions(challenge, webauthn.UserEntity{
+		ID:          webauthn.EncodeID(userHandle),
+		Name:        user.Email,
+		DisplayName: strings.TrimSpace(user.LastName + " " + user.FirstName),
+	}, exclude), nil
+}
+
+// FinishPasskeyRegistration verifies and stores a passkey created with the options of
+// BeginPasskeyRegistration. It becomes the user's second factor unless another one is active.
+func (uc *MFAUsecase) FinishPasskeyRegistration(ctx context.Context, userID int, req RegisterPasskeyRequest) (*models.Passkey, error) {
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	passkeyType, err := uc.passkeyType(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Otherwise a stolen session could add a passkey and pass the second factor from then on
+	reauthMethod := ""
+	if user.RequiresMFA() {
+		if reauthMethod, err = uc.reauthenticate(ctx, user, req.CurrentPassword, req.MFACode); err != nil {
+			return nil, err
+		}
+	}
+
+	registration, err := webauthn.ParseRegistration(req.Credential)
+	if err != nil {
+		return nil, ErrInvalidPasskey
+	}
+	challenge, err := uc.consumePasskeyChallenge(ctx, registration.ClientData.Challenge, models.PasskeyPurposeRegistration, &user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if challenge == nil {
+		return nil, ErrInvalidPasskey
+	}
+
+	credential, err := uc.relyingParty.VerifyRegistration(registration, registration.ClientData.Challenge, false)
+	if err != nil {
+		log.Printf("Refused passkey registration of user %d: %v", user.ID, err)
+		return nil, ErrInvalidPasskey
+	}
+
+	existing, err := uc.passkeyRepo.FindByCredentialID(ctx, credential.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, apperrors.BadRequest("this passkey is already registered", nil)
+	}
+
+	passkeys, err := uc.passkeyRepo.ListByUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	name := truncate(strings.TrimSpace(req.Name), passkeyNameMaxLength)
+	if name == "" {
+		name = fmt.Sprintf("Passkey %d", len(passkeys)+1)
+	}
+
+	passkey := &models.Passkey{
+		UserID:         user.ID,
+		Name:           name,
+		CredentialID:   credential.ID,
+		UserHandle:     challenge.UserHandle,
+		PublicKey:      credential.PublicKey,
+		SignCount:      credential.SignCount,
+		Transports:     truncate(strings.Join(credential.Transports, ","), 255),
+		BackupEligible: credential.BackupEligible,
+	}
+	if err := uc.passkeyRepo.Create(ctx, passkey); err != nil {
+		return nil, err
+	}
+
+	err = recordAudit(ctx, uc.auditLogRepo, models.AuditActionPasskeyRegistered, user.ID, user.ID, map[string]interface{}{
+		"passkey_id":      passkey.ID,
+		"name":            passkey.Name,
+		"backup_eligible": passkey.BackupEligible,
+		"reauth_method":   reauthMethod,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Replacing an existing second factor requires re-authentication through UpdateMFASettings
+	if !user.RequiresMFA() {
+		user.SetMFA(true, &passkeyType.ID)
+		user.MFAType = passkeyType
+		if err := uc.userRepo.Update(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	return passkey, nil
+}
+
+// ListPasskeys lists the passkeys of a user
+func (uc *MFAUsecase) ListPasskeys(ctx context.Context, userID int) ([]*models.Passkey, error) {
+	return uc.passkeyRepo.ListByUser(ctx, userID)
+}
+
+// DeletePasskey removes one of the user's passkeys. The last passkey cannot be removed
+// while passkeys are the user's second factor.
+func (uc *MFAUsecase) DeletePasskey(ctx context.Context, userID, passkeyID int) error {
+	passkey, err := uc.passkeyRepo.FindByID(ctx, passkeyID)
+	if err != nil {
+		return err
+	}
+	if passkey == nil || passkey.UserID != userID {
+		return apperrors.NotFound("passkey not found", "")
+	}
+
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	passkeys, err := uc.passkeyRepo.ListByUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	passkeyIsFactor := user.RequiresMFA() && user.MFAType != nil && user.MFAType.IsPasskey()
+	if len(passkeys) <= 1 && (passkeyIsFactor || user.PasskeyRequired) {
+		return apperrors.BadRequest("the last passkey cannot be removed while it is your second factor", nil)
+	}
+
+	if err := uc.passkeyRepo.Delete(ctx, passkey.ID); err != nil {
+		return err
+	}
+
+	return recordAudit(ctx, uc.auditLogRepo, models.AuditActionPasskeyDeleted, user.ID, user.ID, map[string]interface{}{
+		"passkey_id": passkey.ID,
+		"name":       passkey.Name,
+	})
+}
+
+// BeginPasskeyLogin returns the options to pass to navigator.credentials.get().
+// With an MFA challenge token the passkey is the second step of a login and the assertion is
+// sent to VerifyChallenge; without one the browser offers every passkey of the site and the
+// assertion is sent to LoginWithPasskey.
+func (uc *MFAUsecase) BeginPasskeyLogin(ctx context.Context, mfaToken string) (*webauthn.RequestOptions, error) {
+	if mfaToken == "" {
+		challenge, err := uc.createPasskeyChallenge(ctx, models.PasskeyPurposePasswordless, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		return uc.relyingParty.RequestOptions(challenge, nil, webauthn.UserVerificationRequired), nil
+	}
+
+	claims, err := uc.jwtService.ValidateMFAChallengeToken(ctx, mfaToken)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+	user, err := uc.userRepo.FindByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.RequiresMFA() {
+		return nil, ErrInvalidMFAToken
+	}
+
+	return uc.beginPasskeyAssertion(ctx, user, models.OTPPurposeLogin)
+}
+
+// BeginPasskeyReauthentication returns the options to pass to navigator.credentials.get()
+// so a logged-in user can confirm a sensitive change with their passkey
+func (uc *MFAUsecase) BeginPasskeyReauthentication(ctx context.Context, userID int) (*webauthn.RequestOptions, error) {
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	return uc.beginPasskeyAssertion(ctx, user, models.OTPPurposeReauthentication)
+}
+
+// LoginWithPasskey logs a user in with a passkey alone. The authenticator must have verified
+// the user, e.g. with a PIN or biometrics, so the passkey counts as both factors.
+func (uc *MFAUsecase) LoginWithPasskey(ctx context.Context, credential string) (*LoginResponse, error) {
+	if err := uc.loginAttemptUsecase.CheckIP(ctx, ""); err != nil {
+		return nil, err
+	}
+
+	assertion, err := webauthn.ParseAssertion(credential)
+	if err != nil {
+		return nil, ErrInvalidPasskey
+	}
+	challenge, err := uc.consumePasskeyChallenge(ctx, assertion.ClientData.Challenge, models.PasskeyPurposePasswordless, nil)
+	if err != nil {
+		return nil, err
+	}
+	if challenge == nil {
+		return nil, ErrInvalidPasskey
+	}
+
+	passkey, err := uc.passkeyRepo.FindByCredentialID(ctx, assertion.CredentialID)
+	if err != nil {
+		return nil, err
+	}
+	var user *models.User
+	if passkey != nil && (assertion.UserHandle == nil || bytes.Equal(assertion.UserHandle, passkey.UserHandle)) {
+		if user, err = uc.userRepo.FindByID(ctx, passkey.UserID); err != nil {
+			return nil, err
+		}
+	}
+	if user == nil {
+		if err := uc.loginAttemptUsecase.RecordFailure(ctx, "", nil, models.LoginFailureBadPasskey); err != nil {
+			return nil, err
+		}
+		return nil, apperrors.InvalidCredentials("")
+	}
+
+	if user.IsDisabled() {
+		if err := uc.loginAttemptUsecase.RecordFailure(ctx, user.Email, user, models.LoginFailureAccountDisabled); err != nil {
+			return nil, err
+		}
+		return nil, apperrors.InvalidCredentials("")
+	}
+	if user.IsLocked() {
+		if err := uc.loginAttemptUsecase.RecordFailure(ctx, user.Email, user, models.LoginFailureAccountLocked); err != nil {
+			return nil, err
+		}
+		return nil, apperrors.InvalidCredentials("")
+	}
+
+	ok, err := uc.checkPasskeyAssertion(ctx, passkey, assertion, assertion.ClientData.Challenge, true)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := uc.loginAttemptUsecase.RecordFailure(ctx, user.Email, user, models.LoginFailureBadPasskey); err != nil {
+			return nil, err
+		}
+		return nil, apperrors.InvalidCredentials("")
+	}
+
+	// As with a password, the passkey alone does not let an unverified email in
+	if uc.emailVerificationUsecase.RequiresVerification(user) {
+		if err := uc.loginAttemptUsecase.RecordFailure(ctx, user.Email, user, models.LoginFailureEmailUnverified); err != nil {
+			return nil, err
+		}
+		return nil, ErrEmailNotVerified
+	}
+
+	if err := uc.loginAttemptUsecase.RecordSuccess(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return uc.tokenUsecase.IssueTokens(ctx, user)
+}
+
+// applyPasskeyRequirement requires or stops requiring the user to use a passkey as their
+// second factor. Requiring it makes passkeys the active factor, so the user must have one.
+// The user is not saved.
+func (uc *MFAUsecase) applyPasskeyRequirement(ctx context.Context, user *models.User, required bool) error {
+	if !required {
+		user.PasskeyRequired = false
+		return nil
+	}
+
+	passkeyType, err := uc.passkeyType(ctx)
+	if err != nil {
+		return err
+	}
+	enrolled, err := uc.isEnrolled(ctx, user, passkeyType)
+	if err != nil {
+		return err
+	}
+	if !enrolled {
+		return apperrors.BadRequest("the user must register a passkey before it can be required", nil)
+	}
+
+	user.RequirePasskey(passkeyType)
+	return nil
+}
+
+// verifyPasskey checks an assertion of one of the user's passkeys answering a challenge
+// issued to the user for the given purpose
+func (uc *MFAUsecase) verifyPasskey(ctx context.Context, user *models.User, credential string, purpose string) (bool, error) {
+	assertion, err := webauthn.ParseAssertion(credential)
+	if err != nil {
+		return false, nil
+	}
+
+	passkey, err := uc.passkeyRepo.FindByCredentialID(ctx, assertion.CredentialID)
+	if err != nil {
+		return false, err
+	}
+	if passkey == nil || passkey.UserID != user.ID {
+		return false, nil
+	}
+
+	challenge, err := uc.consumePasskeyChallenge(ctx, assertion.ClientData.Challenge, purpose, &user.ID)
+	if err != nil || challenge == nil {
+		return false, err
+	}
+
+	return uc.checkPasskeyAssertion(ctx, passkey, assertion, assertion.ClientData.Challenge, false)
+}
+
+// checkPasskeyAssertion verifies the signature of an assertion and records the use of the
+// passkey. An assertion whose counter does not increase is refused as it may come from a
+// cloned authenticator, and so is a concurrent use of the same counter value.
+func (uc *MFAUsecase) checkPasskeyAssertion(ctx context.Context, passkey *models.Passkey, assertion *webauthn.Assertion, challenge string, requireUserVerification bool) (bool, error) {
+	signCount, err := uc.relyingParty.VerifyAssertion(assertion, challenge, passkey.PublicKey, passkey.SignCount, requireUserVerification)
+	if err != nil {
+		log.Printf("Refused passkey %d of user %d: %v", passkey.ID, passkey.UserID, err)
+		return false, nil
+	}
+
+	return uc.passkeyRepo.UpdateSignCount(ctx, passkey.ID, passkey.SignCount, signCount)
+}
+
+// beginPasskeyAssertion issues a challenge for one of the user's passkeys
+func (uc *MFAUsecase) beginPasskeyAssertion(ctx context.Context, user *models.User, purpose string) (*webauthn.RequestOptions, error) {
+	passkeys, err := uc.passkeyRepo.ListByUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(passkeys) == 0 {
+		return nil, apperrors.BadRequest("no passkey is registered", nil)
+	}
+
+	allow := make([]webauthn.CredentialDescriptor, 0, len(passkeys))
+	for _, passkey := range passkeys {
+		allow = append(allow, passkeyDescriptor(passkey))
+	}
+
+	challenge, err := uc.createPasskeyChallenge(ctx, purpose, &user.ID, nil)
+	if err != nil {
+		return nil, err
+	}
+	return uc.relyingParty.RequestOptions(challenge, allow, webauthn.UserVerificationPreferred), nil
+}
+
+// createPasskeyChallenge issues a challenge and stores its hash
+func (uc *MFAUsecase) createPasskeyChallenge(ctx context.Context, purpose string, userID *int, userHandle []byte) (string, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return "", err
+	}
+
+	err = uc.passkeyChallengeRepo.Create(ctx, &models.PasskeyChallenge{
+		UserID:        userID,
+		ChallengeHash: auth.HashToken(challenge),
+		Purpose:       purpose,
+		UserHandle:    userHandle,
+		ExpiresAt:     time.Now().Add(uc.config.PasskeyTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return challenge, nil
+}
+
+// consumePasskeyChallenge marks a challenge issued for the purpose and user as used.
+// It returns nil if the challenge is unknown, expired, already used or issued for another purpose.
+func (uc *MFAUsecase) consumePasskeyChallenge(ctx context.Context, challenge, purpose string, userID *int) (*models.PasskeyChallenge, error) {
+	if challenge == "" {
+		return nil, nil
+	}
+
+	record, err := uc.passkeyChallengeRepo.FindByHash(ctx, auth.HashToken(challenge))
+	if err != nil {
+		return nil, err
+	}
+	if record == nil || record.IsUsed() || record.IsExpired() || !record.IsFor(purpose, userID) {
+		return nil, nil
+	}
+
+	used, err := uc.passkeyChallengeRepo.MarkUsed(ctx, record.ID)
+	if err != nil || !used {
+		return nil, err
+	}
+	return record, nil
+}
+
+// passkeyType returns the passkey MFA type, or an error if passkeys are not available
+func (uc *MFAUsecase) passkeyType(ctx context.Context) (*models.MFAType, error) {
+	mfaType, err := uc.mfaTypeRepo.FindByNo(ctx, models.MFATypeNoPasskey)
+	if err != nil {
+		return nil, err
+	}
+	if mfaType == nil || !mfaType.IsActiveType() {
+		return nil, apperrors.BadRequest("passkey MFA is not available", nil)
+	}
+	return mfaType, nil
+}
+
+// passkeyDescriptor identifies a stored passkey in ceremony options
+func passkeyDescriptor(passkey *models.Passkey) webauthn.CredentialDescriptor {
+	return webauthn.CredentialDescriptor{
+		Type:       "public-key",
+		ID:         webauthn.EncodeID(passkey.CredentialID),
+		Transports: passkey.TransportList(),
+	}
+}
@@ -0,0 +1,931 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/auth"
+	apperrors "github.com/vnlab/makeshop-payment/src/infrastructure/errors"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/mail"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/sms"
+	"github.com/vnlab/makeshop-payment/src/lib/totp"
+	"github.com/vnlab/makeshop-payment/src/lib/webauthn"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// recoveryCodeCount is the number of recovery codes issued on enrollment
+	recoveryCodeCount = 10
+	// qrCodeSize is the width and height in pixels of the enrollment QR code
+	qrCodeSize = 256
+	// otpCodeDigits is the length of codes delivered by email or SMS
+	otpCodeDigits = 6
+)
+
+// One-time code rate limit errors
+var (
+	ErrOTPSentRecently    = errors.New("a verification code was sent recently, please wait before requesting another one")
+	ErrOTPTooManyRequests = errors.New("too many verification codes requested, please try again later")
+)
+
+// MFA verification errors
+var (
+	ErrInvalidMFAToken         = errors.New("invalid or expired MFA token")
+	ErrInvalidVerificationCode = errors.New("invalid verification code")
+)
+
+// MFAConfig holds the tunable MFA settings
+type MFAConfig struct {
+	Issuer         string        // Issuer name shown in authenticator apps
+	OTPCodeTTL     time.Duration // Lifetime of emailed or texted codes
+	OTPMaxAttempts int           // Wrong guesses allowed per code
+	OTPResendDelay time.Duration // Minimum delay between two codes sent to the same user
+	OTPMaxPerHour  int           // Maximum codes sent to the same user per hour and channel
+	PasskeyTTL     time.Duration // Time a passkey registration or login may take
+}
+
+// MFAUsecase handles multi-factor authentication business logic
+type MFAUsecase struct {
+	userRepo             repositories.UserRepository
+	mfaTypeRepo          repositories.MFATypeRepository
+	totpRepo             repositories.UserTOTPRepository
+	recoveryCodeRepo     repositories.RecoveryCodeRepository
+	otpCodeRepo          repositories.OTPCodeRepository
+	passkeyRepo          repositories.PasskeyRepository
+	passkeyChallengeRepo repositories.PasskeyChallengeRepository
+	auditLogRepo         repositories.AuditLogRepository
+	jwtService           *auth.JWTService
+	tokenUsecase         *TokenUsecase
+	loginAttemptUsecase  *LoginAttemptUsecase
+	cipher               *auth.SecretCipher
+	mailer               mail.Mailer
+	mailTemplates        *mail.TemplateRenderer
+	smsSender            sms.SMSSender
+	relyingParty         *webauthn.RelyingParty
+	config               MFAConfig
+
+	emailVerificationUsecase *EmailVerificationUsecase
+}
+
+// NewMFAUseCase creates a new MFAUsecase
+func NewMFAUseCase(
+	userRepo repositories.UserRepository,
+	mfaTypeRepo repositories.MFATypeRepository,
+	totpRepo repositories.UserTOTPRepository,
+	recoveryCodeRepo repositories.RecoveryCodeRepository,
+	otpCodeRepo repositories.OTPCodeRepository,
+	passkeyRepo repositories.PasskeyRepository,
+	passkeyChallengeRepo repositories.PasskeyChallengeRepository,
+	auditLogRepo repositories.AuditLogRepository,
+	jwtService *auth.JWTService,
+	tokenUsecase *TokenUsecase,
+	loginAttemptUsecase *LoginAttemptUsecase,
+	emailVerificationUsecase *EmailVerificationUsecase,
+	cipher *auth.SecretCipher,
+	mailer mail.Mailer,
+	mailTemplates *mail.TemplateRenderer,
+	smsSender sms.SMSSender,
+	relyingParty *webauthn.RelyingParty,
+	config MFAConfig,
+) *MFAUsecase {
+	return &MFAUsecase{
+		userRepo:             userRepo,
+		mfaTypeRepo:          mfaTypeRepo,
+		totpRepo:             totpRepo,
+		recoveryCodeRepo:     recoveryCodeRepo,
+		otpCodeRepo:          otpCodeRepo,
+		passkeyRepo:          passkeyRepo,
+		passkeyChallengeRepo: passkeyChallengeRepo,
+		auditLogRepo:         auditLogRepo,
+		jwtService:           jwtService,
+		tokenUsecase:         tokenUsecase,
+		loginAttemptUsecase:  loginAttemptUsecase,
+		cipher:               cipher,
+		mailer:               mailer,
+		mailTemplates:        mailTemplates,
+		smsSender:            smsSender,
+		relyingParty:         relyingParty,
+		config:               config,
+
+		emailVerificationUsecase: emailVerificationUsecase,
+	}
+}
+
+// TOTPEnrollment represents a pending TOTP enrollment
+type TOTPEnrollment struct {
+	Secret    string `json:"secret"`
+	URI       string `json:"uri"`
+	QRCodePNG []byte `json:"qr_code_png"`
+}
+
+// ReauthenticationRequest confirms the identity of a logged-in user before a sensitive change
+type ReauthenticationRequest struct {
+	CurrentPassword string `json:"current_password"`
+	MFACode         string `json:"mfa_code"`
+}
+
+// UpdateMFASettingsRequest represents a change of the user's second factor. Users with MFA
+// enabled re-authenticate with MFACode, others with CurrentPassword.
+type UpdateMFASettingsRequest struct {
+	Enabled         bool   `json:"enabled"`
+	TypeID          *int   `json:"type_id"`
+	CurrentPassword string `json:"current_password"`
+	MFACode         string `json:"mfa_code"`
+}
+
+// VerifyMFARequest represents the second step of a login
+type VerifyMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// EnrollTOTP creates a new TOTP secret for the user. The secret is not used for
+// login until it has been confirmed with ConfirmTOTP. Users who already have MFA
+// enabled must re-authenticate.
+func (uc *MFAUsecase) EnrollTOTP(ctx context.Context, userID int, req ReauthenticationRequest) (*TOTPEnrollment, error) {
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	// Otherwise a stolen session could set up an authenticator app and then switch to it
+	if user.RequiresMFA() {
+		if _, err := uc.reauthenticate(ctx, user, req.CurrentPassword, req.MFACode); err != nil {
+			return nil, err
+		}
+	}
+
+	existing, err := uc.totpRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.IsConfirmed() && user.RequiresMFA() && user.MFAType != nil && user.MFAType.IsOTP() {
+		return nil, apperrors.BadRequest("authenticator app is already enabled", nil)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := uc.cipher.Encrypt(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	record := existing
+	if record == nil {
+		record = &models.UserTOTP{UserID: userID}
+	}
+	record.SecretEncrypted = encrypted
+	record.ConfirmedAt = nil
+	record.LastUsedStep = 0
+
+	if err := uc.totpRepo.Save(ctx, record); err != nil {
+		return nil, err
+	}
+
+	uri := totp.KeyURI(uc.config.Issuer, user.Email, secret)
+	png, err := totp.QRCodePNG(uri, qrCodeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollment{
+		Secret:    secret,
+		URI:       uri,
+		QRCodePNG: png,
+	}, nil
+}
+
+// ConfirmTOTP confirms a pending enrollment with a code from the authenticator app
+// and makes TOTP the user's second factor unless another factor is already active.
+// It returns a fresh set of recovery codes, replacing any previous one.
+func (uc *MFAUsecase) ConfirmTOTP(ctx context.Context, userID int, code string) ([]string, error) {
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	record, err := uc.totpRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, apperrors.NotFound("authenticator app enrollment not found", "")
+	}
+	if record.IsConfirmed() {
+		return nil, apperrors.BadRequest("authenticator app enrollment is already confirmed", nil)
+	}
+
+	secret, err := uc.cipher.Decrypt(record.SecretEncrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := totp.Validate(code, secret, time.Now())
+	if !ok {
+		return nil, ErrInvalidVerificationCode
+	}
+
+	otpType, err := uc.mfaTypeRepo.FindByNo(ctx, models.MFATypeNoOTP)
+	if err != nil {
+		return nil, err
+	}
+	if otpType == nil || !otpType.IsActiveType() {
+		return nil, apperrors.BadRequest("authenticator app MFA is not available", nil)
+	}
+
+	record.Confirm(step)
+	if err := uc.totpRepo.Save(ctx, record); err != nil {
+		return nil, err
+	}
+
+	// Replacing an existing second factor requires re-authentication through UpdateMFASettings
+	if !user.RequiresMFA() {
+		user.SetMFA(true, &otpType.ID)
+		if err := uc.userRepo.Update(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	return uc.issueRecoveryCodes(ctx, userID)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of a user who has MFA enabled,
+// e.g. once most of them have been used. The user must re-authenticate.
+func (uc *MFAUsecase) RegenerateRecoveryCodes(ctx context.Context, userID int, req ReauthenticationRequest) ([]string, error) {
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if !user.RequiresMFA() {
+		return nil, apperrors.BadRequest("recovery codes are only available once MFA is enabled", nil)
+	}
+
+	reauthMethod, err := uc.reauthenticate(ctx, user, req.CurrentPassword, req.MFACode)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := uc.issueRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordAudit(ctx, uc.auditLogRepo, models.AuditActionRecoveryCodesRegenerated, user.ID, user.ID, map[string]interface{}{
+		"reauth_method": reauthMethod,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// StartChallenge issues an MFA challenge token for a user whose password has been verified.
+// For channels that deliver codes, the first code is sent immediately.
+func (uc *MFAUsecase) StartChallenge(ctx context.Context, user *models.User) (*LoginResponse, error) {
+	// A code sent moments ago is still valid, so a repeated login does not need a new one
+	if err := uc.deliverChallengeCode(ctx, user, models.OTPPurposeLogin); err != nil && !errors.Is(err, ErrOTPSentRecently) {
+		return nil, err
+	}
+
+	mfaToken, err := uc.jwtService.GenerateMFAChallengeToken(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResponse{
+		MFARequired: true,
+		MFAToken:    mfaToken,
+		MFAType:     user.MFAType,
+	}, nil
+}
+
+// ResendChallengeCode sends a new code for a pending MFA challenge
+func (uc *MFAUsecase) ResendChallengeCode(ctx context.Context, mfaToken string) error {
+	claims, err := uc.jwtService.ValidateMFAChallengeToken(ctx, mfaToken)
+	if err != nil {
+		return ErrInvalidMFAToken
+	}
+
+	user, err := uc.userRepo.FindByID(ctx, claims.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil || !user.RequiresMFA() {
+		return ErrInvalidMFAToken
+	}
+	if user.MFAType == nil || !(user.MFAType.IsEmail() || user.MFAType.IsSMS()) {
+		return apperrors.BadRequest("code delivery is not available for this MFA type", nil)
+	}
+
+	return uc.deliverChallengeCode(ctx, user, models.OTPPurposeLogin)
+}
+
+// VerifyChallenge exchanges an MFA challenge token and a valid code for access and refresh tokens
+func (uc *MFAUsecase) VerifyChallenge(ctx context.Context, req VerifyMFARequest) (*LoginResponse, error) {
+	claims, err := uc.jwtService.ValidateMFAChallengeToken(ctx, req.MFAToken)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	user, err := uc.userRepo.FindByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.CanLogin() || !user.RequiresMFA() {
+		return nil, ErrInvalidMFAToken
+	}
+	// A lockout started after the password check also stops the second step
+	if user.IsLocked() {
+		if err := uc.loginAttemptUsecase.RecordFailure(ctx, user.Email, user, models.LoginFailureAccountLocked); err != nil {
+			return nil, err
+		}
+		return nil, apperrors.InvalidCredentials("")
+	}
+
+	ok, err := uc.verifyFactor(ctx, user, req.Code, models.OTPPurposeLogin)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := uc.loginAttemptUsecase.RecordFailure(ctx, user.Email, user, models.LoginFailureBadMFACode); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidVerificationCode
+	}
+
+	if err := uc.loginAttemptUsecase.RecordSuccess(ctx, user); err != nil {
+		return nil, err
+	}
+
+	// The challenge token is single use
+	if err := uc.jwtService.RevokeToken(ctx, req.MFAToken); err != nil {
+		return nil, err
+	}
+
+	return uc.tokenUsecase.IssueTokens(ctx, user)
+}
+
+// verifyFactor checks a code against the user's configured second factor. Recovery codes are
+// accepted in place of any factor, except for users required to use a passkey.
+func (uc *MFAUsecase) verifyFactor(ctx context.Context, user *models.User, code string, purpose string) (bool, error) {
+	ok, err := uc.verifyLiveFactor(ctx, user, code, purpose)
+	if err != nil || ok {
+		return ok, err
+	}
+
+	if user.PasskeyRequired {
+		return false, nil
+	}
+	return uc.useRecoveryCode(ctx, user.ID, code)
+}
+
+// verifyLiveFactor checks a code against the user's configured second factor only. For passkeys
+// the code is the JSON assertion. Codes delivered by email or SMS and passkey challenges must have
+// been issued for the given purpose.
+func (uc *MFAUsecase) verifyLiveFactor(ctx context.Context, user *models.User, code string, purpose string) (bool, error) {
+	if user.MFAType == nil {
+		return false, nil
+	}
+
+	code = strings.TrimSpace(code)
+	switch {
+	case user.MFAType.IsOTP():
+		return uc.verifyTOTP(ctx, user.ID, code)
+	case user.MFAType.IsEmail():
+		return uc.verifyDeliveredCode(ctx, user.ID, models.OTPChannelEmail, purpose, code)
+	case user.MFAType.IsSMS():
+		return uc.verifyDeliveredCode(ctx, user.ID, models.OTPChannelSMS, purpose, code)
+	case user.MFAType.IsPasskey():
+		return uc.verifyPasskey(ctx, user, code, purpose)
+	}
+	return false, nil
+}
+
+// deliverChallengeCode sends a code for channels that deliver one
+func (uc *MFAUsecase) deliverChallengeCode(ctx context.Context, user *models.User, purpose string) error {
+	if user.MFAType == nil {
+		return nil
+	}
+
+	switch {
+	case user.MFAType.IsEmail():
+		return uc.sendEmailCode(ctx, user, purpose)
+	case user.MFAType.IsSMS():
+		if !user.HasVerifiedPhoneNumber() {
+			return apperrors.BadRequest("no verified phone number is registered", nil)
+		}
+		return uc.sendSMSCode(ctx, user.ID, *user.PhoneNumber, purpose)
+	}
+	return nil
+}
+
+// ListMFATypes lists all MFA types
+func (uc *MFAUsecase) ListMFATypes(ctx context.Context) ([]*models.MFAType, error) {
+	return uc.mfaTypeRepo.List(ctx)
+}
+
+// SendReauthenticationCode sends a fresh code to a logged-in user whose second factor
+// is delivered by email or SMS, so it can be used to confirm a sensitive change
+func (uc *MFAUsecase) SendReauthenticationCode(ctx context.Context, userID int) error {
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if !user.RequiresMFA() || user.MFAType == nil || !(user.MFAType.IsEmail() || user.MFAType.IsSMS()) {
+		return apperrors.BadRequest("code delivery is not available for this MFA type", nil)
+	}
+
+	return uc.deliverChallengeCode(ctx, user, models.OTPPurposeReauthentication)
+}
+
+// UpdateMFASettings enables, disables or switches the user's second factor after re-authentication
+func (uc *MFAUsecase) UpdateMFASettings(ctx context.Context, userID int, req UpdateMFASettingsRequest) (*models.User, error) {
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	reauthMethod, err := uc.reauthenticate(ctx, user, req.CurrentPassword, req.MFACode)
+	if err != nil {
+		return nil, err
+	}
+
+	previousEnabled := user.EnabledMFA
+	previousTypeID := user.MFATypeID
+
+	if err := uc.applyMFASettings(ctx, user, req.Enabled, req.TypeID); err != nil {
+		return nil, err
+	}
+
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	err = recordAudit(ctx, uc.auditLogRepo, models.AuditActionMFASettingsUpdated, user.ID, user.ID, map[string]interface{}{
+		"previous_enabled": previousEnabled,
+		"previous_type_id": previousTypeID,
+		"enabled":          user.EnabledMFA,
+		"type_id":          user.MFATypeID,
+		"reauth_method":    reauthMethod,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// applyMFASettings enables one of the factors the user has set up, or disables MFA.
+// The user is not saved.
+func (uc *MFAUsecase) applyMFASettings(ctx context.Context, user *models.User, enabled bool, typeID *int) error {
+	if !enabled {
+		if user.PasskeyRequired {
+			return ErrPasskeyRequired
+		}
+		user.SetMFA(false, nil)
+		user.MFAType = nil
+		return nil
+	}
+
+	if typeID == nil {
+		return apperrors.Validation("MFA type is required", nil)
+	}
+
+	mfaType, err := uc.mfaTypeRepo.FindByID(ctx, *typeID)
+	if err != nil {
+		return err
+	}
+	if mfaType == nil {
+		return apperrors.NotFound("MFA type not found", "")
+	}
+	if !mfaType.IsActiveType() {
+		return apperrors.BadRequest("MFA type is not available", nil)
+	}
+	if user.PasskeyRequired && !mfaType.IsPasskey() {
+		return ErrPasskeyRequired
+	}
+
+	enrolled, err := uc.isEnrolled(ctx, user, mfaType)
+	if err != nil {
+		return err
+	}
+	if !enrolled {
+		return apperrors.BadRequest("MFA type must be set up before it can be enabled", nil)
+	}
+
+	user.SetMFA(true, &mfaType.ID)
+	user.MFAType = mfaType
+	return nil
+}
+
+// AdminResetMFA removes every enrolled factor of a user and disables MFA,
+// e.g. when the user has lost their device and their recovery codes
+func (uc *MFAUsecase) AdminResetMFA(ctx context.Context, adminUserID, userID int, reason string) (*models.User, error) {
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	previousEnabled := user.EnabledMFA
+	previousTypeID := user.MFATypeID
+	previousPasskeyRequired := user.PasskeyRequired
+
+	if err := uc.totpRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	if err := uc.recoveryCodeRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	if err := uc.passkeyRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	// The requirement is set again once the user has registered a new passkey
+	user.PasskeyRequired = false
+	user.SetMFA(false, nil)
+	user.MFAType = nil
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	err = recordAudit(ctx, uc.auditLogRepo, models.AuditActionMFAReset, adminUserID, user.ID, map[string]interface{}{
+		"previous_enabled":          previousEnabled,
+		"previous_type_id":          previousTypeID,
+		"previous_passkey_required": previousPasskeyRequired,
+		"reason":                    reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// reauthenticate confirms the identity of a logged-in user. Users with MFA enabled must give a
+// fresh code from their current second factor, optionally with their password. Recovery codes are
+// refused: together with a stolen session they would be enough to replace the second factor.
+// Users without MFA give their password.
+func (uc *MFAUsecase) reauthenticate(ctx context.Context, user *models.User, currentPassword, mfaCode string) (string, error) {
+	if currentPassword != "" && !user.VerifyPassword(currentPassword) {
+		return "", apperrors.InvalidCredentials("current password is incorrect")
+	}
+
+	if !user.RequiresMFA() {
+		if currentPassword == "" {
+			return "", apperrors.Validation("re-authentication is required: provide the current password", nil)
+		}
+		return "password", nil
+	}
+
+	if mfaCode == "" {
+		return "", apperrors.Validation("re-authentication is required: provide a code from your current second factor", nil)
+	}
+	ok, err := uc.verifyLiveFactor(ctx, user, mfaCode, models.OTPPurposeReauthentication)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrInvalidVerificationCode
+	}
+
+	if currentPassword != "" {
+		return "password_and_mfa_code", nil
+	}
+	return "mfa_code", nil
+}
+
+// isEnrolled checks if the user has completed the setup required by an MFA type
+func (uc *MFAUsecase) isEnrolled(ctx context.Context, user *models.User, mfaType *models.MFAType) (bool, error) {
+	switch {
+	case mfaType.IsOTP():
+		record, err := uc.totpRepo.FindByUserID(ctx, user.ID)
+		if err != nil {
+			return false, err
+		}
+		return record != nil && record.IsConfirmed(), nil
+	case mfaType.IsEmail():
+		return user.IsEmailVerified(), nil
+	case mfaType.IsSMS():
+		return user.HasVerifiedPhoneNumber(), nil
+	case mfaType.IsPasskey():
+		passkeys, err := uc.passkeyRepo.ListByUser(ctx, user.ID)
+		if err != nil {
+			return false, err
+		}
+		return len(passkeys) > 0, nil
+	}
+	return false, nil
+}
+
+// RegisterPhoneNumber sends a verification code to a new phone number.
+// The number is stored on the user only once the code is verified.
+func (uc *MFAUsecase) RegisterPhoneNumber(ctx context.Context, userID int, phoneNumber string) error {
+	if err := models.ValidatePhoneNumber(phoneNumber); err != nil {
+		return err
+	}
+
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	return uc.sendSMSCode(ctx, user.ID, phoneNumber, models.OTPPurposePhoneVerification)
+}
+
+// VerifyPhoneNumber verifies the code sent by RegisterPhoneNumber and stores the number.
+// When useForMFA is set and no other factor is active, SMS becomes the user's second factor.
+func (uc *MFAUsecase) VerifyPhoneNumber(ctx context.Context, userID int, code string, useForMFA bool) (*models.User, error) {
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	var smsType *models.MFAType
+	if useForMFA {
+		smsType, err = uc.mfaTypeRepo.FindByNo(ctx, models.MFATypeNoSMS)
+		if err != nil {
+			return nil, err
+		}
+		if smsType == nil || !smsType.IsActiveType() {
+			return nil, apperrors.BadRequest("SMS MFA is not available", nil)
+		}
+	}
+
+	record, err := uc.verifyOTPCode(ctx, user.ID, models.OTPChannelSMS, models.OTPPurposePhoneVerification, strings.TrimSpace(code))
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, ErrInvalidVerificationCode
+	}
+
+	if err := user.SetVerifiedPhoneNumber(record.Destination); err != nil {
+		return nil, err
+	}
+	// Replacing an existing second factor requires re-authentication through UpdateMFASettings
+	if smsType != nil && !user.RequiresMFA() {
+		user.SetMFA(true, &smsType.ID)
+		user.MFAType = smsType
+	}
+
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// sendEmailCode issues a one-time code and emails it to the user
+func (uc *MFAUsecase) sendEmailCode(ctx context.Context, user *models.User, purpose string) error {
+	code, err := uc.issueOTPCode(ctx, user.ID, models.OTPChannelEmail, purpose, user.Email)
+	if err != nil {
+		return err
+	}
+
+	msg, err := uc.mailTemplates.Message(user.Email, mail.TemplateMFACode, map[string]interface{}{
+		"LastName":         user.LastName,
+		"FirstName":        user.FirstName,
+		"Code":             code,
+		"ExpiresInMinutes": int(uc.config.OTPCodeTTL.Minutes()),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := uc.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send verification code: %w", err)
+	}
+	return nil
+}
+
+// sendSMSCode issues a one-time code and texts it to the given phone number
+func (uc *MFAUsecase) sendSMSCode(ctx context.Context, userID int, phoneNumber string, purpose string) error {
+	code, err := uc.issueOTPCode(ctx, userID, models.OTPChannelSMS, purpose, phoneNumber)
+	if err != nil {
+		return err
+	}
+
+	body := sms.MFACodeMessage(code, int(uc.config.OTPCodeTTL.Minutes()))
+	if err := uc.smsSender.Send(ctx, phoneNumber, body); err != nil {
+		return fmt.Errorf("failed to send verification code: %w", err)
+	}
+	return nil
+}
+
+// issueOTPCode generates a numeric code, stores its hash and returns the plain code.
+// Sending is rate limited per user and channel.
+func (uc *MFAUsecase) issueOTPCode(ctx context.Context, userID int, channel, purpose, destination string) (string, error) {
+	if err := uc.checkOTPRateLimit(ctx, userID, channel); err != nil {
+		return "", err
+	}
+
+	code, err := generateNumericCode(otpCodeDigits)
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	record := &models.OTPCode{
+		UserID:      userID,
+		Channel:     channel,
+		Purpose:     purpose,
+		Destination: destination,
+		CodeHash:    string(hash),
+		ExpiresAt:   time.Now().Add(uc.config.OTPCodeTTL),
+	}
+	if err := uc.otpCodeRepo.Create(ctx, record); err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+// checkOTPRateLimit rejects sending when the user requested a code too recently or too often
+func (uc *MFAUsecase) checkOTPRateLimit(ctx context.Context, userID int, channel string) error {
+	recent, err := uc.otpCodeRepo.CountCreatedSince(ctx, userID, channel, time.Now().Add(-uc.config.OTPResendDelay))
+	if err != nil {
+		return err
+	}
+	if recent > 0 {
+		return ErrOTPSentRecently
+	}
+
+	hourly, err := uc.otpCodeRepo.CountCreatedSince(ctx, userID, channel, time.Now().Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	if uc.config.OTPMaxPerHour > 0 && hourly >= int64(uc.config.OTPMaxPerHour) {
+		return ErrOTPTooManyRequests
+	}
+
+	return nil
+}
+
+// verifyDeliveredCode checks a code delivered on the given channel for the given purpose
+func (uc *MFAUsecase) verifyDeliveredCode(ctx context.Context, userID int, channel, purpose, code string) (bool, error) {
+	record, err := uc.verifyOTPCode(ctx, userID, channel, purpose, code)
+	if err != nil {
+		return false, err
+	}
+	return record != nil, nil
+}
+
+// verifyOTPCode checks a code against the latest active code, counting failed attempts.
+// It returns the consumed code, or nil if the code is invalid.
+func (uc *MFAUsecase) verifyOTPCode(ctx context.Context, userID int, channel, purpose, code string) (*models.OTPCode, error) {
+	record, err := uc.otpCodeRepo.FindLatestActive(ctx, userID, channel, purpose)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil || !record.IsUsable(uc.config.OTPMaxAttempts) {
+		return nil, nil
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(record.CodeHash), []byte(code)) != nil {
+		if err := uc.otpCodeRepo.IncrementAttempts(ctx, record.ID); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	consumed, err := uc.otpCodeRepo.MarkConsumed(ctx, record.ID)
+	if err != nil || !consumed {
+		return nil, err
+	}
+	return record, nil
+}
+
+// verifyTOTP checks a TOTP code and rejects codes that have already been used
+func (uc *MFAUsecase) verifyTOTP(ctx context.Context, userID int, code string) (bool, error) {
+	record, err := uc.totpRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if record == nil || !record.IsConfirmed() {
+		return false, nil
+	}
+
+	secret, err := uc.cipher.Decrypt(record.SecretEncrypted)
+	if err != nil {
+		return false, err
+	}
+
+	step, ok := totp.Validate(code, secret, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	return uc.totpRepo.UpdateLastUsedStep(ctx, record.ID, step)
+}
+
+// useRecoveryCode consumes a recovery code if it is valid
+func (uc *MFAUsecase) useRecoveryCode(ctx context.Context, userID int, code string) (bool, error) {
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return false, nil
+	}
+
+	recoveryCode, err := uc.recoveryCodeRepo.FindUnusedByHash(ctx, userID, auth.HashToken(normalized))
+	if err != nil {
+		return false, err
+	}
+	if recoveryCode == nil {
+		return false, nil
+	}
+
+	return uc.recoveryCodeRepo.MarkUsed(ctx, recoveryCode.ID)
+}
+
+// issueRecoveryCodes generates a fresh set of recovery codes and stores their hashes
+func (uc *MFAUsecase) issueRecoveryCodes(ctx context.Context, userID int) ([]string, error) {
+	plain := make([]string, 0, recoveryCodeCount)
+	records := make([]*models.RecoveryCode, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		plain = append(plain, code)
+		records = append(records, &models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: auth.HashToken(normalizeRecoveryCode(code)),
+		})
+	}
+
+	if err := uc.recoveryCodeRepo.ReplaceForUser(ctx, userID, records); err != nil {
+		return nil, err
+	}
+
+	return plain, nil
+}
+
+// generateNumericCode returns a random code of the given number of digits
+func generateNumericCode(digits int) (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < digits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", digits, n.Int64()), nil
+}
+
+// generateRecoveryCode returns a random code formatted as xxxxx-xxxxx
+func generateRecoveryCode() (string, error) {
+	buf := make([]byte, 7)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	encoded := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf))[:10]
+	return encoded[:5] + "-" + encoded[5:], nil
+}
+
+// normalizeRecoveryCode strips separators and case so codes can be typed loosely
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
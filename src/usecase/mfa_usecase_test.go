@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/auth"
+	"github.com/vnlab/makeshop-payment/src/lib/totp"
+)
+
+// newTOTPTest returns a MFAUsecase with a TOTP secret enrolled for user 1
+func newTOTPTest(t *testing.T, confirmed bool) (*MFAUsecase, *fakeTOTPRepo, string) {
+	t.Helper()
+
+	cipher, err := auth.NewSecretCipher("test encryption key")
+	if err != nil {
+		t.Fatalf("NewSecretCipher: %v", err)
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	encrypted, err := cipher.Encrypt(secret)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	repo := newFixture(t).totp
+	repo.record = &models.UserTOTP{ID: 1, UserID: 1, SecretEncrypted: encrypted}
+	if confirmed {
+		repo.record.Confirm(0)
+	}
+	uc := NewMFAUseCase(nil, nil, repo, nil, nil, nil, nil, nil, nil, nil, nil, nil, cipher, nil, nil, nil, nil, MFAConfig{})
+	return uc, repo, secret
+}
+
+func TestVerifyTOTPRefusesReplay(t *testing.T) {
+	uc, repo, secret := newTOTPTest(t, true)
+	ctx := context.Background()
+	now := time.Now()
+
+	code, err := totp.GenerateCode(secret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := uc.verifyTOTP(ctx, 1, code); err != nil || !ok {
+		t.Fatalf("first use: %v, %v", ok, err)
+	}
+	if repo.record.LastUsedStep != totp.Step(now) {
+		t.Errorf("last used step %d, want %d", repo.record.LastUsedStep, totp.Step(now))
+	}
+	if ok, err := uc.verifyTOTP(ctx, 1, code); err != nil || ok {
+		t.Errorf("replay: %v, %v", ok, err)
+	}
+
+	// A code of an earlier step still in the skew window is refused once a later one was used
+	previous, err := totp.GenerateCode(secret, now.Add(-totp.Period*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous != code {
+		if ok, err := uc.verifyTOTP(ctx, 1, previous); err != nil || ok {
+			t.Errorf("earlier code: %v, %v", ok, err)
+		}
+	}
+}
+
+func TestVerifyTOTPAcceptsLaterStep(t *testing.T) {
+	uc, _, secret := newTOTPTest(t, true)
+	ctx := context.Background()
+	now := time.Now()
+
+	previous, _ := totp.GenerateCode(secret, now.Add(-totp.Period*time.Second))
+	current, _ := totp.GenerateCode(secret, now)
+	if previous == current {
+		t.Skip("codes of consecutive steps collide")
+	}
+	if ok, err := uc.verifyTOTP(ctx, 1, previous); err != nil || !ok {
+		t.Fatalf("previous step: %v, %v", ok, err)
+	}
+	if ok, err := uc.verifyTOTP(ctx, 1, current); err != nil || !ok {
+		t.Errorf("current step after the previous one: %v, %v", ok, err)
+	}
+}
+
+func TestVerifyTOTPRefusesUnconfirmedEnrollment(t *testing.T) {
+	uc, _, secret := newTOTPTest(t, false)
+	code, _ := totp.GenerateCode(secret, time.Now())
+	if ok, err := uc.verifyTOTP(context.Background(), 1, code); err != nil || ok {
+		t.Errorf("unconfirmed enrollment: %v, %v", ok, err)
+	}
+}
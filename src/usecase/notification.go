@@ -0,0 +1,19 @@
+package usecase
+
+import (
+	"context"
+	"log"
+
+	"github.com/vnlab/makeshop-payment/src/infrastructure/mail"
+)
+
+// sendMailInBackground sends a message without making the caller wait for the mail server.
+// It is used where the response must not reveal whether an email was sent.
+func sendMailInBackground(ctx context.Context, mailer mail.Mailer, msg *mail.Message) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := mailer.Send(ctx, msg); err != nil {
+			log.Printf("Failed to send %q: %v", msg.Subject, err)
+		}
+	}()
+}
@@ -0,0 +1,107 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/pubsub"
+)
+
+// silentAuditActions are recorded without notifying the target user
+var silentAuditActions = map[string]bool{
+	// Recorded for every request of an impersonation, which already notifies its start
+	models.AuditActionImpersonatedRequest: true,
+}
+
+// NotificationUsecase delivers account notifications to the users who are online
+type NotificationUsecase struct {
+	broker pubsub.Broker
+}
+
+// NewNotificationUseCase creates a new NotificationUsecase
+func NewNotificationUseCase(broker pubsub.Broker) *NotificationUsecase {
+	return &NotificationUsecase{
+		broker: broker,
+	}
+}
+
+// Notify sends a notification to the live subscriptions of a user
+func (uc *NotificationUsecase) Notify(ctx context.Context, userID int, notification *models.Notification) error {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	return uc.broker.Publish(ctx, notificationTopic(userID), payload)
+}
+
+// Subscribe receives the notifications of a user until the context is cancelled
+func (uc *NotificationUsecase) Subscribe(ctx context.Context, userID int) (<-chan *models.Notification, error) {
+	messages, err := uc.broker.Subscribe(ctx, notificationTopic(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	notifications := make(chan *models.Notification)
+	go func() {
+		defer close(notifications)
+		for message := range messages {
+			var notification models.Notification
+			if err := json.Unmarshal(message.Payload, &notification); err != nil {
+				log.Printf("Failed to decode notification: %v", err)
+				continue
+			}
+			select {
+			case notifications <- &notification:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return notifications, nil
+}
+
+// NotifyingAuditLog wraps an audit log repository so that the user targeted by each entry
+// is notified, which makes every audited change to an account reach its owner
+func (uc *NotificationUsecase) NotifyingAuditLog(repo repositories.AuditLogRepository) repositories.AuditLogRepository {
+	return &notifyingAuditLogRepository{
+		AuditLogRepository: repo,
+		notifications:      uc,
+	}
+}
+
+// notifyingAuditLogRepository notifies the target of each audit entry once it is recorded
+type notifyingAuditLogRepository struct {
+	repositories.AuditLogRepository
+	notifications *NotificationUsecase
+}
+
+// Create records the entry, then notifies its target. Notifications are best effort and
+// never fail the change being audited.
+func (r *notifyingAuditLogRepository) Create(ctx context.Context, auditLog *models.AuditLog) error {
+	if err := r.AuditLogRepository.Create(ctx, auditLog); err != nil {
+		return err
+	}
+	if auditLog.TargetUserID == nil || silentAuditActions[auditLog.Action] {
+		return nil
+	}
+
+	notification := &models.Notification{
+		Type:          auditLog.Action,
+		SelfInitiated: auditLog.ActorUserID != nil && *auditLog.ActorUserID == *auditLog.TargetUserID,
+		CreatedAt:     time.Now(),
+	}
+	if err := r.notifications.Notify(ctx, *auditLog.TargetUserID, notification); err != nil {
+		log.Printf("Failed to notify user %d of %s: %v", *auditLog.TargetUserID, auditLog.Action, err)
+	}
+	return nil
+}
+
+// notificationTopic is the topic carrying the notifications of a user
+func notificationTopic(userID int) string {
+	return fmt.Sprintf("users.%d.notifications", userID)
+}
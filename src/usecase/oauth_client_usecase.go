@@ -0,0 +1,321 @@
+package usecase
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/auth"
+	apperrors "github.com/vnlab/makeshop-payment/src/infrastructure/errors"
+)
+
+const (
+	// oauthClientIDBytes is the size of the public client identifier
+	oauthClientIDBytes = 16
+	// oauthClientSecretBytes is the entropy of a client secret
+	oauthClientSecretBytes = 32
+)
+
+// OAuth client errors
+var (
+	ErrInvalidClient       = errors.New("invalid client")
+	ErrOAuthClientNotFound = errors.New("OAuth client not found")
+)
+
+// CreateOAuthClientRequest represents a new OAuth client
+type CreateOAuthClientRequest struct {
+	Name   string
+	UserID int // Account the client acts for
+	Scopes []string
+}
+
+// ClientToken is an access token issued with the client_credentials grant
+type ClientToken struct {
+	AccessToken string
+	ExpiresIn   time.Duration
+	Scopes      []string
+}
+
+// OAuthClientUsecase registers OAuth clients and issues their access tokens
+type OAuthClientUsecase struct {
+	clientRepo        repositories.OAuthClientRepository
+	userRepo          repositories.UserRepository
+	auditLogRepo      repositories.AuditLogRepository
+	permissionUsecase *PermissionUsecase
+	jwtService        *auth.JWTService
+	tokenTTL          time.Duration
+}
+
+// NewOAuthClientUseCase creates a new OAuthClientUsecase
+func NewOAuthClientUseCase(
+	clientRepo repositories.OAuthClientRepository,
+	userRepo repositories.UserRepository,
+	auditLogRepo repositories.AuditLogRepository,
+	permissionUsecase *PermissionUsecase,
+	jwtService *auth.JWTService,
+	tokenTTL time.Duration,
+) *OAuthClientUsecase {
+	return &OAuthClientUsecase{
+		clientRepo:        clientRepo,
+		userRepo:          userRepo,
+		auditLogRepo:      auditLogRepo,
+		permissionUsecase: permissionUsecase,
+		jwtService:        jwtService,
+		tokenTTL:          tokenTTL,
+	}
+}
+
+// CreateClient registers a client acting for a user and returns it with its secret,
+// which is shown only once. Scopes are limited to payment permissions of the user's role.
+func (uc *OAuthClientUsecase) CreateClient(ctx context.Context, adminID int, req CreateOAuthClientRequest) (*models.OAuthClient, string, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, "", apperrors.Validation("client name is required", nil)
+	}
+
+	owner, err := uc.userRepo.FindByID(ctx, req.UserID)
+	if err != nil {
+		return nil, "", err
+	}
+	if owner == nil {
+		return nil, "", ErrUserNotFound
+	}
+	if err := uc.permissionUsecase.ValidateScopes(ctx, owner.RoleID, req.Scopes); err != nil {
+		return nil, "", err
+	}
+
+	clientID, err := auth.GenerateRandomHex(oauthClientIDBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := auth.GenerateRandomToken(oauthClientSecretBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	client := &models.OAuthClient{
+		ClientID:   clientID,
+		Name:       truncate(name, 100),
+		SecretHash: auth.HashToken(secret),
+		UserID:     owner.ID,
+		Scopes:     strings.Join(req.Scopes, ","),
+	}
+	if err := uc.clientRepo.Create(ctx, client); err != nil {
+		return nil, "", err
+	}
+	client.User = owner
+
+	err = recordAudit(ctx, uc.auditLogRepo, models.AuditActionOAuthClientCreated, adminID, owner.ID, map[string]interface{}{
+		"oauth_client_id": client.ID,
+		"client_id":       client.ClientID,
+		"name":            client.Name,
+		"scopes":          client.ScopeList(),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return client, secret, nil
+}
+
+// ListClients lists every OAuth client
+func (uc *OAuthClientUsecase) ListClients(ctx context.Context) ([]*models.OAuthClient, error) {
+	return uc.clientRepo.List(ctx)
+}
+
+// RotateSecret replaces the secret of a client and returns the new one.
+// Access tokens issued with the previous secret stay valid until they expire.
+func (uc *OAuthClientUsecase) RotateSecret(ctx context.Context, adminID, id int) (*models.OAuthClient, string, error) {
+	client, err := uc.findClient(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if client.IsRevoked() {
+		return nil, "", ErrOAuthClientNotFound
+	}
+
+	secret, err := auth.GenerateRandomToken(oauthClientSecretBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := uc.clientRepo.UpdateSecret(ctx, client.ID, auth.HashToken(secret)); err != nil {
+		return nil, "", err
+	}
+
+	err = recordAudit(ctx, uc.auditLogRepo, models.AuditActionOAuthClientRotated, adminID, client.UserID, map[string]interface{}{
+		"oauth_client_id": client.ID,
+		"client_id":       client.ClientID,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return client, secret, nil
+}
+
+// RevokeClient revokes a client. Its access tokens are refused from then on.
+func (uc *OAuthClientUsecase) RevokeClient(ctx context.Context, adminID, id int) error {
+	client, err := uc.findClient(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := uc.clientRepo.Revoke(ctx, client.ID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return nil
+	}
+
+	return recordAudit(ctx, uc.auditLogRepo, models.AuditActionOAuthClientRevoked, adminID, client.UserID, map[string]interface{}{
+		"oauth_client_id": client.ID,
+		"client_id":       client.ClientID,
+	})
+}
+
+// AuthenticateClient checks the credentials of a client calling the OAuth endpoints.
+// Every failure returns ErrInvalidClient.
+func (uc *OAuthClientUsecase) AuthenticateClient(ctx context.Context, clientID, secret string) (*models.OAuthClient, error) {
+	client, err := uc.clientRepo.FindByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil || client.IsRevoked() ||
+		subtle.ConstantTimeCompare([]byte(client.SecretHash), []byte(auth.HashToken(secret))) != 1 {
+		return nil, ErrInvalidClient
+	}
+
+	if _, err := uc.activeOwner(ctx, client); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// IssueToken issues an access token to an authenticated client with the client_credentials grant.
+// The requested scopes must have been granted to the client; none requests all of them.
+func (uc *OAuthClientUsecase) IssueToken(ctx context.Context, client *models.OAuthClient, requestedScopes []string) (*ClientToken, error) {
+	granted := client.ScopeList()
+	scopes := granted
+	if len(requestedScopes) > 0 {
+		for _, scope := range requestedScopes {
+			if !containsString(granted, scope) {
+				return nil, ErrInvalidScope
+			}
+		}
+		scopes = requestedScopes
+	}
+
+	owner, err := uc.activeOwner(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	scopes, err = uc.permissionUsecase.DelegatedPermissions(ctx, owner.RoleID, scopes)
+	if err != nil {
+		return nil, err
+	}
+	if len(scopes) == 0 {
+		return nil, ErrInvalidScope
+	}
+
+	accessToken, err := uc.jwtService.GenerateClientToken(client.ClientID, owner.ID, scopes, uc.tokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ClientToken{
+		AccessToken: accessToken,
+		ExpiresIn:   uc.tokenTTL,
+		Scopes:      scopes,
+	}, nil
+}
+
+// AuthenticateToken checks an access token presented to the API by a client and returns its
+// claims, the client and the permissions it grants: the token scopes that the client and the
+// owner's role still hold. Every failure returns ErrInvalidClient.
+func (uc *OAuthClientUsecase) AuthenticateToken(ctx context.Context, token string) (*auth.TokenClaims, *models.OAuthClient, []string, error) {
+	claims, err := uc.jwtService.ValidateClientToken(ctx, token)
+	if err != nil {
+		return nil, nil, nil, ErrInvalidClient
+	}
+
+	client, err := uc.clientRepo.FindByClientID(ctx, claims.ClientID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if client == nil || client.IsRevoked() || client.UserID != claims.UserID {
+		return nil, nil, nil, ErrInvalidClient
+	}
+
+	owner, err := uc.activeOwner(ctx, client)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var scopes []string
+	for _, scope := range strings.Fields(claims.Scope) {
+		if containsString(client.ScopeList(), scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	permissions, err := uc.permissionUsecase.DelegatedPermissions(ctx, owner.RoleID, scopes)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return claims, client, permissions, nil
+}
+
+// Introspect returns the claims of a token issued to the calling client, or nil when the
+// token is not active. Tokens of other clients are reported as inactive (RFC 7662).
+func (uc *OAuthClientUsecase) Introspect(ctx context.Context, caller *models.OAuthClient, token string) (*auth.TokenClaims, error) {
+	claims, _, _, err := uc.AuthenticateToken(ctx, token)
+	if errors.Is(err, ErrInvalidClient) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if claims.ClientID != caller.ClientID {
+		return nil, nil
+	}
+	return claims, nil
+}
+
+// RevokeToken revokes a token issued to the calling client. Unknown, expired and other
+// clients' tokens are ignored, as the response must not tell them apart (RFC 7009).
+func (uc *OAuthClientUsecase) RevokeToken(ctx context.Context, caller *models.OAuthClient, token string) error {
+	claims, err := uc.jwtService.ValidateClientToken(ctx, token)
+	if err != nil || claims.ClientID != caller.ClientID {
+		return nil
+	}
+	return uc.jwtService.RevokeToken(ctx, token)
+}
+
+// findClient loads a client or returns ErrOAuthClientNotFound
+func (uc *OAuthClientUsecase) findClient(ctx context.Context, id int) (*models.OAuthClient, error) {
+	client, err := uc.clientRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, ErrOAuthClientNotFound
+	}
+	return client, nil
+}
+
+// activeOwner loads the owner of a client, refusing clients of deleted or disabled accounts
+func (uc *OAuthClientUsecase) activeOwner(ctx context.Context, client *models.OAuthClient) (*models.User, error) {
+	owner, err := uc.userRepo.FindByID(ctx, client.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if owner == nil || !owner.CanLogin() {
+		return nil, ErrInvalidClient
+	}
+	return owner, nil
+}
@@ -0,0 +1,177 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/auth"
+	apperrors "github.com/vnlab/makeshop-payment/src/infrastructure/errors"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/mail"
+)
+
+// passwordResetTokenBytes is the entropy of a password reset token
+const passwordResetTokenBytes = 32
+
+// ErrInvalidPasswordResetToken is returned for unknown, expired or already used reset tokens
+var ErrInvalidPasswordResetToken = errors.New("invalid or expired password reset token")
+
+// PasswordResetConfig holds the settings of the password reset flow
+type PasswordResetConfig struct {
+	TokenTTL   time.Duration // Lifetime of a reset link
+	ResetURL   string        // Front-end page receiving the token
+	MaxPerHour int           // Maximum reset emails sent to the same user per hour
+}
+
+// PasswordResetUsecase handles recovery of forgotten passwords
+type PasswordResetUsecase struct {
+	userRepo       repositories.UserRepository
+	resetTokenRepo repositories.PasswordResetTokenRepository
+	auditLogRepo   repositories.AuditLogRepository
+	tokenUsecase   *TokenUsecase
+	mailer         mail.Mailer
+	mailTemplates  *mail.TemplateRenderer
+	config         PasswordResetConfig
+}
+
+// NewPasswordResetUseCase creates a new PasswordResetUsecase
+func NewPasswordResetUseCase(
+	userRepo repositories.UserRepository,
+	resetTokenRepo repositories.PasswordResetTokenRepository,
+	auditLogRepo repositories.AuditLogRepository,
+	tokenUsecase *TokenUsecase,
+	mailer mail.Mailer,
+	mailTemplates *mail.TemplateRenderer,
+	config PasswordResetConfig,
+) *PasswordResetUsecase {
+	return &PasswordResetUsecase{
+		userRepo:       userRepo,
+		resetTokenRepo: resetTokenRepo,
+		auditLogRepo:   auditLogRepo,
+		tokenUsecase:   tokenUsecase,
+		mailer:         mailer,
+		mailTemplates:  mailTemplates,
+		config:         config,
+	}
+}
+
+// RequestPasswordReset emails a reset link to the user with the given email.
+// It behaves the same whether or not the email exists so accounts cannot be enumerated:
+// the email is sent in the background and delivery errors are only logged.
+func (uc *PasswordResetUsecase) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := uc.userRepo.FindByEmail(ctx, strings.TrimSpace(email))
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+
+	count, err := uc.resetTokenRepo.CountCreatedSince(ctx, user.ID, time.Now().Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	if count >= int64(uc.config.MaxPerHour) {
+		return nil
+	}
+
+	token, err := auth.GenerateRandomToken(passwordResetTokenBytes)
+	if err != nil {
+		return err
+	}
+
+	err = uc.resetTokenRepo.Create(ctx, &models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: auth.HashToken(token),
+		IPAddress: ClientInfoFromContext(ctx).IPAddress,
+		ExpiresAt: time.Now().Add(uc.config.TokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	msg, err := uc.mailTemplates.Message(user.Email, mail.TemplatePasswordReset, map[string]interface{}{
+		"LastName":         user.LastName,
+		"FirstName":        user.FirstName,
+		"ResetURL":         uc.resetURL(token),
+		"ExpiresInMinutes": int(uc.config.TokenTTL.Minutes()),
+	})
+	if err != nil {
+		return err
+	}
+
+	sendMailInBackground(ctx, uc.mailer, msg)
+	return nil
+}
+
+// ResetPassword sets a new password with a reset token and ends every existing session
+func (uc *PasswordResetUsecase) ResetPassword(ctx context.Context, token, newPassword string) error {
+	resetToken, err := uc.resetTokenRepo.FindByHash(ctx, auth.HashToken(token))
+	if err != nil {
+		return err
+	}
+	if resetToken == nil || resetToken.IsUsed() || resetToken.IsExpired() {
+		return ErrInvalidPasswordResetToken
+	}
+
+	user, err := uc.userRepo.FindByID(ctx, resetToken.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrInvalidPasswordResetToken
+	}
+
+	if err := user.ChangePassword(newPassword); err != nil {
+		return apperrors.Validation(err.Error(), nil)
+	}
+
+	// Consume the token before saving so it cannot be used twice
+	used, err := uc.resetTokenRepo.MarkUsed(ctx, resetToken.ID)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidPasswordResetToken
+	}
+
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	if err := uc.tokenUsecase.RevokeAllForUser(ctx, user.ID); err != nil {
+		return err
+	}
+
+	err = recordAudit(ctx, uc.auditLogRepo, models.AuditActionPasswordReset, user.ID, user.ID, map[string]interface{}{
+		"reset_token_id": resetToken.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	msg, err := uc.mailTemplates.Message(user.Email, mail.TemplatePasswordChanged, map[string]interface{}{
+		"LastName":  user.LastName,
+		"FirstName": user.FirstName,
+		"ChangedAt": time.Now().Format("2006/01/02 15:04"),
+	})
+	if err != nil {
+		return err
+	}
+
+	sendMailInBackground(ctx, uc.mailer, msg)
+	return nil
+}
+
+// resetURL builds the link to the reset page carrying the token
+func (uc *PasswordResetUsecase) resetURL(token string) string {
+	separator := "?"
+	if strings.Contains(uc.config.ResetURL, "?") {
+		separator = "&"
+	}
+	return uc.config.ResetURL + separator + "token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,220 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+)
+
+// permissionCacheTTL bounds how long a replica serves role permissions changed on another replica
+const permissionCacheTTL = time.Minute
+
+// Permission errors
+var (
+	ErrRoleNotFound       = errors.New("role not found")
+	ErrPermissionNotFound = errors.New("permission not found")
+	ErrLastRoleManager    = errors.New("the system administrator role must keep the roles.manage permission")
+	ErrInvalidScope       = errors.New("scopes must be payment permissions granted to the owner's role")
+)
+
+// cachedPermissions are the permission codes of a role loaded at a given time
+type cachedPermissions struct {
+	codes    []string
+	loadedAt time.Time
+}
+
+// PermissionUsecase resolves and manages the permissions granted to roles
+type PermissionUsecase struct {
+	roleRepo       repositories.RoleRepository
+	permissionRepo repositories.PermissionRepository
+	auditLogRepo   repositories.AuditLogRepository
+
+	mu    sync.RWMutex
+	cache map[int]cachedPermissions
+}
+
+// NewPermissionUseCase creates a new PermissionUsecase
+func NewPermissionUseCase(
+	roleRepo repositories.RoleRepository,
+	permissionRepo repositories.PermissionRepository,
+	auditLogRepo repositories.AuditLogRepository,
+) *PermissionUsecase {
+	return &PermissionUsecase{
+		roleRepo:       roleRepo,
+		permissionRepo: permissionRepo,
+		auditLogRepo:   auditLogRepo,
+		cache:          make(map[int]cachedPermissions),
+	}
+}
+
+// PermissionCodes returns the permission codes granted to a role.
+// Results are cached for a short time since they are needed on every request.
+func (uc *PermissionUsecase) PermissionCodes(ctx context.Context, roleID int) ([]string, error) {
+	uc.mu.RLock()
+	cached, ok := uc.cache[roleID]
+	uc.mu.RUnlock()
+	if ok && time.Since(cached.loadedAt) < permissionCacheTTL {
+		return cached.codes, nil
+	}
+
+	permissions, err := uc.permissionRepo.ListByRole(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		codes = append(codes, permission.Code)
+	}
+
+	uc.mu.Lock()
+	uc.cache[roleID] = cachedPermissions{codes: codes, loadedAt: time.Now()}
+	uc.mu.Unlock()
+
+	return codes, nil
+}
+
+// ValidateScopes checks that scopes can be delegated by a role to an API key or OAuth client
+func (uc *PermissionUsecase) ValidateScopes(ctx context.Context, roleID int, scopes []string) error {
+	if len(scopes) == 0 {
+		return ErrInvalidScope
+	}
+
+	granted, err := uc.PermissionCodes(ctx, roleID)
+	if err != nil {
+		return err
+	}
+	for _, scope := range scopes {
+		if !models.IsDelegatedScope(scope) || !containsString(granted, scope) {
+			return ErrInvalidScope
+		}
+	}
+	return nil
+}
+
+// DelegatedPermissions returns the scopes delegated by a role that the role still holds,
+// so that revoking a permission from the role also takes it from its API keys and OAuth clients
+func (uc *PermissionUsecase) DelegatedPermissions(ctx context.Context, roleID int, scopes []string) ([]string, error) {
+	granted, err := uc.PermissionCodes(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if models.IsDelegatedScope(scope) && containsString(granted, scope) {
+			permissions = append(permissions, scope)
+		}
+	}
+	return permissions, nil
+}
+
+// ListPermissions lists every permission
+func (uc *PermissionUsecase) ListPermissions(ctx context.Context) ([]*models.Permission, error) {
+	return uc.permissionRepo.List(ctx)
+}
+
+// ListRoles lists every role
+func (uc *PermissionUsecase) ListRoles(ctx context.Context) ([]*models.Role, error) {
+	return uc.roleRepo.List(ctx)
+}
+
+// ListRolePermissions lists the permissions granted to a role
+func (uc *PermissionUsecase) ListRolePermissions(ctx context.Context, roleID int) ([]*models.Permission, error) {
+	return uc.permissionRepo.ListByRole(ctx, roleID)
+}
+
+// GrantPermission grants a permission to a role
+func (uc *PermissionUsecase) GrantPermission(ctx context.Context, adminID, roleID int, code string) (*models.Role, error) {
+	role, permission, err := uc.findRoleAndPermission(ctx, roleID, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.permissionRepo.Grant(ctx, role.ID, permission.ID); err != nil {
+		return nil, err
+	}
+	uc.invalidate(role.ID)
+
+	err = recordAuditEntry(ctx, uc.auditLogRepo, models.AuditActionPermissionGranted, &adminID, nil, map[string]interface{}{
+		"role_id":    role.ID,
+		"role_code":  role.Code,
+		"permission": permission.Code,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+// RevokePermission removes a permission from a role
+func (uc *PermissionUsecase) RevokePermission(ctx context.Context, adminID, roleID int, code string) (*models.Role, error) {
+	role, permission, err := uc.findRoleAndPermission(ctx, roleID, code)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep at least one role able to manage permissions
+	if role.IsAdmin() && permission.Code == models.PermissionRolesManage {
+		return nil, ErrLastRoleManager
+	}
+
+	if err := uc.permissionRepo.Revoke(ctx, role.ID, permission.ID); err != nil {
+		return nil, err
+	}
+	uc.invalidate(role.ID)
+
+	err = recordAuditEntry(ctx, uc.auditLogRepo, models.AuditActionPermissionRevoked, &adminID, nil, map[string]interface{}{
+		"role_id":    role.ID,
+		"role_code":  role.Code,
+		"permission": permission.Code,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+// findRoleAndPermission loads the role and permission of an assignment
+func (uc *PermissionUsecase) findRoleAndPermission(ctx context.Context, roleID int, code string) (*models.Role, *models.Permission, error) {
+	role, err := uc.roleRepo.FindByID(ctx, roleID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if role == nil {
+		return nil, nil, ErrRoleNotFound
+	}
+
+	permission, err := uc.permissionRepo.FindByCode(ctx, code)
+	if err != nil {
+		return nil, nil, err
+	}
+	if permission == nil {
+		return nil, nil, ErrPermissionNotFound
+	}
+
+	return role, permission, nil
+}
+
+// invalidate drops the cached permissions of a role on this replica
+func (uc *PermissionUsecase) invalidate(roleID int) {
+	uc.mu.Lock()
+	delete(uc.cache, roleID)
+	uc.mu.Unlock()
+}
+
+// containsString checks if a list contains a value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+)
+
+// sessionTouchInterval limits how often the last-seen time of a session is written
+const sessionTouchInterval = time.Minute
+
+// Session errors
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionRevoked  = errors.New("session has been revoked")
+)
+
+// SessionUsecase lets users see and end the sessions of their devices
+type SessionUsecase struct {
+	sessionRepo  repositories.SessionRepository
+	tokenUsecase *TokenUsecase
+}
+
+// NewSessionUseCase creates a new SessionUsecase
+func NewSessionUseCase(
+	sessionRepo repositories.SessionRepository,
+	tokenUsecase *TokenUsecase,
+) *SessionUsecase {
+	return &SessionUsecase{
+		sessionRepo:  sessionRepo,
+		tokenUsecase: tokenUsecase,
+	}
+}
+
+// ValidateSession checks that an access token's session is still active and records the activity
+func (uc *SessionUsecase) ValidateSession(ctx context.Context, userID int, sessionID string) error {
+	// Access tokens issued before sessions existed carry no session ID
+	if sessionID == "" {
+		return ErrSessionRevoked
+	}
+
+	session, err := uc.sessionRepo.FindByID(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if session == nil || session.UserID != userID || session.IsRevoked() {
+		return ErrSessionRevoked
+	}
+
+	if time.Since(session.LastSeenAt) < sessionTouchInterval {
+		return nil
+	}
+	return uc.sessionRepo.Touch(ctx, session.ID, ClientInfoFromContext(ctx).IPAddress, nil)
+}
+
+// ListSessions lists the active sessions of a user
+func (uc *SessionUsecase) ListSessions(ctx context.Context, userID int) ([]*models.Session, error) {
+	return uc.sessionRepo.ListActiveByUser(ctx, userID)
+}
+
+// RevokeSession ends one of the user's sessions
+func (uc *SessionUsecase) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	session, err := uc.sessionRepo.FindByID(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if session == nil || session.UserID != userID {
+		return ErrSessionNotFound
+	}
+
+	return uc.tokenUsecase.RevokeSession(ctx, session.ID)
+}
+
+// RevokeOtherSessions ends every session of the user except the current one and returns how many were ended
+func (uc *SessionUsecase) RevokeOtherSessions(ctx context.Context, userID int, currentSessionID string) (int, error) {
+	ids, err := uc.sessionRepo.RevokeAllForUser(ctx, userID, currentSessionID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		if err := uc.tokenUsecase.RevokeSession(ctx, id); err != nil {
+			return 0, err
+		}
+	}
+	return len(ids), nil
+}
@@ -0,0 +1,362 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/auth"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/oidc"
+)
+
+// Single sign-on errors
+var (
+	ErrSSODisabled     = errors.New("single sign-on is not enabled")
+	ErrInvalidSSOState = errors.New("single sign-on request is invalid or has expired")
+	ErrSSOFailed       = errors.New("single sign-on with the identity provider failed")
+	ErrSSODenied       = errors.New("your account is not allowed to sign in with single sign-on")
+)
+
+// SSORoleMapping grants a role to the members of an identity provider group
+type SSORoleMapping struct {
+	Group    string
+	RoleCode string
+}
+
+// ParseSSORoleMapping parses comma separated group=ROLE_CODE pairs.
+// The system administrator role cannot be granted by the identity provider.
+func ParseSSORoleMapping(value string) ([]SSORoleMapping, error) {
+	var mappings []SSORoleMapping
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		group, roleCode, found := strings.Cut(pair, "=")
+		group, roleCode = strings.TrimSpace(group), strings.TrimSpace(roleCode)
+		if !found || group == "" || roleCode == "" {
+			return nil, fmt.Errorf("invalid role mapping %q, expected group=ROLE_CODE", pair)
+		}
+		if roleCode == string(models.RoleCodeAdmin) {
+			return nil, fmt.Errorf("role mapping %q cannot grant %s", pair, roleCode)
+		}
+		mappings = append(mappings, SSORoleMapping{Group: group, RoleCode: roleCode})
+	}
+	return mappings, nil
+}
+
+// SSOConfig holds the single sign-on settings
+type SSOConfig struct {
+	RoleMapping []SSORoleMapping // Evaluated in order, the first group the user belongs to wins
+	StateTTL    time.Duration    // Time a sign-in started with the provider may take
+}
+
+// SSOLogin is a sign-in started with the identity provider
+type SSOLogin struct {
+	AuthorizationURL string // Page of the provider the user is sent to
+	State            string // Value the provider sends back, kept by the front-end to check the callback
+}
+
+// SSOUsecase signs users in with an external OpenID Connect provider. Provider accounts are
+// linked to users by verified email address, and unknown users are created on first sign-in.
+type SSOUsecase struct {
+	userRepo            repositories.UserRepository
+	roleRepo            repositories.RoleRepository
+	identityRepo        repositories.UserIdentityRepository
+	stateRepo           repositories.SSOStateRepository
+	auditLogRepo        repositories.AuditLogRepository
+	tokenUsecase        *TokenUsecase
+	mfaUsecase          *MFAUsecase
+	loginAttemptUsecase *LoginAttemptUsecase
+	provider            *oidc.Provider // nil when single sign-on is disabled
+	config              SSOConfig
+}
+
+// NewSSOUseCase creates a new SSOUsecase
+func NewSSOUseCase(
+	userRepo repositories.UserRepository,
+	roleRepo repositories.RoleRepository,
+	identityRepo repositories.UserIdentityRepository,
+	stateRepo repositories.SSOStateRepository,
+	auditLogRepo repositories.AuditLogRepository,
+	tokenUsecase *TokenUsecase,
+	mfaUsecase *MFAUsecase,
+	loginAttemptUsecase *LoginAttemptUsecase,
+	provider *oidc.Provider,
+	config SSOConfig,
+) *SSOUsecase {
+	return &SSOUsecase{
+		userRepo:            userRepo,
+		roleRepo:            roleRepo,
+		identityRepo:        identityRepo,
+		stateRepo:           stateRepo,
+		auditLogRepo:        auditLogRepo,
+		tokenUsecase:        tokenUsecase,
+		mfaUsecase:          mfaUsecase,
+		loginAttemptUsecase: loginAttemptUsecase,
+		provider:            provider,
+		config:              config,
+	}
+}
+
+// BeginLogin starts a sign-in with the identity provider and returns the URL to send the user to
+func (uc *SSOUsecase) BeginLogin(ctx context.Context) (*SSOLogin, error) {
+	if uc.provider == nil {
+		return nil, ErrSSODisabled
+	}
+
+	state, err := auth.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := auth.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	codeVerifier, codeChallenge, err := oidc.NewPKCE()
+	if err != nil {
+		return nil, err
+	}
+
+	authorizationURL, err := uc.provider.AuthCodeURL(ctx, state, nonce, codeChallenge)
+	if err != nil {
+		log.Printf("Failed to start single sign-on: %v", err)
+		return nil, ErrSSOFailed
+	}
+
+	err = uc.stateRepo.Create(ctx, &models.SSOState{
+		StateHash:    auth.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(uc.config.StateTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &SSOLogin{
+		AuthorizationURL: authorizationURL,
+		State:            state,
+	}, nil
+}
+
+// CompleteLogin finishes a sign-in with the code the identity provider sent back.
+// Like a password login it starts an MFA challenge when the user has MFA configured.
+func (uc *SSOUsecase) CompleteLogin(ctx context.Context, code, state string) (*LoginResponse, error) {
+	if uc.provider == nil {
+		return nil, ErrSSODisabled
+	}
+	if err := uc.loginAttemptUsecase.CheckIP(ctx, ""); err != nil {
+		return nil, err
+	}
+
+	ssoState, err := uc.stateRepo.FindByHash(ctx, auth.HashToken(state))
+	if err != nil {
+		return nil, err
+	}
+	if ssoState == nil || ssoState.IsUsed() || ssoState.IsExpired() {
+		return nil, ErrInvalidSSOState
+	}
+	// A state completes a single sign-in, even when the code turns out to be invalid
+	used, err := uc.stateRepo.MarkUsed(ctx, ssoState.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, ErrInvalidSSOState
+	}
+
+	rawIDToken, err := uc.provider.Exchange(ctx, code, ssoState.CodeVerifier)
+	if err != nil {
+		log.Printf("Failed to redeem single sign-on code: %v", err)
+		return nil, ErrSSOFailed
+	}
+	idToken, err := uc.provider.VerifyIDToken(ctx, rawIDToken, ssoState.Nonce)
+	if err != nil {
+		log.Printf("Refused single sign-on ID token: %v", err)
+		return nil, ErrSSOFailed
+	}
+
+	user, identity, err := uc.resolveUser(ctx, idToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsDisabled() {
+		if err := uc.loginAttemptUsecase.RecordFailure(ctx, user.Email, user, models.LoginFailureAccountDisabled); err != nil {
+			return nil, err
+		}
+		return nil, ErrSSODenied
+	}
+	if user.IsLocked() {
+		if err := uc.loginAttemptUsecase.RecordFailure(ctx, user.Email, user, models.LoginFailureAccountLocked); err != nil {
+			return nil, err
+		}
+		return nil, ErrSSODenied
+	}
+
+	if err := uc.identityRepo.TouchLastLogin(ctx, identity.ID); err != nil {
+		return nil, err
+	}
+
+	// The failure counter is only cleared once the second factor is verified
+	if user.RequiresMFA() {
+		return uc.mfaUsecase.StartChallenge(ctx, user)
+	}
+
+	if err := uc.loginAttemptUsecase.RecordSuccess(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return uc.tokenUsecase.IssueTokens(ctx, user)
+}
+
+// resolveUser finds the user of a provider account, linking it to an existing user with the
+// same verified email address or creating a new user, and applies the role mapping
+func (uc *SSOUsecase) resolveUser(ctx context.Context, idToken *oidc.IDToken) (*models.User, *models.UserIdentity, error) {
+	role, err := uc.mappedRole(ctx, idToken.Groups)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	identity, err := uc.identityRepo.FindByIssuerAndSubject(ctx, idToken.Issuer, idToken.Subject)
+	if err != nil {
+		return nil, nil, err
+	}
+	if identity != nil {
+		user, err := uc.userRepo.FindByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if user == nil {
+			return nil, nil, uc.deny(ctx, idToken.Email, nil, "the linked account has been deleted")
+		}
+		if err := uc.syncRole(ctx, user, role); err != nil {
+			return nil, nil, err
+		}
+		return user, identity, nil
+	}
+
+	// Accounts are only linked by an address the provider vouches for,
+	// or anyone able to set that address at the provider could take them over
+	email := strings.ToLower(idToken.Email)
+	if email == "" || !idToken.EmailVerified {
+		return nil, nil, uc.deny(ctx, idToken.Email, nil, "the provider did not verify the email address")
+	}
+
+	user, err := uc.userRepo.FindByEmailIncludingDeleted(ctx, email)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	action := models.AuditActionSSOLinked
+	if user != nil {
+		if user.IsDeleted() {
+			return nil, nil, uc.deny(ctx, email, nil, "the account with this email address has been deleted")
+		}
+		if err := uc.syncRole(ctx, user, role); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		if role == nil {
+			return nil, nil, uc.deny(ctx, email, nil, "no group of the user is mapped to a role")
+		}
+		user, err = models.NewSSOUser(email, truncate(idToken.GivenName, 100), truncate(idToken.FamilyName, 100), role.ID)
+		if err != nil {
+			return nil, nil, uc.deny(ctx, email, nil, err.Error())
+		}
+		if err := uc.userRepo.Create(ctx, user); err != nil {
+			return nil, nil, err
+		}
+		// Reload user to get the role relationship
+		user, err = uc.userRepo.FindByID(ctx, user.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		action = models.AuditActionSSOProvisioned
+	}
+
+	identity = &models.UserIdentity{
+		UserID:  user.ID,
+		Issuer:  idToken.Issuer,
+		Subject: idToken.Subject,
+		Email:   truncate(email, 255),
+	}
+	if err := uc.identityRepo.Create(ctx, identity); err != nil {
+		return nil, nil, err
+	}
+
+	err = recordAudit(ctx, uc.auditLogRepo, action, user.ID, user.ID, map[string]interface{}{
+		"issuer":  identity.Issuer,
+		"subject": identity.Subject,
+		"role_id": user.RoleID,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, identity, nil
+}
+
+// syncRole gives a user the role mapped from their groups. System administrators keep their
+// role whatever their groups; other users must belong to a mapped group.
+// A role change ends every session so the new permissions apply at once.
+func (uc *SSOUsecase) syncRole(ctx context.Context, user *models.User, role *models.Role) error {
+	if user.Role != nil && user.Role.IsAdmin() {
+		return nil
+	}
+	if role == nil {
+		return uc.deny(ctx, user.Email, user, "no group of the user is mapped to a role")
+	}
+	if role.ID == user.RoleID {
+		return nil
+	}
+
+	previousRoleID := user.RoleID
+	user.RoleID = role.ID
+	user.Role = role
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+	if err := uc.tokenUsecase.RevokeAllForUser(ctx, user.ID); err != nil {
+		return err
+	}
+
+	return recordAudit(ctx, uc.auditLogRepo, models.AuditActionUserUpdated, user.ID, user.ID, map[string]interface{}{
+		"previous_role_id": previousRoleID,
+		"role_id":          role.ID,
+		"source":           "sso",
+	})
+}
+
+// mappedRole returns the role of the first mapped group the user belongs to, or nil
+func (uc *SSOUsecase) mappedRole(ctx context.Context, groups []string) (*models.Role, error) {
+	for _, mapping := range uc.config.RoleMapping {
+		if !containsString(groups, mapping.Group) {
+			continue
+		}
+		role, err := uc.roleRepo.FindByCode(ctx, mapping.RoleCode)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			return nil, fmt.Errorf("role %s of SSO group %s not found", mapping.RoleCode, mapping.Group)
+		}
+		return role, nil
+	}
+	return nil, nil
+}
+
+// deny records a refused sign-in and returns ErrSSODenied. The reason is only logged.
+func (uc *SSOUsecase) deny(ctx context.Context, email string, user *models.User, reason string) error {
+	log.Printf("Refused single sign-on of %q: %s", email, reason)
+	if err := uc.loginAttemptUsecase.RecordFailure(ctx, email, user, models.LoginFailureSSODenied); err != nil {
+		return err
+	}
+	return ErrSSODenied
+}
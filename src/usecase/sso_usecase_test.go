@@ -0,0 +1,306 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/oidc"
+)
+
+// ssoTest is a SSOUsecase signing in against a MockServer
+type ssoTest struct {
+	*fixture
+	usecase    *SSOUsecase
+	issuer     string
+	identities *fakeIdentityRepo
+}
+
+// newSSOTest starts a MockServer signing the configured user in. The "payments-business"
+// group is mapped to the business role and "payments-general" to the general role.
+func newSSOTest(t *testing.T, mockConfig oidc.MockConfig) *ssoTest {
+	t.Helper()
+
+	var mock *oidc.MockServer
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mock.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	mockConfig.Issuer = server.URL
+	mockConfig.ClientID = "makeshop-payment"
+	mockConfig.ClientSecret = "secret"
+	var err error
+	if mock, err = oidc.NewMockServer(mockConfig); err != nil {
+		t.Fatalf("NewMockServer: %v", err)
+	}
+	provider := oidc.NewProvider(oidc.Config{
+		IssuerURL:    server.URL,
+		ClientID:     mockConfig.ClientID,
+		ClientSecret: mockConfig.ClientSecret,
+		RedirectURL:  "http://localhost:3000/sso/callback",
+		Scopes:       []string{"email", "profile"},
+	})
+
+	test := &ssoTest{
+		fixture:    newFixture(t),
+		issuer:     server.URL,
+		identities: &fakeIdentityRepo{},
+	}
+	test.usecase = NewSSOUseCase(
+		test.users,
+		test.roles,
+		test.identities,
+		&fakeSSOStateRepo{},
+		test.auditLogs,
+		test.tokenUsecase(),
+		nil, // None of the users has MFA configured
+		test.loginAttemptUsecase(LoginProtectionConfig{}),
+		provider,
+		SSOConfig{
+			RoleMapping: []SSORoleMapping{
+				{Group: "payments-business", RoleCode: test.businessRole.Code},
+				{Group: "payments-general", RoleCode: test.generalRole.Code},
+			},
+			StateTTL: 10 * time.Minute,
+		},
+	)
+	return test
+}
+
+// login signs in at the provider and completes the login with the code it sends back
+func (s *ssoTest) login(t *testing.T) (*LoginResponse, error) {
+	t.Helper()
+	ctx := context.Background()
+
+	login, err := s.usecase.BeginLogin(ctx)
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(login.AuthorizationURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: status %d, location %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if callback.Query().Get("state") != login.State {
+		t.Fatalf("provider returned state %q, expected %q", callback.Query().Get("state"), login.State)
+	}
+
+	return s.usecase.CompleteLogin(ctx, callback.Query().Get("code"), login.State)
+}
+
+func TestSSOCompleteLoginProvisionsUser(t *testing.T) {
+	test := newSSOTest(t, oidc.MockConfig{
+		Email:      "Jane@Example.com",
+		GivenName:  "Jane",
+		FamilyName: "Doe",
+		Groups:     []string{"staff", "payments-business"},
+	})
+
+	resp, err := test.login(t)
+	if err != nil {
+		t.Fatalf("CompleteLogin: %v", err)
+	}
+	if resp.Token == "" || resp.RefreshToken == "" || resp.MFARequired {
+		t.Errorf("expected tokens, got %+v", resp)
+	}
+	user := resp.User
+	if user == nil || user.Email != "jane@example.com" || user.RoleID != test.businessRole.ID || user.EmailVerifiedAt == nil {
+		t.Fatalf("unexpected user %+v", user)
+	}
+	if len(test.identities.identities) != 1 || test.identities.identities[0].UserID != user.ID {
+		t.Fatalf("expected the provider account to be linked, got %+v", test.identities.identities)
+	}
+	if test.auditLogs.actions[0] != models.AuditActionSSOProvisioned {
+		t.Errorf("expected %s to be audited, got %v", models.AuditActionSSOProvisioned, test.auditLogs.actions)
+	}
+
+	// The next sign-in uses the linked identity
+	resp, err = test.login(t)
+	if err != nil {
+		t.Fatalf("second CompleteLogin: %v", err)
+	}
+	if resp.User.ID != user.ID || len(test.users.users) != 1 || len(test.identities.identities) != 1 {
+		t.Errorf("expected the same user to sign in again")
+	}
+	if test.identities.identities[0].LastLoginAt == nil {
+		t.Errorf("expected the last login of the identity to be recorded")
+	}
+	if test.sessions.created != 2 {
+		t.Errorf("expected a session per sign-in, got %d", test.sessions.created)
+	}
+}
+
+func TestSSOCompleteLoginLinksVerifiedEmail(t *testing.T) {
+	test := newSSOTest(t, oidc.MockConfig{Email: "jane@example.com", Groups: []string{"payments-business"}})
+	existing := test.addUser(t, "jane@example.com", test.businessRole)
+
+	resp, err := test.login(t)
+	if err != nil {
+		t.Fatalf("CompleteLogin: %v", err)
+	}
+	if resp.User.ID != existing.ID || len(test.users.users) != 1 {
+		t.Fatalf("expected the existing user to be linked, got %+v", resp.User)
+	}
+	if test.auditLogs.actions[0] != models.AuditActionSSOLinked {
+		t.Errorf("expected %s to be audited, got %v", models.AuditActionSSOLinked, test.auditLogs.actions)
+	}
+}
+
+func TestSSOCompleteLoginRefusesUnverifiedEmail(t *testing.T) {
+	for _, verified := range []interface{}{false, "false", nil} {
+		test := newSSOTest(t, oidc.MockConfig{
+			Email:       "jane@example.com",
+			Groups:      []string{"payments-business"},
+			ExtraClaims: map[string]interface{}{"email_verified": verified},
+		})
+		existing := test.addUser(t, "jane@example.com", test.businessRole)
+
+		if _, err := test.login(t); !errors.Is(err, ErrSSODenied) {
+			t.Fatalf("email_verified %v: expected ErrSSODenied, got %v", verified, err)
+		}
+		if len(test.identities.identities) != 0 {
+			t.Errorf("email_verified %v: the provider account was linked to user %d", verified, existing.ID)
+		}
+		if len(test.users.users) != 1 {
+			t.Errorf("email_verified %v: a user was created", verified)
+		}
+		if test.lastFailure() != models.LoginFailureSSODenied {
+			t.Errorf("email_verified %v: expected a refused attempt, got %q", verified, test.lastFailure())
+		}
+	}
+}
+
+func TestSSOCompleteLoginRefusesInvalidIDToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+	}{
+		{name: "nonce mismatch", claims: map[string]interface{}{"nonce": "replayed-nonce"}},
+		{name: "other audience", claims: map[string]interface{}{"aud": "other-client"}},
+		{name: "other authorized party", claims: map[string]interface{}{"azp": "other-client"}},
+		{name: "several audiences", claims: map[string]interface{}{"aud": []string{"makeshop-payment", "other-client"}}},
+		{name: "expired", claims: map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test := newSSOTest(t, oidc.MockConfig{
+				Email:       "jane@example.com",
+				Groups:      []string{"payments-business"},
+				ExtraClaims: tt.claims,
+			})
+			if _, err := test.login(t); !errors.Is(err, ErrSSOFailed) {
+				t.Fatalf("expected ErrSSOFailed, got %v", err)
+			}
+			if len(test.users.users) != 0 || len(test.identities.identities) != 0 {
+				t.Errorf("a user was signed in with a refused ID token")
+			}
+		})
+	}
+}
+
+func TestSSOCompleteLoginRefusesReusedState(t *testing.T) {
+	test := newSSOTest(t, oidc.MockConfig{Email: "jane@example.com", Groups: []string{"payments-business"}})
+	ctx := context.Background()
+
+	login, err := test.usecase.BeginLogin(ctx)
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	// The code is invalid, but the state is spent anyway
+	if _, err := test.usecase.CompleteLogin(ctx, "invalid-code", login.State); !errors.Is(err, ErrSSOFailed) {
+		t.Fatalf("expected ErrSSOFailed, got %v", err)
+	}
+	if _, err := test.usecase.CompleteLogin(ctx, "invalid-code", login.State); !errors.Is(err, ErrInvalidSSOState) {
+		t.Fatalf("expected ErrInvalidSSOState, got %v", err)
+	}
+	if _, err := test.usecase.CompleteLogin(ctx, "invalid-code", "unknown-state"); !errors.Is(err, ErrInvalidSSOState) {
+		t.Fatalf("expected ErrInvalidSSOState for an unknown state, got %v", err)
+	}
+}
+
+func TestSSOCompleteLoginMapsGroupsToRoles(t *testing.T) {
+	// The first mapped group the user belongs to wins
+	test := newSSOTest(t, oidc.MockConfig{Email: "jane@example.com", Groups: []string{"payments-general", "payments-business"}})
+	existing := test.addUser(t, "jane@example.com", test.generalRole)
+
+	resp, err := test.login(t)
+	if err != nil {
+		t.Fatalf("CompleteLogin: %v", err)
+	}
+	if resp.User.RoleID != test.businessRole.ID || existing.RoleID != test.businessRole.ID {
+		t.Fatalf("expected the business role, got role %d", resp.User.RoleID)
+	}
+	if len(test.sessions.revokedForUser) != 1 || test.sessions.revokedForUser[0] != existing.ID {
+		t.Errorf("expected the sessions of the user to be revoked on the role change")
+	}
+	if test.auditLogs.actions[0] != models.AuditActionUserUpdated {
+		t.Errorf("expected the role change to be audited, got %v", test.auditLogs.actions)
+	}
+}
+
+func TestSSOCompleteLoginKeepsAdminRole(t *testing.T) {
+	test := newSSOTest(t, oidc.MockConfig{Email: "admin@example.com", Groups: []string{"payments-general"}})
+	admin := test.addUser(t, "admin@example.com", test.adminRole)
+
+	resp, err := test.login(t)
+	if err != nil {
+		t.Fatalf("CompleteLogin: %v", err)
+	}
+	if resp.User.ID != admin.ID || resp.User.RoleID != test.adminRole.ID {
+		t.Errorf("expected the administrator to keep their role, got role %d", resp.User.RoleID)
+	}
+	if len(test.sessions.revokedForUser) != 0 {
+		t.Errorf("expected no session to be revoked")
+	}
+}
+
+func TestSSOCompleteLoginDeniesUnmappedGroups(t *testing.T) {
+	t.Run("new user", func(t *testing.T) {
+		test := newSSOTest(t, oidc.MockConfig{Email: "jane@example.com", Groups: []string{"staff"}})
+
+		if _, err := test.login(t); !errors.Is(err, ErrSSODenied) {
+			t.Fatalf("expected ErrSSODenied, got %v", err)
+		}
+		if len(test.users.users) != 0 || len(test.identities.identities) != 0 {
+			t.Errorf("a user was provisioned without a mapped group")
+		}
+		if test.lastFailure() != models.LoginFailureSSODenied {
+			t.Errorf("expected a refused attempt, got %q", test.lastFailure())
+		}
+	})
+
+	t.Run("linked user removed from their group", func(t *testing.T) {
+		test := newSSOTest(t, oidc.MockConfig{Email: "jane@example.com", Groups: []string{"staff"}})
+		existing := test.addUser(t, "jane@example.com", test.businessRole)
+		_ = test.identities.Create(context.Background(), &models.UserIdentity{
+			UserID:  existing.ID,
+			Issuer:  test.issuer,
+			Subject: "mock|jane@example.com",
+		})
+
+		if _, err := test.login(t); !errors.Is(err, ErrSSODenied) {
+			t.Fatalf("expected ErrSSODenied, got %v", err)
+		}
+		if existing.RoleID != test.businessRole.ID {
+			t.Errorf("the role of the denied user changed to %d", existing.RoleID)
+		}
+		if test.sessions.created != 0 {
+			t.Errorf("a session was started for the denied user")
+		}
+	})
+}
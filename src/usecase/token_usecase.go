@@ -0,0 +1,240 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	models "github.com/vnlab/makeshop-payment/src/domain/models"
+	"github.com/vnlab/makeshop-payment/src/domain/repositories"
+	"github.com/vnlab/makeshop-payment/src/infrastructure/auth"
+	"github.com/vnlab/makeshop-payment/src/lib/useragent"
+)
+
+// refreshTokenBytes is the entropy of an opaque refresh token
+const refreshTokenBytes = 32
+
+// ErrInvalidRefreshToken is returned for unknown, expired, revoked or replayed refresh tokens
+var ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+// TokenUsecase issues access tokens together with rotating refresh tokens
+type TokenUsecase struct {
+	userRepo         repositories.UserRepository
+	refreshTokenRepo repositories.RefreshTokenRepository
+	sessionRepo      repositories.SessionRepository
+	auditLogRepo     repositories.AuditLogRepository
+	jwtService       *auth.JWTService
+	refreshTokenTTL  time.Duration
+}
+
+// NewTokenUseCase creates a new TokenUsecase
+func NewTokenUseCase(
+	userRepo repositories.UserRepository,
+	refreshTokenRepo repositories.RefreshTokenRepository,
+	sessionRepo repositories.SessionRepository,
+	auditLogRepo repositories.AuditLogRepository,
+	jwtService *auth.JWTService,
+	refreshTokenTTL time.Duration,
+) *TokenUsecase {
+	return &TokenUsecase{
+		userRepo:         userRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		sessionRepo:      sessionRepo,
+		auditLogRepo:     auditLogRepo,
+		jwtService:       jwtService,
+		refreshTokenTTL:  refreshTokenTTL,
+	}
+}
+
+// IssueTokens starts a new session for a user who just logged in.
+// The session ID is also the family ID of its refresh tokens.
+func (uc *TokenUsecase) IssueTokens(ctx context.Context, user *models.User) (*LoginResponse, error) {
+	client := ClientInfoFromContext(ctx)
+	now := time.Now()
+	session := &models.Session{
+		ID:          uuid.NewString(),
+		UserID:      user.ID,
+		DeviceLabel: truncate(useragent.DeviceLabel(client.UserAgent), 100),
+		UserAgent:   truncate(client.UserAgent, 255),
+		IPAddress:   client.IPAddress,
+		LastSeenAt:  now,
+		ExpiresAt:   now.Add(uc.refreshTokenTTL),
+	}
+	if err := uc.sessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return uc.issueTokens(ctx, user, session.ID, nil)
+}
+
+// IssueImpersonationToken starts a session in which actor acts as user.
+// Only an access token is issued, so the session ends when it expires.
+func (uc *TokenUsecase) IssueImpersonationToken(ctx context.Context, user, actor *models.User, duration time.Duration) (*LoginResponse, error) {
+	client := ClientInfoFromContext(ctx)
+	now := time.Now()
+	session := &models.Session{
+		ID:             uuid.NewString(),
+		UserID:         user.ID,
+		DeviceLabel:    truncate(useragent.DeviceLabel(client.UserAgent), 100),
+		UserAgent:      truncate(client.UserAgent, 255),
+		IPAddress:      client.IPAddress,
+		LastSeenAt:     now,
+		ExpiresAt:      now.Add(duration),
+		ImpersonatorID: &actor.ID,
+	}
+	if err := uc.sessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := uc.jwtService.GenerateImpersonationToken(user, actor, session.ID, duration)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResponse{
+		Token:     accessToken,
+		ExpiresAt: &session.ExpiresAt,
+		User:      user,
+	}, nil
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh token.
+// Replaying a token that was already exchanged revokes its whole family, since either
+// the legitimate client or an attacker holds a stolen copy.
+func (uc *TokenUsecase) Refresh(ctx context.Context, refreshToken string) (*LoginResponse, error) {
+	token, err := uc.refreshTokenRepo.FindByHash(ctx, auth.HashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || token.IsRevoked() || token.IsExpired() {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if token.IsRotated() {
+		return nil, uc.handleReuse(ctx, token)
+	}
+
+	// Guard against two concurrent exchanges of the same token
+	rotated, err := uc.refreshTokenRepo.MarkRotated(ctx, token.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		return nil, uc.handleReuse(ctx, token)
+	}
+
+	session, err := uc.sessionRepo.FindByID(ctx, token.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil || session.IsRevoked() {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := uc.userRepo.FindByID(ctx, token.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.CanLogin() {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	resp, err := uc.issueTokens(ctx, user, session.ID, &token.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A session lives as long as its latest refresh token
+	expiresAt := time.Now().Add(uc.refreshTokenTTL)
+	if err := uc.sessionRepo.Touch(ctx, session.ID, ClientInfoFromContext(ctx).IPAddress, &expiresAt); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Revoke ends the session of a user's refresh token, e.g. on logout.
+// Unknown tokens are ignored so logout never fails on a stale client.
+func (uc *TokenUsecase) Revoke(ctx context.Context, userID int, refreshToken string) error {
+	token, err := uc.refreshTokenRepo.FindByHash(ctx, auth.HashToken(refreshToken))
+	if err != nil {
+		return err
+	}
+	if token == nil || token.UserID != userID {
+		return nil
+	}
+	return uc.RevokeSession(ctx, token.FamilyID)
+}
+
+// RevokeSession ends a session: access tokens carrying its ID are refused and
+// its refresh tokens can no longer be exchanged
+func (uc *TokenUsecase) RevokeSession(ctx context.Context, sessionID string) error {
+	if _, err := uc.sessionRepo.Revoke(ctx, sessionID); err != nil {
+		return err
+	}
+	return uc.refreshTokenRepo.RevokeFamily(ctx, sessionID)
+}
+
+// RevokeAllForUser revokes every session, access and refresh token of a user, e.g. after a password change
+func (uc *TokenUsecase) RevokeAllForUser(ctx context.Context, userID int) error {
+	if _, err := uc.sessionRepo.RevokeAllForUser(ctx, userID, ""); err != nil {
+		return err
+	}
+	if err := uc.refreshTokenRepo.RevokeAllForUser(ctx, userID); err != nil {
+		return err
+	}
+	return uc.jwtService.RevokeAllForUser(ctx, userID)
+}
+
+// issueTokens signs an access token and stores a new refresh token for the given session
+func (uc *TokenUsecase) issueTokens(ctx context.Context, user *models.User, sessionID string, parentID *int) (*LoginResponse, error) {
+	accessToken, err := uc.jwtService.GenerateToken(user, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := auth.GenerateRandomToken(refreshTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	client := ClientInfoFromContext(ctx)
+	now := time.Now()
+	err = uc.refreshTokenRepo.Create(ctx, &models.RefreshToken{
+		UserID:     user.ID,
+		FamilyID:   sessionID,
+		ParentID:   parentID,
+		TokenHash:  auth.HashToken(refreshToken),
+		DeviceInfo: truncate(client.UserAgent, 255),
+		IPAddress:  client.IPAddress,
+		ExpiresAt:  now.Add(uc.refreshTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := now.Add(uc.jwtService.TokenDuration())
+	return &LoginResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    &expiresAt,
+		User:         user,
+	}, nil
+}
+
+// handleReuse ends the session of a replayed refresh token and records the incident
+func (uc *TokenUsecase) handleReuse(ctx context.Context, token *models.RefreshToken) error {
+	if err := uc.RevokeSession(ctx, token.FamilyID); err != nil {
+		return err
+	}
+
+	err := recordAudit(ctx, uc.auditLogRepo, models.AuditActionRefreshTokenReused, token.UserID, token.UserID, map[string]interface{}{
+		"family_id": token.FamilyID,
+		"token_id":  token.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return ErrInvalidRefreshToken
+}
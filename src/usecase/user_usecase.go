@@ -3,17 +3,40 @@ package usecase
 import (
 	"context"
 	"errors"
+	"sync"
+	"time"
 
 	models "github.com/vnlab/makeshop-payment/src/domain/models"
 	"github.com/vnlab/makeshop-payment/src/domain/repositories"
 	"github.com/vnlab/makeshop-payment/src/infrastructure/auth"
+	apperrors "github.com/vnlab/makeshop-payment/src/infrastructure/errors"
+	"github.com/vnlab/makeshop-payment/src/lib/validator"
+	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	dummyPasswordHash     []byte
+	dummyPasswordHashOnce sync.Once
+)
+
+// verifyDummyPassword runs a bcrypt comparison that always fails, taking as long as a real password check
+func verifyDummyPassword(password string) {
+	dummyPasswordHashOnce.Do(func() {
+		dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	})
+	_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+}
+
 // UserUsecase handles user-related business logic
 type UserUsecase struct {
-	userRepo    repositories.UserRepository
-	roleRepo    repositories.RoleRepository
-	jwtService  *auth.JWTService
+	userRepo     repositories.UserRepository
+	roleRepo     repositories.RoleRepository
+	jwtService   *auth.JWTService
+	tokenUsecase *TokenUsecase
+	mfaUsecase   *MFAUsecase
+
+	emailVerificationUsecase *EmailVerificationUsecase
+	loginAttemptUsecase      *LoginAttemptUsecase
 }
 
 // NewUserUseCase creates a new UserUsecase
@@ -21,11 +44,20 @@ func NewUserUseCase(
 	userRepo repositories.UserRepository,
 	roleRepo repositories.RoleRepository,
 	jwtService *auth.JWTService,
+	tokenUsecase *TokenUsecase,
+	mfaUsecase *MFAUsecase,
+	emailVerificationUsecase *EmailVerificationUsecase,
+	loginAttemptUsecase *LoginAttemptUsecase,
 ) *UserUsecase {
 	return &UserUsecase{
-		userRepo:    userRepo,
-		roleRepo:    roleRepo,
-		jwtService:  jwtService,
+		userRepo:     userRepo,
+		roleRepo:     roleRepo,
+		jwtService:   jwtService,
+		tokenUsecase: tokenUsecase,
+		mfaUsecase:   mfaUsecase,
+
+		emailVerificationUsecase: emailVerificationUsecase,
+		loginAttemptUsecase:      loginAttemptUsecase,
 	}
 }
 
@@ -53,47 +85,92 @@ type UpdateProfileRequest struct {
 	LastNameKana  string `json:"last_name_kana" binding:"required"`
 }
 
-// LoginResponse represents a login response with token
+// LoginResponse represents a login response with an access token and a refresh token.
+// When MFARequired is set, the tokens and User are empty and MFAToken must be
+// exchanged through MFA verification.
 type LoginResponse struct {
-	Token string         `json:"token"`
-	User  *models.User `json:"user"`
+	Token        string          `json:"token,omitempty"`
+	RefreshToken string          `json:"refresh_token,omitempty"`
+	ExpiresAt    *time.Time      `json:"expires_at,omitempty"`
+	User         *models.User    `json:"user,omitempty"`
+	MFARequired  bool            `json:"mfa_required"`
+	MFAToken     string          `json:"mfa_token,omitempty"`
+	MFAType      *models.MFAType `json:"mfa_type,omitempty"`
 }
 
-// Login authenticates a user and returns a JWT token
+// Login authenticates a user and returns an access token and a refresh token.
+// Every credential failure returns the same error so callers cannot tell
+// unknown accounts, wrong passwords and locked or disabled accounts apart.
 func (uc *UserUsecase) Login(ctx context.Context, req LoginRequest) (*LoginResponse, error) {
+	if err := uc.loginAttemptUsecase.CheckIP(ctx, req.Email); err != nil {
+		return nil, err
+	}
+
 	user, err := uc.userRepo.FindByEmail(ctx, req.Email)
 	if err != nil {
 		return nil, err
 	}
 
 	if user == nil {
-		return nil, errors.New("invalid email or password 1")
+		// Spend the same time as a password check so unknown emails cannot be detected
+		verifyDummyPassword(req.Password)
+		if err := uc.loginAttemptUsecase.RecordFailure(ctx, req.Email, nil, models.LoginFailureUnknownUser); err != nil {
+			return nil, err
+		}
+		return nil, apperrors.InvalidCredentials("")
+	}
+
+	if user.IsDisabled() {
+		verifyDummyPassword(req.Password)
+		if err := uc.loginAttemptUsecase.RecordFailure(ctx, req.Email, user, models.LoginFailureAccountDisabled); err != nil {
+			return nil, err
+		}
+		return nil, apperrors.InvalidCredentials("")
+	}
+
+	if user.IsLocked() {
+		verifyDummyPassword(req.Password)
+		if err := uc.loginAttemptUsecase.RecordFailure(ctx, req.Email, user, models.LoginFailureAccountLocked); err != nil {
+			return nil, err
+		}
+		return nil, apperrors.InvalidCredentials("")
 	}
 
 	if !user.VerifyPassword(req.Password) {
-		return nil, errors.New("invalid email or password 2")
+		if err := uc.loginAttemptUsecase.RecordFailure(ctx, req.Email, user, models.LoginFailureBadPassword); err != nil {
+			return nil, err
+		}
+		return nil, apperrors.InvalidCredentials("")
 	}
 
-	token, err := uc.jwtService.GenerateToken(user)
-	if err != nil {
+	if uc.emailVerificationUsecase.RequiresVerification(user) {
+		if err := uc.loginAttemptUsecase.RecordFailure(ctx, req.Email, user, models.LoginFailureEmailUnverified); err != nil {
+			return nil, err
+		}
+		return nil, ErrEmailNotVerified
+	}
+
+	// The failure counter is only cleared once the second factor is verified
+	if user.RequiresMFA() {
+		return uc.mfaUsecase.StartChallenge(ctx, user)
+	}
+
+	if err := uc.loginAttemptUsecase.RecordSuccess(ctx, user); err != nil {
 		return nil, err
 	}
 
-	return &LoginResponse{
-		Token: token,
-		User:  user,
-	}, nil
+	return uc.tokenUsecase.IssueTokens(ctx, user)
 }
 
 // Register creates a new user
 func (uc *UserUsecase) Register(ctx context.Context, req RegisterRequest) (*models.User, error) {
-	// Check if email already exists
-	existingUser, err := uc.userRepo.FindByEmail(ctx, req.Email)
-	if err != nil {
+	if err := validator.Validate(&req); err != nil {
 		return nil, err
 	}
-	if existingUser != nil {
-		return nil, errors.New("email already exists")
+
+	// Check if email already exists
+	if err := ensureEmailAvailable(ctx, uc.userRepo, req.Email); err != nil {
+		return nil, err
 	}
 
 	// Get customer role
@@ -102,7 +179,7 @@ func (uc *UserUsecase) Register(ctx context.Context, req RegisterRequest) (*mode
 		return nil, err
 	}
 	if customerRole == nil {
-		return nil, errors.New("customer role not found")
+		return nil, apperrors.Internal("Registration is not available", errors.New("customer role not found"))
 	}
 
 	// Create new user with customer role
@@ -116,7 +193,7 @@ func (uc *UserUsecase) Register(ctx context.Context, req RegisterRequest) (*mode
 		customerRole.ID,
 	)
 	if err != nil {
-		return nil, err
+		return nil, apperrors.Validation(err.Error(), nil)
 	}
 
 	// Save user to database
@@ -130,9 +207,26 @@ func (uc *UserUsecase) Register(ctx context.Context, req RegisterRequest) (*mode
 		return nil, err
 	}
 
+	if err := uc.emailVerificationUsecase.SendVerification(ctx, user); err != nil {
+		return nil, err
+	}
+
 	return user, nil
 }
 
+// ensureEmailAvailable checks that no account uses the email address.
+// Deleted accounts keep their address until they are purged, so they can still be restored.
+func ensureEmailAvailable(ctx context.Context, userRepo repositories.UserRepository, email string) error {
+	existingUser, err := userRepo.FindByEmailIncludingDeleted(ctx, email)
+	if err != nil {
+		return err
+	}
+	if existingUser != nil {
+		return apperrors.DuplicateEntry("email already exists", "email")
+	}
+	return nil
+}
+
 // GetUserByID retrieves a user by ID
 func (uc *UserUsecase) GetUserByID(ctx context.Context, id int) (*models.User, error) {
 	return uc.userRepo.FindByID(ctx, id)
@@ -140,16 +234,20 @@ func (uc *UserUsecase) GetUserByID(ctx context.Context, id int) (*models.User, e
 
 // UpdateUserProfile updates a user's profile
 func (uc *UserUsecase) UpdateUserProfile(ctx context.Context, userID int, req UpdateProfileRequest) (*models.User, error) {
+	if err := validator.Validate(&req); err != nil {
+		return nil, err
+	}
+
 	user, err := uc.userRepo.FindByID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 	if user == nil {
-		return nil, errors.New("user not found")
+		return nil, apperrors.NotFound("user not found", "user")
 	}
 
 	if err := user.UpdateProfile(req.FirstName, req.LastName, req.FirstNameKana, req.LastNameKana); err != nil {
-		return nil, err
+		return nil, apperrors.Validation(err.Error(), nil)
 	}
 
 	if err := uc.userRepo.Update(ctx, user); err != nil {
@@ -166,18 +264,23 @@ func (uc *UserUsecase) ChangePassword(ctx context.Context, userID int, currentPa
 		return err
 	}
 	if user == nil {
-		return errors.New("user not found")
+		return apperrors.NotFound("user not found", "user")
 	}
 
 	if !user.VerifyPassword(currentPassword) {
-		return errors.New("current password is incorrect")
+		return apperrors.InvalidCredentials("current password is incorrect")
 	}
 
 	if err := user.ChangePassword(newPassword); err != nil {
+		return apperrors.Validation(err.Error(), nil)
+	}
+
+	if err := uc.userRepo.Update(ctx, user); err != nil {
 		return err
 	}
 
-	return uc.userRepo.Update(ctx, user)
+	// Sessions started with the old password must not survive the change
+	return uc.tokenUsecase.RevokeAllForUser(ctx, user.ID)
 }
 
 // ListUsers lists users with pagination